@@ -0,0 +1,287 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// resolvedRetention is how long resolved alerts stay listed
+const resolvedRetention = 5 * time.Minute
+
+// State is the lifecycle stage of an alert
+type State int
+
+const (
+	// StatePending means the condition holds, but not yet for the rule's duration
+	StatePending State = iota
+	// StateFiring means the condition held for the rule's duration
+	StateFiring
+	// StateResolved means a firing alert's condition cleared
+	StateResolved
+)
+
+// String returns the state name
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateFiring:
+		return "firing"
+	default:
+		return "resolved"
+	}
+}
+
+// Alert is one rule applied to one instance: the system, or a single process
+type Alert struct {
+	Host        string // Set when the engine watches several hosts
+	Rule        string
+	Severity    string
+	Process     monitor.ProcessKey // Zero for system alerts
+	ProcessName string
+	Instance    string // "system", or the process name and PID
+	State       State
+	Value       float64 // Latest value of the metric
+	Threshold   float64
+	Metric      string
+	Op          string
+	Since       time.Time // When the condition started to hold
+	FiredAt     time.Time
+	ResolvedAt  time.Time
+}
+
+// Description formats the condition and the latest value
+func (a Alert) Description() string {
+	return fmt.Sprintf("%s %s %s %g (now %.1f)", a.Instance, a.Metric, a.Op, a.Threshold, a.Value)
+}
+
+// alertID identifies an alert, so every rule alerts at most once per instance
+type alertID struct {
+	host    string
+	rule    string
+	process monitor.ProcessKey
+}
+
+// Engine evaluates rules on every monitor update. Time is taken from the
+// updates, so replayed and remote data is evaluated like live data.
+type Engine struct {
+	rules []Rule
+
+	mu          sync.Mutex
+	alerts      map[alertID]*Alert
+	transitions []Alert // Fired or resolved during the current evaluation
+	handlers    []func(Alert)
+}
+
+// NewEngine creates an engine for the given rules
+func NewEngine(rules []Rule) (*Engine, error) {
+	compiled := make([]Rule, len(rules))
+	copy(compiled, rules)
+	if err := compileRules(compiled); err != nil {
+		return nil, err
+	}
+	return &Engine{rules: compiled, alerts: make(map[alertID]*Alert)}, nil
+}
+
+// Observe evaluates the rules on an update, for use with Monitor.Subscribe
+func (e *Engine) Observe(update monitor.Update) {
+	e.evaluate("", update)
+}
+
+// HostObserver returns a function like Observe for one of several monitored hosts
+func (e *Engine) HostObserver(host string) func(monitor.Update) {
+	return func(update monitor.Update) {
+		e.evaluate(host, update)
+	}
+}
+
+// Covers reports whether a process rule names processes of the given name.
+// Rules without a process pattern apply to everything and don't count.
+func (e *Engine) Covers(name string) bool {
+	for i := range e.rules {
+		if e.rules[i].Scope == ScopeProcess && e.rules[i].process != nil && e.rules[i].process.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe registers a function called whenever an alert starts firing or
+// resolves. It runs on the monitor's updating goroutine and must not block.
+func (e *Engine) Subscribe(fn func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers = append(e.handlers, fn)
+}
+
+// Alerts returns the current alerts: firing first, then pending, then
+// resolved, more severe and older ones first
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+	e.mu.Unlock()
+
+	order := map[State]int{StateFiring: 0, StatePending: 1, StateResolved: 2}
+	sort.Slice(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if order[a.State] != order[b.State] {
+			return order[a.State] < order[b.State]
+		}
+		if a.Severity != b.Severity {
+			return severityRank(a.Severity) > severityRank(b.Severity)
+		}
+		if !a.Since.Equal(b.Since) {
+			return a.Since.Before(b.Since)
+		}
+		return a.Instance < b.Instance
+	})
+	return alerts
+}
+
+// Counts returns the number of firing and pending alerts
+func (e *Engine) Counts() (firing, pending int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, alert := range e.alerts {
+		switch alert.State {
+		case StateFiring:
+			firing++
+		case StatePending:
+			pending++
+		}
+	}
+	return firing, pending
+}
+
+// evaluate advances the alerts of one host and reports the transitions
+func (e *Engine) evaluate(host string, update monitor.Update) {
+	e.mu.Lock()
+	e.advance(host, update)
+	transitions := e.transitions
+	e.transitions = nil
+	handlers := e.handlers
+	e.mu.Unlock()
+
+	for _, alert := range transitions {
+		for _, fn := range handlers {
+			fn(alert)
+		}
+	}
+}
+
+// advance evaluates every rule on an update. Process rules see every running
+// process, not only the top list. Callers must hold the lock.
+func (e *Engine) advance(host string, update monitor.Update) {
+	now := update.System.Timestamp
+
+	processes := update.All
+	if processes == nil {
+		processes = update.Processes
+	}
+	// Network usage is only attributed to the top processes
+	attributed := make(map[monitor.ProcessKey]bool, len(update.Processes))
+	for _, proc := range update.Processes {
+		attributed[proc.Key()] = true
+	}
+
+	seen := make(map[alertID]bool)
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if rule.Scope == ScopeSystem {
+			id := alertID{host: host, rule: rule.Name}
+			seen[id] = true
+			e.step(id, rule, "", rule.systemValue(update.System), now)
+			continue
+		}
+		for _, proc := range processes {
+			if !rule.matches(proc) {
+				continue
+			}
+			id := alertID{host: host, rule: rule.Name, process: proc.Key()}
+			seen[id] = true
+			if rule.network && !attributed[proc.Key()] {
+				continue // Value unknown, the alert stays as it is
+			}
+			proc.CPUPercent = update.CPUMode.ToPerCore(proc.CPUPercent, update.System.NumCPU)
+			e.step(id, rule, proc.Name, rule.processValue(proc), now)
+		}
+	}
+
+	// Processes that exited or stopped matching end their alerts
+	for id, alert := range e.alerts {
+		if id.host != host || seen[id] {
+			continue
+		}
+		switch alert.State {
+		case StatePending:
+			delete(e.alerts, id)
+		case StateFiring:
+			e.resolve(alert, now)
+		}
+	}
+
+	for id, alert := range e.alerts {
+		if id.host == host && alert.State == StateResolved && now.Sub(alert.ResolvedAt) > resolvedRetention {
+			delete(e.alerts, id)
+		}
+	}
+}
+
+// step moves one alert through its states. Callers must hold the lock.
+func (e *Engine) step(id alertID, rule *Rule, name string, value float64, now time.Time) {
+	alert, exists := e.alerts[id]
+	if !exists || alert.State == StateResolved {
+		if !rule.exceeds(value) {
+			return
+		}
+		instance := "system"
+		if id.process.PID != 0 {
+			instance = fmt.Sprintf("%s (PID %d)", name, id.process.PID)
+		}
+		// A new occurrence replaces a resolved alert of the same instance
+		alert = &Alert{
+			Host:        id.host,
+			Rule:        rule.Name,
+			Severity:    rule.Severity,
+			Process:     id.process,
+			ProcessName: name,
+			Instance:    instance,
+			State:       StatePending,
+			Threshold:   rule.Threshold,
+			Metric:      rule.Metric,
+			Op:          rule.Op,
+			Since:       now,
+		}
+		e.alerts[id] = alert
+	}
+	alert.Value = value
+
+	switch alert.State {
+	case StatePending:
+		if !rule.exceeds(value) {
+			delete(e.alerts, id)
+		} else if now.Sub(alert.Since) >= time.Duration(rule.For) {
+			alert.State = StateFiring
+			alert.FiredAt = now
+			e.transitions = append(e.transitions, *alert)
+		}
+	case StateFiring:
+		if rule.cleared(value) {
+			e.resolve(alert, now)
+		}
+	}
+}
+
+// resolve ends a firing alert. Callers must hold the lock.
+func (e *Engine) resolve(alert *Alert, now time.Time) {
+	alert.State = StateResolved
+	alert.ResolvedAt = now
+	e.transitions = append(e.transitions, *alert)
+}
@@ -0,0 +1,80 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+func TestProcessAlertsCoverAllProcesses(t *testing.T) {
+	engine, err := NewEngine([]Rule{
+		{Name: "memory", Scope: ScopeProcess, Process: "worker.*", Metric: "memory_mb", Op: ">", Threshold: 100},
+		{Name: "upload", Scope: ScopeProcess, Process: "worker.*", Metric: "net_sent_kbps", Op: ">", Threshold: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transitions []Alert
+	engine.Subscribe(func(a Alert) { transitions = append(transitions, a) })
+
+	worker := monitor.ProcessInfo{PID: 7, Name: "worker", MemoryMB: 500, NetSentRate: 500, CreateTime: testStart}
+	other := monitor.ProcessInfo{PID: 8, Name: "other", CreateTime: testStart}
+	states := func() map[string]State {
+		result := make(map[string]State)
+		for _, a := range engine.Alerts() {
+			result[a.Rule] = a.State
+		}
+		return result
+	}
+
+	steps := []struct {
+		name string
+		top  []monitor.ProcessInfo
+		all  []monitor.ProcessInfo
+		want map[string]State
+	}{
+		{"in the top list", []monitor.ProcessInfo{worker}, []monitor.ProcessInfo{worker, other},
+			map[string]State{"memory": StateFiring, "upload": StateFiring}},
+		// Network usage is unknown outside the top list, memory still exceeds
+		{"out of the top list", []monitor.ProcessInfo{other}, []monitor.ProcessInfo{worker, other},
+			map[string]State{"memory": StateFiring, "upload": StateFiring}},
+		{"renamed", []monitor.ProcessInfo{other}, []monitor.ProcessInfo{{PID: 7, Name: "sh", CreateTime: testStart}, other},
+			map[string]State{"memory": StateResolved, "upload": StateResolved}},
+	}
+
+	at := testStart
+	for _, step := range steps {
+		at = at.Add(2 * time.Second)
+		engine.Observe(monitor.Update{System: monitor.SystemMetrics{Timestamp: at, NumCPU: 1}, Processes: step.top, All: step.all})
+		got := states()
+		for rule, want := range step.want {
+			if got[rule] != want {
+				t.Errorf("%s: %s alert is %v, want %v", step.name, rule, got[rule], want)
+			}
+		}
+	}
+
+	// Fired once each, resolved once each, no flapping in between
+	if len(transitions) != 4 {
+		t.Errorf("got %d transitions, want 4: %+v", len(transitions), transitions)
+	}
+}
+
+func TestProcessAlertResolvesOnExit(t *testing.T) {
+	engine, err := NewEngine([]Rule{{Name: "cpu", Scope: ScopeProcess, Metric: "cpu_percent", Op: ">", Threshold: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy := monitor.ProcessInfo{PID: 7, Name: "busy", CPUPercent: 90, CreateTime: testStart}
+
+	engine.Observe(monitor.Update{System: monitor.SystemMetrics{Timestamp: testStart, NumCPU: 1}, All: []monitor.ProcessInfo{busy}})
+	engine.Observe(monitor.Update{System: monitor.SystemMetrics{Timestamp: testStart.Add(time.Second), NumCPU: 1}, All: []monitor.ProcessInfo{}})
+
+	alerts := engine.Alerts()
+	if len(alerts) != 1 || alerts[0].State != StateResolved || alerts[0].Process != busy.Key() {
+		t.Errorf("alerts = %+v, want the alert of PID 7 resolved", alerts)
+	}
+}
@@ -0,0 +1,214 @@
+// Package alert evaluates threshold rules against monitor updates and tracks
+// the resulting alerts through their pending, firing and resolved states.
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Rule scopes
+const (
+	ScopeSystem  = "system"
+	ScopeProcess = "process"
+)
+
+// Severities, in increasing order
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// systemMetrics are the values a system rule can test
+var systemMetrics = map[string]func(monitor.SystemMetrics) float64{
+	"cpu_percent":     func(s monitor.SystemMetrics) float64 { return s.CPUPercent },
+	"memory_percent":  func(s monitor.SystemMetrics) float64 { return s.MemoryPercent },
+	"memory_used_mb":  func(s monitor.SystemMetrics) float64 { return s.UsedMemoryMB },
+	"disk_read_kbps":  func(s monitor.SystemMetrics) float64 { return s.DiskReadRate },
+	"disk_write_kbps": func(s monitor.SystemMetrics) float64 { return s.DiskWriteRate },
+	"load1":           func(s monitor.SystemMetrics) float64 { return s.Load.Load1 },
+	"load5":           func(s monitor.SystemMetrics) float64 { return s.Load.Load5 },
+	"load15":          func(s monitor.SystemMetrics) float64 { return s.Load.Load15 },
+}
+
+// processMetrics are the values a process rule can test. CPU usage is
+// per-core, 100 is one fully used core.
+var processMetrics = map[string]func(monitor.ProcessInfo) float64{
+	"cpu_percent":     func(p monitor.ProcessInfo) float64 { return p.CPUPercent },
+	"memory_percent":  func(p monitor.ProcessInfo) float64 { return float64(p.MemoryPerc) },
+	"memory_mb":       func(p monitor.ProcessInfo) float64 { return p.MemoryMB },
+	"disk_read_kbps":  func(p monitor.ProcessInfo) float64 { return p.DiskReadRate },
+	"disk_write_kbps": func(p monitor.ProcessInfo) float64 { return p.DiskWriteRate },
+	"net_sent_kbps":   func(p monitor.ProcessInfo) float64 { return p.NetSentRate },
+	"net_recv_kbps":   func(p monitor.ProcessInfo) float64 { return p.NetRecvRate },
+}
+
+// Duration is a time.Duration written as a string like "2m" in rule files
+type Duration time.Duration
+
+// UnmarshalJSON parses a Go duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\"")
+	}
+	value, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// Rule raises an alert while a metric stays beyond a threshold. Process
+// rules are evaluated for every matching process, each alerting on its own.
+type Rule struct {
+	Name       string   `json:"name"`
+	Scope      string   `json:"scope"`   // ScopeSystem or ScopeProcess
+	Process    string   `json:"process"` // Regular expression matching the whole process name, empty for any
+	Metric     string   `json:"metric"`
+	Op         string   `json:"op"` // >, >=, < or <=
+	Threshold  float64  `json:"threshold"`
+	For        Duration `json:"for"`        // How long the condition must hold before firing
+	Hysteresis float64  `json:"hysteresis"` // How far back past the threshold a firing alert must go to resolve
+	Severity   string   `json:"severity"`
+
+	process       *regexp.Regexp
+	systemValue   func(monitor.SystemMetrics) float64
+	processValue  func(monitor.ProcessInfo) float64
+	network       bool // Metric is only known for the top processes
+	above         bool // Op is > or >=
+	includesEqual bool // Op is >= or <=
+}
+
+// LoadRules reads a JSON array of rules from a file
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := compileRules(rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// compileRules fills in defaults and validates the rules
+func compileRules(rules []Rule) error {
+	names := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d: missing name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %d: duplicate name %q", i+1, rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.Severity == "" {
+			rule.Severity = SeverityWarning
+		}
+		if severityRank(rule.Severity) < 0 {
+			return fmt.Errorf("rule %q: unknown severity %q (available: info, warning, critical)", rule.Name, rule.Severity)
+		}
+		if rule.For < 0 || rule.Hysteresis < 0 {
+			return fmt.Errorf("rule %q: for and hysteresis must not be negative", rule.Name)
+		}
+
+		switch rule.Op {
+		case ">", ">=":
+			rule.above = true
+		case "<", "<=":
+		default:
+			return fmt.Errorf("rule %q: unknown op %q (available: >, >=, <, <=)", rule.Name, rule.Op)
+		}
+		rule.includesEqual = strings.HasSuffix(rule.Op, "=")
+
+		var ok bool
+		switch rule.Scope {
+		case ScopeSystem:
+			if rule.Process != "" {
+				return fmt.Errorf("rule %q: process only applies to the process scope", rule.Name)
+			}
+			if rule.systemValue, ok = systemMetrics[rule.Metric]; !ok {
+				return fmt.Errorf("rule %q: unknown system metric %q (available: %s)", rule.Name, rule.Metric, metricNames(systemMetrics))
+			}
+		case ScopeProcess:
+			if rule.processValue, ok = processMetrics[rule.Metric]; !ok {
+				return fmt.Errorf("rule %q: unknown process metric %q (available: %s)", rule.Name, rule.Metric, metricNames(processMetrics))
+			}
+			rule.network = rule.Metric == "net_sent_kbps" || rule.Metric == "net_recv_kbps"
+			process, err := regexp.Compile("^(?:" + rule.Process + ")$")
+			if err != nil {
+				return fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			if rule.Process != "" {
+				rule.process = process
+			}
+		default:
+			return fmt.Errorf("rule %q: unknown scope %q (available: system, process)", rule.Name, rule.Scope)
+		}
+	}
+	return nil
+}
+
+// matches reports whether a process rule applies to the process
+func (r *Rule) matches(proc monitor.ProcessInfo) bool {
+	return r.process == nil || r.process.MatchString(proc.Name)
+}
+
+// exceeds reports whether the value meets the rule's condition
+func (r *Rule) exceeds(value float64) bool {
+	if r.includesEqual && value == r.Threshold {
+		return true
+	}
+	if r.above {
+		return value > r.Threshold
+	}
+	return value < r.Threshold
+}
+
+// cleared reports whether a firing alert's value went back far enough to resolve
+func (r *Rule) cleared(value float64) bool {
+	if r.Hysteresis == 0 {
+		return !r.exceeds(value)
+	}
+	if r.above {
+		return value < r.Threshold-r.Hysteresis
+	}
+	return value > r.Threshold+r.Hysteresis
+}
+
+// severityRank orders severities, unknown ones are -1
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityInfo:
+		return 0
+	case SeverityWarning:
+		return 1
+	case SeverityCritical:
+		return 2
+	}
+	return -1
+}
+
+func metricNames[T any](metrics map[string]T) string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
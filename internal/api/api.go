@@ -0,0 +1,258 @@
+// Package api serves monitor data as JSON over HTTP, including a live
+// stream of updates as server-sent events.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// keepAliveInterval is how often idle event streams send a comment, so
+// proxies don't close them
+const keepAliveInterval = 15 * time.Second
+
+// Server implements the JSON API on top of a Monitor
+type Server struct {
+	monitor *monitor.Monitor
+
+	mu      sync.Mutex
+	clients map[chan monitor.Update]struct{} // Event stream subscribers
+}
+
+// NewServer creates the API for a monitor and subscribes to its updates
+func NewServer(mon *monitor.Monitor) *Server {
+	s := &Server{
+		monitor: mon,
+		clients: make(map[chan monitor.Update]struct{}),
+	}
+	mon.Subscribe(s.publish)
+	return s
+}
+
+// Register adds the API routes to a mux
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/system", s.handleSystem)
+	mux.HandleFunc("GET /api/processes", s.handleProcesses)
+	mux.HandleFunc("GET /api/processes/{pid}/metrics", s.handleProcessMetrics)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+}
+
+func (s *Server) handleSystem(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, newSystem(s.monitor.GetSystemMetrics()))
+}
+
+// handleProcesses lists processes. Query parameters: sort (pid, name, cpu,
+// memory), order (asc, desc), filter (name or PID substring) and limit.
+func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
+	query, err := parseProcessQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, ProcessList{
+		Timestamp: s.monitor.GetSystemMetrics().Timestamp,
+		CPUMode:   s.monitor.GetCPUMode().String(),
+		Processes: newProcesses(query.apply(s.monitor.GetProcesses())),
+	})
+}
+
+// handleProcessMetrics returns the time series of a process. Query
+// parameters: tier (resolution index, 0 is the finest) and start_time, which
+// tells apart processes that reused the PID.
+func (s *Server) handleProcessMetrics(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.ParseInt(r.PathValue("pid"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid pid", http.StatusBadRequest)
+		return
+	}
+	tier := 0
+	if value := r.URL.Query().Get("tier"); value != "" {
+		tier, err = strconv.Atoi(value)
+		if err != nil || tier < 0 || tier >= len(s.monitor.MetricTiers()) {
+			http.Error(w, fmt.Sprintf("invalid tier, must be between 0 and %d", len(s.monitor.MetricTiers())-1), http.StatusBadRequest)
+			return
+		}
+	}
+	var startTime int64
+	if value := r.URL.Query().Get("start_time"); value != "" {
+		if startTime, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "invalid start_time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, proc := range s.monitor.GetProcesses() {
+		key := proc.Key()
+		if key.PID != int32(pid) || (startTime != 0 && key.StartTime != startTime) {
+			continue
+		}
+		writeJSON(w, ProcessMetrics{
+			PID:        key.PID,
+			StartTime:  key.StartTime,
+			Name:       proc.Name,
+			CPUMode:    s.monitor.GetCPUMode().String(),
+			Resolution: s.monitor.MetricTiers()[tier].Resolution.Seconds(),
+			Samples:    newSamples(s.monitor.GetProcessHistory(key, tier)),
+		})
+		return
+	}
+	http.Error(w, "process not found", http.StatusNotFound)
+}
+
+// handleEvents streams every monitor update as an "update" event. It accepts
+// the same query parameters as /api/processes.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := parseProcessQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates := make(chan monitor.Update, 1)
+	s.mu.Lock()
+	s.clients[updates] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, updates)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Start with the current state instead of waiting for the next update
+	send := func(update monitor.Update) bool {
+		data, err := json.Marshal(Event{
+			System:    newSystem(update.System),
+			CPUMode:   update.CPUMode.String(),
+			Processes: newProcesses(query.apply(update.Processes)),
+		})
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: update\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	if !send(monitor.Update{
+		System:    s.monitor.GetSystemMetrics(),
+		Processes: s.monitor.GetProcesses(),
+		CPUMode:   s.monitor.GetCPUMode(),
+	}) {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case update := <-updates:
+			if !send(update) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// publish hands an update to every event stream. Slow clients skip updates
+// instead of holding up the monitor.
+func (s *Server) publish(update monitor.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for client := range s.clients {
+		select {
+		case <-client: // Replace an update the client hasn't picked up yet
+		default:
+		}
+		client <- update
+	}
+}
+
+// processQuery selects and orders processes for a response
+type processQuery struct {
+	sortBy monitor.SortBy
+	sorted bool
+	desc   bool
+	filter string
+	limit  int
+}
+
+func parseProcessQuery(r *http.Request) (processQuery, error) {
+	values := r.URL.Query()
+	query := processQuery{filter: values.Get("filter")}
+
+	if name := values.Get("sort"); name != "" {
+		sortBy, err := monitor.ParseSortBy(name)
+		if err != nil {
+			return query, err
+		}
+		query.sortBy = sortBy
+		query.sorted = true
+		query.desc = sortBy.DefaultDescending()
+	}
+	switch strings.ToLower(values.Get("order")) {
+	case "":
+	case "asc":
+		query.desc = false
+	case "desc":
+		query.desc = true
+	default:
+		return query, fmt.Errorf("invalid order %q (available: asc, desc)", values.Get("order"))
+	}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return query, fmt.Errorf("invalid limit %q", value)
+		}
+		query.limit = limit
+	}
+	return query, nil
+}
+
+// apply filters, sorts and truncates a process list. Without a sort
+// parameter the order of the interactive UI is kept.
+func (q processQuery) apply(processes []monitor.ProcessInfo) []monitor.ProcessInfo {
+	processes = monitor.FilterProcesses(processes, q.filter)
+	if q.sorted {
+		sorted := make([]monitor.ProcessInfo, len(processes))
+		copy(sorted, processes)
+		monitor.SortProcesses(sorted, q.sortBy, q.desc)
+		processes = sorted
+	}
+	if q.limit > 0 && len(processes) > q.limit {
+		processes = processes[:q.limit]
+	}
+	return processes
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+var testProcesses = []monitor.ProcessInfo{
+	{PID: 100, PPID: 1, Name: "postgres", CPUPercent: 80, MemoryMB: 512, CreateTime: testStart.Add(-time.Hour)},
+	{PID: 200, PPID: 1, Name: "nginx", CPUPercent: 20, MemoryMB: 1024, CreateTime: testStart.Add(-time.Hour)},
+	{PID: 300, PPID: 200, Name: "nginx", CPUPercent: 5, MemoryMB: 64, CreateTime: testStart.Add(-time.Minute)},
+}
+
+// newTestServer serves the API for a monitor holding one snapshot
+func newTestServer(t *testing.T) (*monitor.Monitor, *monitor.FakeClock, *httptest.Server) {
+	t.Helper()
+	clock := monitor.NewFakeClock(testStart)
+	mon := monitor.NewSnapshotMonitor(clock)
+	mon.ApplySnapshot(monitor.SystemMetrics{Timestamp: testStart, NumCPU: 4, CPUPercent: 42.5, TotalMemoryMB: 8192}, testProcesses, nil)
+
+	mux := http.NewServeMux()
+	NewServer(mon).Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return mon, clock, server
+}
+
+// getJSON fetches a path and decodes the response into value
+func getJSON(t *testing.T, server *httptest.Server, path string, value any) int {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type = %q", path, ct)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestSystem(t *testing.T) {
+	_, _, server := newTestServer(t)
+	var system System
+	if status := getJSON(t, server, "/api/system", &system); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if !system.Timestamp.Equal(testStart) || system.CPUPercent != 42.5 || system.NumCPU != 4 || system.TotalMemoryMB != 8192 {
+		t.Errorf("system = %+v", system)
+	}
+}
+
+func TestProcesses(t *testing.T) {
+	_, _, server := newTestServer(t)
+	tests := []struct {
+		query string
+		want  []int32
+	}{
+		{"", []int32{100, 200, 300}}, // Order of the UI: CPU, descending
+		{"?sort=memory", []int32{200, 100, 300}},
+		{"?sort=pid&order=desc", []int32{300, 200, 100}},
+		{"?filter=nginx&limit=1", []int32{200}},
+	}
+	for _, tt := range tests {
+		var list ProcessList
+		if status := getJSON(t, server, "/api/processes"+tt.query, &list); status != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.query, status)
+		}
+		var got []int32
+		for _, proc := range list.Processes {
+			got = append(got, proc.PID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: PIDs %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?sort=colour", "?order=up", "?limit=-1"} {
+		if status := getJSON(t, server, "/api/processes"+query, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, status)
+		}
+	}
+}
+
+func TestProcessMetricsNotFound(t *testing.T) {
+	_, _, server := newTestServer(t)
+	var metrics ProcessMetrics
+	if status := getJSON(t, server, "/api/processes/100/metrics", &metrics); status != http.StatusOK {
+		t.Fatalf("status %d for a listed process", status)
+	}
+	if metrics.Name != "postgres" || len(metrics.Samples) != 1 {
+		t.Errorf("metrics = %+v, want one sample of postgres", metrics)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/processes/999/metrics", http.StatusNotFound},
+		{"/api/processes/100/metrics?start_time=1", http.StatusNotFound}, // An earlier holder of the PID
+		{"/api/processes/abc/metrics", http.StatusBadRequest},
+		{"/api/processes/100/metrics?tier=99", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status := getJSON(t, server, tt.path, nil); status != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.path, status, tt.status)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	mon, clock, server := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events?limit=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	// readEvent reads one event, which ends with an empty line
+	readEvent := func() Event {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading event: %v", err)
+			}
+			if line == "\n" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) != 2 || lines[0] != "event: update\n" || !strings.HasPrefix(lines[1], "data: ") {
+			t.Fatalf("event lines = %q", lines)
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &event); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	// The current state comes first
+	event := readEvent()
+	if !event.System.Timestamp.Equal(testStart) || len(event.Processes) != 1 || event.Processes[0].PID != 100 {
+		t.Errorf("first event = %+v", event)
+	}
+
+	clock.Advance(time.Second)
+	next := testStart.Add(time.Second)
+	mon.ApplySnapshot(monitor.SystemMetrics{Timestamp: next, NumCPU: 4}, testProcesses[1:], nil)
+	event = readEvent()
+	if !event.System.Timestamp.Equal(next) || len(event.Processes) != 1 || event.Processes[0].PID != 200 {
+		t.Errorf("event after the update = %+v", event)
+	}
+}
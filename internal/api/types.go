@@ -0,0 +1,168 @@
+package api
+
+import (
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// The JSON field names follow the batch mode output
+
+// System is the response of /api/system
+type System struct {
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	NumCPU        int       `json:"num_cpu"`
+	MemoryPercent float64   `json:"memory_percent"`
+	TotalMemoryMB float64   `json:"total_memory_mb"`
+	UsedMemoryMB  float64   `json:"used_memory_mb"`
+	DiskReadRate  float64   `json:"disk_read_kbps"`
+	DiskWriteRate float64   `json:"disk_write_kbps"`
+	Disks         []Disk    `json:"disks"`
+}
+
+// Disk is one block device of the system metrics
+type Disk struct {
+	Name        string  `json:"name"`
+	ReadRate    float64 `json:"read_kbps"`
+	WriteRate   float64 `json:"write_kbps"`
+	ReadIOPS    float64 `json:"read_iops"`
+	WriteIOPS   float64 `json:"write_iops"`
+	BusyPercent float64 `json:"busy_percent"`
+}
+
+// Process is one entry of the process list
+type Process struct {
+	PID           int32     `json:"pid"`
+	PPID          int32     `json:"ppid"`
+	StartTime     int64     `json:"start_time"` // Milliseconds since the epoch, identifies the process together with the PID
+	Name          string    `json:"name"`
+	User          string    `json:"user,omitempty"`
+	CreateTime    time.Time `json:"create_time"`
+	State         string    `json:"state"` // R, S, D, Z, T, ...
+	Threads       int32     `json:"threads"`
+	Nice          int32     `json:"nice"`
+	Priority      int32     `json:"priority"`
+	TTY           string    `json:"tty,omitempty"`
+	Cmdline       string    `json:"cmdline,omitempty"`
+	Cwd           string    `json:"cwd,omitempty"`
+	Exe           string    `json:"exe,omitempty"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryMB      float64   `json:"memory_mb"`
+	MemoryPercent float32   `json:"memory_percent"`
+	DiskReadKB    float64   `json:"disk_read_kb"`
+	DiskWriteKB   float64   `json:"disk_write_kb"`
+	DiskReadRate  float64   `json:"disk_read_kbps"`
+	DiskWriteRate float64   `json:"disk_write_kbps"`
+	DiskReadPerc  float64   `json:"disk_read_percent"`
+	DiskWritePerc float64   `json:"disk_write_percent"`
+	NetSentKB     float64   `json:"net_sent_kb"`
+	NetRecvKB     float64   `json:"net_recv_kb"`
+	NetSentRate   float64   `json:"net_sent_kbps"`
+	NetRecvRate   float64   `json:"net_recv_kbps"`
+	CtxSwitchRate float64   `json:"ctx_switches_ps"`
+	MinorFaultPS  float64   `json:"minor_faults_ps"`
+	MajorFaultPS  float64   `json:"major_faults_ps"`
+}
+
+// ProcessList is the response of /api/processes
+type ProcessList struct {
+	Timestamp time.Time `json:"timestamp"`
+	CPUMode   string    `json:"cpu_mode"` // "per-core" or "normalized"
+	Processes []Process `json:"processes"`
+}
+
+// ProcessMetrics is the response of /api/processes/{pid}/metrics
+type ProcessMetrics struct {
+	PID        int32    `json:"pid"`
+	StartTime  int64    `json:"start_time"`
+	Name       string   `json:"name"`
+	CPUMode    string   `json:"cpu_mode"`
+	Resolution float64  `json:"resolution_seconds"`
+	Samples    []Sample `json:"samples"`
+}
+
+// Sample is one point of a process time series
+type Sample struct {
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryMB      float64   `json:"memory_mb"`
+	DiskReadRate  float64   `json:"disk_read_kbps"`
+	DiskWriteRate float64   `json:"disk_write_kbps"`
+	DiskReadPerc  float64   `json:"disk_read_percent"`
+	DiskWritePerc float64   `json:"disk_write_percent"`
+	NetSentRate   float64   `json:"net_sent_kbps"`
+	NetRecvRate   float64   `json:"net_recv_kbps"`
+}
+
+// Event is the payload of an "update" server-sent event
+type Event struct {
+	System    System    `json:"system"`
+	CPUMode   string    `json:"cpu_mode"`
+	Processes []Process `json:"processes"`
+}
+
+func newSystem(system monitor.SystemMetrics) System {
+	result := System{
+		Timestamp:     system.Timestamp,
+		CPUPercent:    system.CPUPercent,
+		NumCPU:        system.NumCPU,
+		MemoryPercent: system.MemoryPercent,
+		TotalMemoryMB: system.TotalMemoryMB,
+		UsedMemoryMB:  system.UsedMemoryMB,
+		DiskReadRate:  system.DiskReadRate,
+		DiskWriteRate: system.DiskWriteRate,
+		Disks:         make([]Disk, 0, len(system.Disks)),
+	}
+	for _, disk := range system.Disks {
+		result.Disks = append(result.Disks, Disk(disk))
+	}
+	return result
+}
+
+func newProcesses(processes []monitor.ProcessInfo) []Process {
+	result := make([]Process, 0, len(processes))
+	for _, proc := range processes {
+		result = append(result, Process{
+			PID:           proc.PID,
+			PPID:          proc.PPID,
+			StartTime:     proc.Key().StartTime,
+			Name:          proc.Name,
+			User:          proc.Username,
+			CreateTime:    proc.CreateTime,
+			State:         proc.State,
+			Threads:       proc.Threads,
+			Nice:          proc.Nice,
+			Priority:      proc.Priority,
+			TTY:           proc.TTY,
+			Cmdline:       proc.Cmdline,
+			Cwd:           proc.Cwd,
+			Exe:           proc.Exe,
+			CPUPercent:    proc.CPUPercent,
+			MemoryMB:      proc.MemoryMB,
+			MemoryPercent: proc.MemoryPerc,
+			DiskReadKB:    proc.DiskReadKB,
+			DiskWriteKB:   proc.DiskWriteKB,
+			DiskReadRate:  proc.DiskReadRate,
+			DiskWriteRate: proc.DiskWriteRate,
+			DiskReadPerc:  proc.DiskReadPerc,
+			DiskWritePerc: proc.DiskWritePerc,
+			NetSentKB:     proc.NetSentKB,
+			NetRecvKB:     proc.NetRecvKB,
+			NetSentRate:   proc.NetSentRate,
+			NetRecvRate:   proc.NetRecvRate,
+			CtxSwitchRate: proc.CtxSwitchRate,
+			MinorFaultPS:  proc.MinorFaultPS,
+			MajorFaultPS:  proc.MajorFaultPS,
+		})
+	}
+	return result
+}
+
+func newSamples(samples []monitor.MetricSample) []Sample {
+	result := make([]Sample, 0, len(samples))
+	for _, sample := range samples {
+		result = append(result, Sample(sample))
+	}
+	return result
+}
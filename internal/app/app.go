@@ -3,78 +3,572 @@ package app
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 
+	"hyperbyte-proc-monitor/internal/alert"
+	"hyperbyte-proc-monitor/internal/api"
+	"hyperbyte-proc-monitor/internal/audit"
+	"hyperbyte-proc-monitor/internal/exporter"
 	"hyperbyte-proc-monitor/internal/monitor"
+	"hyperbyte-proc-monitor/internal/notify"
+	"hyperbyte-proc-monitor/internal/remote"
+	"hyperbyte-proc-monitor/internal/session"
+	"hyperbyte-proc-monitor/internal/sink"
+	"hyperbyte-proc-monitor/internal/storage"
 	"hyperbyte-proc-monitor/internal/ui"
+	"hyperbyte-proc-monitor/internal/web"
 )
 
+// Config holds the command line settings of the application
+type Config struct {
+	HistoryEnabled   bool
+	HistoryDir       string
+	HistoryRetention time.Duration
+	HistoryMaxBytes  int64
+
+	Headless         bool     // Run without the UI, e.g. as an exporter
+	Columns          []string // Optional process table columns, see ui.ColumnNames
+	Listen           string   // HTTP listen address for /metrics, /api and the dashboard, empty to disable
+	MetricsLabels    []string // Process labels of the Prometheus series
+	MetricsMaxSeries int      // Process series limit of the Prometheus exporter
+
+	RemoteWriteURL           string // Prometheus remote-write endpoint, empty to disable
+	RemoteWriteQueueDir      string // Batches waiting for delivery, empty for memory only
+	RemoteWriteRelabel       string // JSON file with relabeling rules
+	RemoteWriteInterval      time.Duration
+	RemoteWriteMaxQueueBytes int64
+
+	OTLPEndpoint string            // OpenTelemetry collector URL, empty to disable
+	OTLPProtocol string            // exporter.OTLPProtobuf or exporter.OTLPJSON
+	OTLPHeaders  map[string]string // Extra request headers
+	OTLPInterval time.Duration
+
+	Sinks []string // Influx and Graphite sink specs, see sink.Parse
+
+	AlertRules string // JSON file with alert rules, empty to disable
+	Notify     string // JSON file with notification channels, empty to disable
+
+	AgentListen       string          // TCP address to stream snapshots to remote viewers on, empty to disable
+	AgentTLS          remote.TLSFiles // CA signs the viewer certificates
+	AgentToken        string
+	AgentAllowSignals bool // Let viewers signal and tune processes
+
+	AuditLog string // File recording process actions, empty to disable
+}
+
+// DefaultConfig returns the settings used when no flags are given
+func DefaultConfig() Config {
+	defaults := storage.DefaultOptions(storage.DefaultDir())
+	remoteWrite := exporter.DefaultRemoteWriteOptions("")
+	otlp := exporter.DefaultOTLPOptions("")
+	return Config{
+		HistoryEnabled:   false, // Opt-in, it writes to disk continuously
+		HistoryDir:       defaults.Dir,
+		HistoryRetention: defaults.Retention,
+		HistoryMaxBytes:  defaults.MaxBytes,
+		MetricsLabels:    exporter.DefaultLabels,
+		MetricsMaxSeries: 200,
+
+		RemoteWriteQueueDir:      filepath.Join(filepath.Dir(defaults.Dir), "remote-write"),
+		RemoteWriteInterval:      remoteWrite.Interval,
+		RemoteWriteMaxQueueBytes: remoteWrite.MaxQueueBytes,
+
+		OTLPProtocol: otlp.Protocol,
+		OTLPInterval: otlp.Interval,
+
+		AuditLog: DefaultAuditLog(),
+	}
+}
+
+// DefaultAuditLog returns the audit log path next to the default history directory
+func DefaultAuditLog() string {
+	return filepath.Join(filepath.Dir(storage.DefaultDir()), "audit.log")
+}
+
 // App represents the main application
 type App struct {
-	monitor *monitor.Monitor
-	ui      *ui.UI
-	ctx     context.Context
-	cancel  context.CancelFunc
-	wg      sync.WaitGroup
+	monitor  *monitor.Monitor
+	history  *storage.Store
+	remote   *exporter.RemoteWriter // Nil unless pushing via remote-write
+	otlp     *exporter.OTLPExporter // Nil unless exporting via OTLP
+	sinks    []*sink.Sink
+	agent    *remote.Agent    // Nil unless streaming to remote viewers
+	notifier *notify.Notifier // Nil unless notification channels are configured
+	audit    *audit.Log       // Nil unless process actions are recorded
+	player   *session.Player  // Set when replaying a recorded session
+	clients  []*remote.Client // Set when viewing remote agents
+	ui       *ui.UI           // Nil when running headless
+	server   *http.Server
+	ln       net.Listener
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
 }
 
 // NewApp creates a new application instance
-func NewApp() (*App, error) {
+func NewApp(config Config) (*App, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create monitor
 	mon := monitor.NewMonitor()
 
+	a := &App{
+		monitor: mon,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	// Serve metrics before anything else, so a busy port fails fast
+	if config.Listen != "" {
+		if err := a.setupServer(config); err != nil {
+			a.abandon()
+			return nil, err
+		}
+	}
+
+	if config.RemoteWriteURL != "" {
+		if err := a.setupRemoteWrite(config); err != nil {
+			a.abandon()
+			return nil, err
+		}
+	}
+
+	if config.OTLPEndpoint != "" {
+		opts := exporter.DefaultOTLPOptions(config.OTLPEndpoint)
+		opts.Protocol = config.OTLPProtocol
+		opts.Headers = config.OTLPHeaders
+		opts.Interval = config.OTLPInterval
+		otlp, err := exporter.NewOTLPExporter(mon, opts)
+		if err != nil {
+			a.abandon()
+			return nil, err
+		}
+		otlp.SetLogf(a.reportError)
+		a.otlp = otlp
+	}
+
+	// Process actions come from the UI, or from viewers of the agent
+	if config.AuditLog != "" && (!config.Headless || config.AgentAllowSignals) {
+		log, err := audit.Open(config.AuditLog)
+		if err != nil {
+			a.abandon()
+			return nil, err
+		}
+		a.audit = log
+	}
+
+	if config.AgentListen != "" {
+		agent, err := remote.NewAgent(mon, remote.AgentOptions{
+			Listen:       config.AgentListen,
+			TLS:          config.AgentTLS,
+			Token:        config.AgentToken,
+			AllowSignals: config.AgentAllowSignals,
+			Audit:        a.audit,
+		})
+		if err != nil {
+			a.abandon()
+			return nil, fmt.Errorf("failed to start agent: %w", err)
+		}
+		agent.SetLogf(a.reportError)
+		a.agent = agent
+	}
+
+	for _, spec := range config.Sinks {
+		s, err := sink.Parse(spec)
+		if err != nil {
+			a.abandon()
+			return nil, err
+		}
+		s.SetLogf(a.reportError)
+		a.sinks = append(a.sinks, s)
+	}
+
+	var alerts *alert.Engine
+	if config.AlertRules != "" {
+		engine, err := newAlertEngine(config.AlertRules)
+		if err != nil {
+			a.abandon()
+			return nil, err
+		}
+		alerts = engine
+		mon.Subscribe(alerts.Observe)
+	}
+
+	if config.Notify != "" {
+		notifier, err := newNotifier(config.Notify)
+		if err != nil {
+			a.abandon()
+			return nil, err
+		}
+		notifier.SetLogf(a.reportError)
+		a.notifier = notifier
+		mon.SubscribeEvents(notifier.ProcessEvent)
+		if alerts != nil {
+			alerts.Subscribe(notifier.Alert)
+			notifier.SetAlertCoverage(alerts.Covers)
+		}
+	}
+
 	// Create UI
-	userInterface := ui.NewUI(mon)
+	if !config.Headless {
+		a.ui = ui.NewUI(mon)
+		a.ui.SetProcessActions(mon)
+		a.ui.SetProcessTuning(mon)
+		a.ui.SetFileDescriptors(mon)
+		a.ui.SetCommandLines(mon)
+		if a.audit != nil {
+			a.ui.SetAuditLog(a.audit)
+		}
+		if err := a.ui.SetColumns(config.Columns); err != nil {
+			a.abandon()
+			return nil, err
+		}
+		if alerts != nil {
+			a.ui.SetAlerts(alerts)
+		}
+	}
+
+	// Open the on-disk history; the monitor is still usable without it
+	if config.HistoryEnabled {
+		opts := storage.DefaultOptions(config.HistoryDir)
+		opts.Retention = config.HistoryRetention
+		opts.MaxBytes = config.HistoryMaxBytes
+
+		store, err := storage.Open(opts)
+		if err != nil {
+			a.reportError("History disabled: %v", err)
+		} else {
+			a.history = store
+			if a.ui != nil {
+				a.ui.SetHistory(store)
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// setupServer prepares the HTTP listener for the metrics endpoint, the JSON API
+// and the web dashboard
+func (a *App) setupServer(config Config) error {
+	prometheus, err := exporter.NewPrometheusExporter(a.monitor, exporter.SeriesOptions{
+		Labels:    config.MetricsLabels,
+		MaxSeries: config.MetricsMaxSeries,
+	})
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus)
+	api.NewServer(a.monitor).Register(mux)
+	web.Register(mux)
+
+	ln, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", config.Listen, err)
+	}
+	a.ln = ln
+	a.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Ends event streams when the app stops, so shutdown doesn't wait for them
+		BaseContext: func(net.Listener) context.Context { return a.ctx },
+	}
+	return nil
+}
+
+// setupRemoteWrite creates the remote-write client and feeds it every update
+func (a *App) setupRemoteWrite(config Config) error {
+	opts := exporter.DefaultRemoteWriteOptions(config.RemoteWriteURL)
+	opts.Series = exporter.SeriesOptions{
+		Labels:    config.MetricsLabels,
+		MaxSeries: config.MetricsMaxSeries,
+	}
+	opts.Interval = config.RemoteWriteInterval
+	opts.QueueDir = config.RemoteWriteQueueDir
+	opts.MaxQueueBytes = config.RemoteWriteMaxQueueBytes
+	if config.RemoteWriteRelabel != "" {
+		rules, err := exporter.LoadRelabelRules(config.RemoteWriteRelabel)
+		if err != nil {
+			return fmt.Errorf("failed to load relabeling rules: %w", err)
+		}
+		opts.Relabel = rules
+	}
+
+	remote, err := exporter.NewRemoteWriter(opts)
+	if err != nil {
+		return fmt.Errorf("failed to set up remote write: %w", err)
+	}
+	remote.SetLogf(a.reportError)
+	a.remote = remote
+	a.monitor.Subscribe(remote.Observe)
+	return nil
+}
+
+// newAlertEngine loads alert rules from a file
+func newAlertEngine(path string) (*alert.Engine, error) {
+	rules, err := alert.LoadRules(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert rules: %w", err)
+	}
+	return alert.NewEngine(rules)
+}
+
+// newNotifier loads notification channels from a file
+func newNotifier(path string) (*notify.Notifier, error) {
+	channels, err := notify.LoadChannels(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	notifier, err := notify.New(channels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	return notifier, nil
+}
+
+// NewReplayApp creates an application that plays back a recorded session
+// instead of monitoring the local host
+func NewReplayApp(path string) (*App, error) {
+	_, frames, err := session.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	player, err := session.NewPlayer(frames)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	userInterface := ui.NewUI(player.Monitor())
+	userInterface.SetPlayback(player)
+	player.SetOnUpdate(userInterface.Refresh)
 
 	return &App{
-		monitor: mon,
+		monitor: player.Monitor(),
+		player:  player,
 		ui:      userInterface,
 		ctx:     ctx,
 		cancel:  cancel,
 	}, nil
 }
 
+// NewConnectApp creates an application that shows the processes of remote
+// agents instead of monitoring the local host. A single agent must be
+// reachable right away; with several, the fleet view shows which are not.
+// Alert rules, if given, are evaluated on the received snapshots, and the
+// actions sent to the agents are recorded in the audit log, if given.
+func NewConnectApp(agents []remote.ClientOptions, alertRules, auditLog string) (*App, error) {
+	var alerts *alert.Engine
+	if alertRules != "" {
+		engine, err := newAlertEngine(alertRules)
+		if err != nil {
+			return nil, err
+		}
+		alerts = engine
+	}
+
+	var log *audit.Log
+	if auditLog != "" {
+		var err error
+		if log, err = audit.Open(auditLog); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &App{
+		clients: make([]*remote.Client, 0, len(agents)),
+		audit:   log,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for _, opts := range agents {
+		var client *remote.Client
+		var err error
+		if len(agents) == 1 {
+			client, err = remote.Dial(ctx, opts)
+		} else {
+			client, err = remote.NewClient(opts)
+		}
+		if err != nil {
+			a.abandon()
+			return nil, fmt.Errorf("%s: %w", opts.Address, err)
+		}
+		a.clients = append(a.clients, client)
+	}
+	clients := a.clients
+
+	userInterface := ui.NewUI(clients[0].Monitor())
+	if log != nil {
+		userInterface.SetAuditLog(log)
+	}
+	if len(clients) == 1 {
+		userInterface.SetProcessActions(clients[0])
+		userInterface.SetProcessTuning(clients[0])
+		userInterface.SetRemote(clients[0])
+	} else {
+		hosts := make([]ui.Host, len(clients))
+		for i, client := range clients {
+			hosts[i] = ui.Host{Monitor: client.Monitor(), Actions: client, Tuning: client, Remote: client}
+		}
+		userInterface.SetFleet(hosts)
+	}
+	for _, client := range clients {
+		client.SetOnUpdate(userInterface.Refresh)
+	}
+
+	if alerts != nil {
+		if len(clients) == 1 {
+			clients[0].Monitor().Subscribe(alerts.Observe)
+		} else {
+			for _, client := range clients {
+				client.Monitor().Subscribe(alerts.HostObserver(client.State().Address))
+			}
+		}
+		userInterface.SetAlerts(alerts)
+	}
+
+	a.monitor = clients[0].Monitor()
+	a.ui = userInterface
+	return a, nil
+}
+
 // Run starts the application
 func (a *App) Run() error {
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start monitoring goroutine
+	// Start monitoring goroutine, or play back the recording, or receive
+	// snapshots from the agents
 	a.wg.Add(1)
-	go a.monitoringLoop()
+	if a.player != nil {
+		go a.playbackLoop()
+	} else if a.clients != nil {
+		go a.connectLoop()
+	} else {
+		go a.monitoringLoop()
+	}
 
 	// Start cleanup goroutine
 	a.wg.Add(1)
 	go a.cleanupLoop()
 
+	// Push samples to the remote-write endpoint
+	if a.remote != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.remote.Run(a.ctx)
+		}()
+	}
+
+	// Export to the OpenTelemetry collector
+	if a.otlp != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.otlp.Run(a.ctx)
+		}()
+	}
+
+	// Write to the Influx and Graphite sinks
+	for _, s := range a.sinks {
+		a.wg.Add(1)
+		go func(s *sink.Sink) {
+			defer a.wg.Done()
+			s.Run(a.ctx)
+		}(s)
+	}
+
+	// Stream snapshots to remote viewers
+	if a.agent != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			if err := a.agent.Serve(a.ctx); err != nil {
+				a.reportError("Agent error: %v", err)
+			}
+		}()
+	}
+
+	// Deliver notifications
+	if a.notifier != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.notifier.Run(a.ctx)
+		}()
+	}
+
+	// Serve HTTP endpoints
+	if a.server != nil {
+		go func() {
+			if err := a.server.Serve(a.ln); err != nil && err != http.ErrServerClosed {
+				a.reportError("HTTP server error: %v", err)
+			}
+		}()
+	}
+
 	// Handle graceful shutdown
 	go func() {
 		<-sigChan
-		fmt.Println("\nReceived interrupt signal, shutting down...")
+		if a.ui == nil {
+			fmt.Println("\nReceived interrupt signal, shutting down...")
+		}
 		a.Stop()
 	}()
 
-	// Run UI (blocks until UI is closed)
-	err := a.ui.Run(a.ctx)
+	// Run UI (blocks until UI is closed), or wait for a signal when headless
+	var err error
+	if a.ui != nil {
+		err = a.ui.Run(a.ctx)
+	} else {
+		<-a.ctx.Done()
+	}
 
 	// Stop everything and wait for goroutines to finish
 	a.Stop()
 	a.wg.Wait()
 
+	if a.server != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = a.server.Shutdown(shutdownCtx)
+		cancel()
+	}
+
+	if a.history != nil {
+		if closeErr := a.history.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close history: %w", closeErr)
+		}
+	}
+
+	if a.audit != nil {
+		if closeErr := a.audit.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close audit log: %w", closeErr)
+		}
+	}
+
 	return err
 }
 
 // Stop stops the application
 func (a *App) Stop() {
 	a.cancel()
-	a.ui.Stop()
+	if a.ui != nil {
+		a.ui.Stop()
+	}
 }
 
 // monitoringLoop continuously updates system and process metrics
@@ -89,8 +583,10 @@ func (a *App) monitoringLoop() {
 
 	// Initial update
 	if err := a.monitor.UpdateMetrics(a.ctx); err != nil {
-		fmt.Printf("Error updating metrics: %v\n", err)
+		a.reportError("Error updating metrics: %v", err)
 	}
+	a.recordHistory()
+	a.feedSinks()
 
 	for {
 		select {
@@ -99,17 +595,125 @@ func (a *App) monitoringLoop() {
 		case <-systemTicker.C:
 			// Update only system metrics (lightweight)
 			if err := a.updateSystemMetricsOnly(); err != nil {
-				fmt.Printf("Error updating system metrics: %v\n", err)
+				a.reportError("Error updating system metrics: %v", err)
 			}
 		case <-processTicker.C:
 			// Full update including processes (heavier)
 			if err := a.monitor.UpdateMetrics(a.ctx); err != nil {
-				fmt.Printf("Error updating metrics: %v\n", err)
+				a.reportError("Error updating metrics: %v", err)
 			}
+			a.recordHistory()
+			a.feedSinks()
 		}
 	}
 }
 
+// recordHistory appends the latest system and process samples to the on-disk history
+func (a *App) recordHistory() {
+	if a.history == nil {
+		return
+	}
+
+	system := a.monitor.GetSystemMetrics()
+	if system.Timestamp.IsZero() {
+		return
+	}
+	mode := a.monitor.GetCPUMode()
+
+	processes := a.monitor.GetProcesses()
+	snapshot := storage.Snapshot{
+		System: storage.SystemRecord{
+			Timestamp:     system.Timestamp,
+			CPUPercent:    system.CPUPercent,
+			MemoryPercent: system.MemoryPercent,
+			UsedMemoryMB:  system.UsedMemoryMB,
+			TotalMemoryMB: system.TotalMemoryMB,
+			DiskReadRate:  system.DiskReadRate,
+			DiskWriteRate: system.DiskWriteRate,
+		},
+		Processes: make([]storage.ProcessRecord, 0, len(processes)),
+	}
+	for _, proc := range processes {
+		snapshot.Processes = append(snapshot.Processes, storage.ProcessRecord{
+			Key:  proc.Key(),
+			Name: proc.Name,
+			Sample: monitor.MetricSample{
+				Timestamp:     system.Timestamp,
+				CPUPercent:    mode.ToPerCore(proc.CPUPercent, system.NumCPU),
+				MemoryMB:      proc.MemoryMB,
+				DiskReadRate:  proc.DiskReadRate,
+				DiskWriteRate: proc.DiskWriteRate,
+				DiskReadPerc:  proc.DiskReadPerc,
+				DiskWritePerc: proc.DiskWritePerc,
+				NetSentRate:   proc.NetSentRate,
+				NetRecvRate:   proc.NetRecvRate,
+			},
+		})
+	}
+
+	if err := a.history.Append(snapshot); err != nil {
+		a.reportError("Error recording history: %v", err)
+	}
+}
+
+// feedSinks hands the latest update to every sink. Sinks only keep a
+// reference, so this never waits for a backend.
+func (a *App) feedSinks() {
+	if len(a.sinks) == 0 {
+		return
+	}
+
+	update := monitor.Update{
+		System:    a.monitor.GetSystemMetrics(),
+		Processes: a.monitor.GetProcesses(),
+		CPUMode:   a.monitor.GetCPUMode(),
+	}
+	for _, s := range a.sinks {
+		s.Offer(update)
+	}
+}
+
+// abandon releases what an app that never ran holds: its listeners, sinks,
+// audit log and agent connections
+func (a *App) abandon() {
+	a.cancel()
+	if a.ln != nil {
+		a.ln.Close()
+	}
+	if a.agent != nil {
+		a.agent.Close()
+	}
+	for _, s := range a.sinks {
+		s.Close()
+	}
+	if a.audit != nil {
+		a.audit.Close()
+	}
+	for _, client := range a.clients {
+		client.Close()
+	}
+}
+
+// playbackLoop feeds recorded frames into the monitor
+func (a *App) playbackLoop() {
+	defer a.wg.Done()
+	a.player.Run(a.ctx)
+}
+
+// connectLoop receives snapshots from every agent
+func (a *App) connectLoop() {
+	defer a.wg.Done()
+	var wg sync.WaitGroup
+	for _, client := range a.clients {
+		wg.Add(1)
+		go func(client *remote.Client) {
+			defer wg.Done()
+			client.Run(a.ctx)
+		}(client)
+	}
+	wg.Wait()
+}
+
 // updateSystemMetricsOnly updates just the lightweight system metrics
 func (a *App) updateSystemMetricsOnly() error {
 	// This could be optimized to only update system-level metrics
@@ -129,7 +733,29 @@ func (a *App) cleanupLoop() {
 		case <-a.ctx.Done():
 			return
 		case <-ticker.C:
-			a.monitor.CleanupOldMetrics()
+			a.cleanupMetrics()
 		}
 	}
 }
+
+// cleanupMetrics drops the time series of exited processes, on every agent's
+// monitor when viewing remote agents
+func (a *App) cleanupMetrics() {
+	if len(a.clients) == 0 {
+		a.monitor.CleanupOldMetrics()
+		return
+	}
+	for _, client := range a.clients {
+		client.Monitor().CleanupOldMetrics()
+	}
+}
+
+// reportError shows an error of a background task in the status bar, or on
+// stderr when running headless, where it does not garble the screen
+func (a *App) reportError(format string, args ...any) {
+	if a.ui != nil {
+		a.ui.ReportError(fmt.Sprintf(format, args...))
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+	"hyperbyte-proc-monitor/internal/remote"
+)
+
+// writeTestTLS writes a self-signed certificate that is its own CA
+func writeTestTLS(t *testing.T) remote.TLSFiles {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "viewer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := remote.TLSFiles{Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem"), CA: filepath.Join(dir, "cert.pem")}
+	if err := os.WriteFile(files.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(files.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestCleanupCoversEveryAgent(t *testing.T) {
+	files := writeTestTLS(t)
+	exited := monitor.ProcessInfo{PID: 200, Name: "exited", CreateTime: testStart}
+	running := monitor.ProcessInfo{PID: 100, Name: "running", CreateTime: testStart}
+
+	a := &App{}
+	for _, address := range []string{"127.0.0.1:7001", "127.0.0.1:7002", "127.0.0.1:7003"} {
+		client, err := remote.NewClient(remote.ClientOptions{Address: address, TLS: files})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		mon := client.Monitor()
+		mon.ApplySnapshot(monitor.SystemMetrics{Timestamp: testStart}, []monitor.ProcessInfo{running, exited}, nil)
+		mon.ApplySnapshot(monitor.SystemMetrics{Timestamp: testStart.Add(time.Second)}, []monitor.ProcessInfo{running}, nil)
+		a.clients = append(a.clients, client)
+	}
+	a.monitor = a.clients[0].Monitor()
+
+	a.cleanupMetrics()
+	for _, client := range a.clients {
+		mon := client.Monitor()
+		if len(mon.GetProcessMetrics(exited.Key())) != 0 {
+			t.Errorf("%s: time series of the exited process kept", client.State().Address)
+		}
+		if len(mon.GetProcessMetrics(running.Key())) == 0 {
+			t.Errorf("%s: time series of the running process dropped", client.State().Address)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// BatchConfig holds the settings of the headless batch mode
+type BatchConfig struct {
+	Format   string // "jsonl" or "csv"
+	Interval time.Duration
+	Count    int      // Number of snapshots, zero runs until interrupted
+	Fields   []string // Process fields to emit, empty for all
+	Filter   string   // Case-insensitive match on process name or PID
+	SortBy   monitor.SortBy
+	SortDesc bool
+	Top      int // Maximum processes per snapshot, zero for all
+}
+
+// batchField is one process column of the batch output
+type batchField struct {
+	name  string
+	value func(p monitor.ProcessInfo) any
+}
+
+// batchFields lists every process field available in batch mode, in default order
+var batchFields = []batchField{
+	{"pid", func(p monitor.ProcessInfo) any { return p.PID }},
+	{"ppid", func(p monitor.ProcessInfo) any { return p.PPID }},
+	{"name", func(p monitor.ProcessInfo) any { return p.Name }},
+	{"cpu_percent", func(p monitor.ProcessInfo) any { return p.CPUPercent }},
+	{"memory_mb", func(p monitor.ProcessInfo) any { return p.MemoryMB }},
+	{"memory_percent", func(p monitor.ProcessInfo) any { return p.MemoryPerc }},
+	{"create_time", func(p monitor.ProcessInfo) any { return p.CreateTime.Format(time.RFC3339) }},
+	{"user", func(p monitor.ProcessInfo) any { return p.Username }},
+	{"state", func(p monitor.ProcessInfo) any { return p.State }},
+	{"threads", func(p monitor.ProcessInfo) any { return p.Threads }},
+	{"nice", func(p monitor.ProcessInfo) any { return p.Nice }},
+	{"priority", func(p monitor.ProcessInfo) any { return p.Priority }},
+	{"tty", func(p monitor.ProcessInfo) any { return p.TTY }},
+	{"cwd", func(p monitor.ProcessInfo) any { return p.Cwd }},
+	{"exe", func(p monitor.ProcessInfo) any { return p.Exe }},
+	{"cmdline", func(p monitor.ProcessInfo) any { return p.Cmdline }},
+	{"disk_read_kb", func(p monitor.ProcessInfo) any { return p.DiskReadKB }},
+	{"disk_write_kb", func(p monitor.ProcessInfo) any { return p.DiskWriteKB }},
+	{"disk_read_kbps", func(p monitor.ProcessInfo) any { return p.DiskReadRate }},
+	{"disk_write_kbps", func(p monitor.ProcessInfo) any { return p.DiskWriteRate }},
+	{"disk_read_percent", func(p monitor.ProcessInfo) any { return p.DiskReadPerc }},
+	{"disk_write_percent", func(p monitor.ProcessInfo) any { return p.DiskWritePerc }},
+	{"net_sent_kb", func(p monitor.ProcessInfo) any { return p.NetSentKB }},
+	{"net_recv_kb", func(p monitor.ProcessInfo) any { return p.NetRecvKB }},
+	{"net_sent_kbps", func(p monitor.ProcessInfo) any { return p.NetSentRate }},
+	{"net_recv_kbps", func(p monitor.ProcessInfo) any { return p.NetRecvRate }},
+	{"ctx_switches_ps", func(p monitor.ProcessInfo) any { return p.CtxSwitchRate }},
+	{"minor_faults_ps", func(p monitor.ProcessInfo) any { return p.MinorFaultPS }},
+	{"major_faults_ps", func(p monitor.ProcessInfo) any { return p.MajorFaultPS }},
+}
+
+// BatchFieldNames returns the names accepted by BatchConfig.Fields
+func BatchFieldNames() []string {
+	names := make([]string, len(batchFields))
+	for i, field := range batchFields {
+		names[i] = field.name
+	}
+	return names
+}
+
+// selectBatchFields resolves field names, returning all fields for an empty list
+func selectBatchFields(names []string) ([]batchField, error) {
+	if len(names) == 0 {
+		return batchFields, nil
+	}
+
+	selected := make([]batchField, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, field := range batchFields {
+			if field.name == name {
+				selected = append(selected, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(BatchFieldNames(), ","))
+		}
+	}
+	return selected, nil
+}
+
+// snapshotWriter emits one monitor snapshot in a batch output format
+type snapshotWriter interface {
+	WriteSnapshot(system monitor.SystemMetrics, processes []monitor.ProcessInfo) error
+}
+
+// RunBatch samples the local host on every interval and writes each snapshot to out
+func RunBatch(config BatchConfig, out io.Writer) error {
+	fields, err := selectBatchFields(config.Fields)
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriter(out)
+	var writer snapshotWriter
+	switch config.Format {
+	case "jsonl":
+		writer = &jsonlWriter{out: buffered, fields: fields}
+	case "csv":
+		writer = &csvWriter{out: csv.NewWriter(buffered), fields: fields}
+	default:
+		return fmt.Errorf("unknown format %q (available: jsonl, csv)", config.Format)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	mon := monitor.NewMonitor()
+	mon.SetSorting(config.SortBy, config.SortDesc)
+	mon.SetProcessFilter(config.Filter)
+
+	// The first update only establishes the baselines rates are computed from
+	if err := mon.UpdateMetrics(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for emitted := 0; config.Count <= 0 || emitted < config.Count; emitted++ {
+		select {
+		case <-ctx.Done():
+			return buffered.Flush()
+		case <-ticker.C:
+		}
+
+		if err := mon.UpdateMetrics(ctx); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+
+		processes := mon.GetProcesses()
+		if config.Top > 0 && len(processes) > config.Top {
+			processes = processes[:config.Top]
+		}
+		if err := writer.WriteSnapshot(mon.GetSystemMetrics(), processes); err != nil {
+			return err
+		}
+		// Flush every snapshot so consumers reading a pipe see it right away
+		if err := buffered.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return buffered.Flush()
+}
+
+// jsonlWriter writes one JSON object per snapshot and line
+type jsonlWriter struct {
+	out    io.Writer
+	fields []batchField
+}
+
+// jsonSystem is the system part of a JSON Lines snapshot
+type jsonSystem struct {
+	CPUPercent    float64    `json:"cpu_percent"`
+	NumCPU        int        `json:"num_cpu"`
+	MemoryPercent float64    `json:"memory_percent"`
+	TotalMemoryMB float64    `json:"total_memory_mb"`
+	UsedMemoryMB  float64    `json:"used_memory_mb"`
+	DiskReadRate  float64    `json:"disk_read_kbps"`
+	DiskWriteRate float64    `json:"disk_write_kbps"`
+	Disks         []jsonDisk `json:"disks"`
+}
+
+// jsonDisk is one block device of a JSON Lines snapshot
+type jsonDisk struct {
+	Name        string  `json:"name"`
+	ReadRate    float64 `json:"read_kbps"`
+	WriteRate   float64 `json:"write_kbps"`
+	ReadIOPS    float64 `json:"read_iops"`
+	WriteIOPS   float64 `json:"write_iops"`
+	BusyPercent float64 `json:"busy_percent"`
+}
+
+func (w *jsonlWriter) WriteSnapshot(system monitor.SystemMetrics, processes []monitor.ProcessInfo) error {
+	sys := jsonSystem{
+		CPUPercent:    system.CPUPercent,
+		NumCPU:        system.NumCPU,
+		MemoryPercent: system.MemoryPercent,
+		TotalMemoryMB: system.TotalMemoryMB,
+		UsedMemoryMB:  system.UsedMemoryMB,
+		DiskReadRate:  system.DiskReadRate,
+		DiskWriteRate: system.DiskWriteRate,
+		Disks:         make([]jsonDisk, 0, len(system.Disks)),
+	}
+	for _, disk := range system.Disks {
+		sys.Disks = append(sys.Disks, jsonDisk(disk))
+	}
+	sysJSON, err := json.Marshal(sys)
+	if err != nil {
+		return err
+	}
+
+	// Process objects are assembled by hand to keep the selected field order
+	var line strings.Builder
+	fmt.Fprintf(&line, `{"timestamp":%q,"system":%s,"processes":[`, system.Timestamp.Format(time.RFC3339Nano), sysJSON)
+	for i, proc := range processes {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		line.WriteByte('{')
+		for j, field := range w.fields {
+			value, err := json.Marshal(field.value(proc))
+			if err != nil {
+				return err
+			}
+			if j > 0 {
+				line.WriteByte(',')
+			}
+			fmt.Fprintf(&line, "%q:%s", field.name, value)
+		}
+		line.WriteByte('}')
+	}
+	line.WriteString("]}\n")
+
+	_, err = io.WriteString(w.out, line.String())
+	return err
+}
+
+// csvWriter writes one row per process, repeating the system columns on each row
+type csvWriter struct {
+	out           *csv.Writer
+	fields        []batchField
+	headerWritten bool
+}
+
+// csvSystemColumns are the system columns leading every CSV row
+var csvSystemColumns = []string{"timestamp", "system_cpu_percent", "system_memory_percent", "system_disk_read_kbps", "system_disk_write_kbps"}
+
+func (w *csvWriter) WriteSnapshot(system monitor.SystemMetrics, processes []monitor.ProcessInfo) error {
+	if !w.headerWritten {
+		header := append([]string(nil), csvSystemColumns...)
+		for _, field := range w.fields {
+			header = append(header, field.name)
+		}
+		if err := w.out.Write(header); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	systemColumns := []string{
+		system.Timestamp.Format(time.RFC3339Nano),
+		formatCSVValue(system.CPUPercent),
+		formatCSVValue(system.MemoryPercent),
+		formatCSVValue(system.DiskReadRate),
+		formatCSVValue(system.DiskWriteRate),
+	}
+	for _, proc := range processes {
+		row := append([]string(nil), systemColumns...)
+		for _, field := range w.fields {
+			row = append(row, formatCSVValue(field.value(proc)))
+		}
+		if err := w.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.out.Flush()
+	return w.out.Error()
+}
+
+// formatCSVValue renders a field value for CSV output
+func formatCSVValue(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', 2, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+var batchSystem = monitor.SystemMetrics{
+	Timestamp:     testStart,
+	CPUPercent:    42.5,
+	NumCPU:        4,
+	MemoryPercent: 50,
+	TotalMemoryMB: 8192,
+	UsedMemoryMB:  4096,
+	DiskReadRate:  100,
+	DiskWriteRate: 12.345,
+	Disks:         []monitor.DiskDeviceMetrics{{Name: "sda", ReadRate: 100, WriteRate: 12.345, ReadIOPS: 5, WriteIOPS: 1, BusyPercent: 3}},
+}
+
+var batchProcesses = []monitor.ProcessInfo{
+	{PID: 100, PPID: 1, Name: "postgres", CPUPercent: 12.5, MemoryMB: 256, MemoryPerc: 3.125, CreateTime: testStart.Add(-time.Hour), Username: "postgres"},
+	{PID: 200, PPID: 100, Name: `worker, "io"`, CPUPercent: 0.004, MemoryMB: 1.5},
+}
+
+// writeBatch renders the test snapshot with the given format and fields
+func writeBatch(t *testing.T, format string, fields []string) string {
+	t.Helper()
+	selected, err := selectBatchFields(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	var writer snapshotWriter
+	if format == "csv" {
+		writer = &csvWriter{out: csv.NewWriter(&out), fields: selected}
+	} else {
+		writer = &jsonlWriter{out: &out, fields: selected}
+	}
+	for i := 0; i < 2; i++ {
+		if err := writer.WriteSnapshot(batchSystem, batchProcesses); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out.String()
+}
+
+func TestBatchJSONL(t *testing.T) {
+	got := writeBatch(t, "jsonl", []string{"pid", "name", "cpu_percent", "memory_percent", "create_time"})
+	line := `{"timestamp":"2026-10-16T09:00:00Z",` +
+		`"system":{"cpu_percent":42.5,"num_cpu":4,"memory_percent":50,"total_memory_mb":8192,"used_memory_mb":4096,"disk_read_kbps":100,"disk_write_kbps":12.345,` +
+		`"disks":[{"name":"sda","read_kbps":100,"write_kbps":12.345,"read_iops":5,"write_iops":1,"busy_percent":3}]},` +
+		`"processes":[{"pid":100,"name":"postgres","cpu_percent":12.5,"memory_percent":3.125,"create_time":"2026-10-16T08:00:00Z"},` +
+		`{"pid":200,"name":"worker, \"io\"","cpu_percent":0.004,"memory_percent":0,"create_time":"0001-01-01T00:00:00Z"}]}` + "\n"
+	if want := line + line; got != want {
+		t.Errorf("jsonl output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBatchCSV(t *testing.T) {
+	got := writeBatch(t, "csv", []string{"name", "pid", "cpu_percent", "memory_percent"})
+	rows := `2026-10-16T09:00:00Z,42.50,50.00,100.00,12.35,postgres,100,12.50,3.12
+2026-10-16T09:00:00Z,42.50,50.00,100.00,12.35,"worker, ""io""",200,0.00,0.00
+`
+	// The header is written once, before the first snapshot
+	want := "timestamp,system_cpu_percent,system_memory_percent,system_disk_read_kbps,system_disk_write_kbps,name,pid,cpu_percent,memory_percent\n" + rows + rows
+	if got != want {
+		t.Errorf("csv output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBatchAllFieldsByDefault(t *testing.T) {
+	header, _, _ := strings.Cut(writeBatch(t, "csv", nil), "\n")
+	want := strings.Join(append(append([]string(nil), csvSystemColumns...), BatchFieldNames()...), ",")
+	if header != want {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+}
+
+func TestBatchRejectsUnknownFields(t *testing.T) {
+	_, err := selectBatchFields([]string{"pid", "colour"})
+	if err == nil || !strings.Contains(err.Error(), `"colour"`) {
+		t.Errorf("selectBatchFields returned %v, want the unknown field named", err)
+	}
+	if err := RunBatch(BatchConfig{Format: "jsonl", Fields: []string{"colour"}}, &bytes.Buffer{}); err == nil {
+		t.Error("RunBatch accepted an unknown field")
+	}
+	if err := RunBatch(BatchConfig{Format: "xml"}, &bytes.Buffer{}); err == nil {
+		t.Error("RunBatch accepted an unknown format")
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+	"hyperbyte-proc-monitor/internal/session"
+)
+
+// RecordConfig holds the settings of a headless recording
+type RecordConfig struct {
+	Output   string
+	Interval time.Duration
+	Duration time.Duration // Zero records until interrupted
+}
+
+// Record captures every monitor update to a session file until interrupted
+// or the configured duration has passed
+func Record(config RecordConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	hostname, _ := os.Hostname()
+	writer, err := session.Create(config.Output, session.Header{
+		Hostname: hostname,
+		Started:  time.Now(),
+		Interval: config.Interval,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+
+	mon := monitor.NewMonitor()
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	fmt.Fprintf(os.Stderr, "Recording to %s every %s, press Ctrl+C to stop\n", config.Output, config.Interval)
+
+	frames := 0
+	for {
+		if err := mon.UpdateMetrics(ctx); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "Error updating metrics: %v\n", err)
+		} else {
+			if err := writer.WriteFrame(captureFrame(mon)); err != nil {
+				writer.Close()
+				return fmt.Errorf("failed to write session frame: %w", err)
+			}
+			frames++
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close session file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Recorded %d frames to %s\n", frames, config.Output)
+	return nil
+}
+
+// captureFrame copies the current monitor state, including the processes
+// outside the top list, into a session frame with per-core CPU usage
+func captureFrame(mon *monitor.Monitor) session.Frame {
+	system := mon.GetSystemMetrics()
+	processes := mon.GetProcesses()
+
+	mode := mon.GetCPUMode()
+	top := make(map[monitor.ProcessKey]bool, len(processes))
+	for i := range processes {
+		processes[i].CPUPercent = mode.ToPerCore(processes[i].CPUPercent, system.NumCPU)
+		top[processes[i].Key()] = true
+	}
+	var others []monitor.ProcessInfo
+	for _, proc := range mon.GetAllProcesses() {
+		if !top[proc.Key()] {
+			proc.CPUPercent = mode.ToPerCore(proc.CPUPercent, system.NumCPU)
+			others = append(others, proc)
+		}
+	}
+	return session.Frame{System: system, Processes: processes, Others: others}
+}
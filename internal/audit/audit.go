@@ -0,0 +1,106 @@
+// Package audit records actions taken on processes, such as signals, to an
+// append-only log file with one JSON object per line.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Results of an action
+const (
+	ResultOK     = "ok"
+	ResultFailed = "failed"
+	ResultDenied = "denied" // Refused by an agent's policy
+)
+
+// Entry is an action as written to the audit log
+type Entry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`                // Who ran pulse
+	SudoUser string    `json:"sudo_user,omitempty"` // Who ran pulse through sudo
+	Host     string    `json:"host"`                // Where the process runs
+	Agent    string    `json:"agent,omitempty"`     // Agent the action was sent through
+	Viewer   string    `json:"viewer,omitempty"`    // Viewer that asked this agent
+
+	PID       int32     `json:"pid"`
+	StartTime time.Time `json:"start_time"`
+	Name      string    `json:"name"`
+	Cmdline   string    `json:"cmdline,omitempty"`
+
+	Action   string `json:"action"`              // e.g. "signal"
+	Argument string `json:"argument"`            // e.g. "SIGTERM"
+	TreeRoot int32  `json:"tree_root,omitempty"` // Set when the action went to a whole process tree
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Log appends entries to an audit log file
+type Log struct {
+	mu       sync.Mutex
+	file     *os.File
+	user     string
+	sudoUser string
+	host     string
+}
+
+// Open opens an audit log for appending, creating it and its directory if needed
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	l := &Log{file: file, sudoUser: os.Getenv("SUDO_USER")}
+	l.host, _ = os.Hostname()
+	if current, err := user.Current(); err == nil {
+		l.user = current.Username
+	} else {
+		l.user = strconv.Itoa(os.Getuid())
+	}
+	return l, nil
+}
+
+// Record appends an entry. Time, user and, if empty, host are filled in. The
+// line is synced to disk before returning, so it survives a crash right after
+// the action.
+func (l *Log) Record(entry Entry) error {
+	entry.Time = time.Now()
+	entry.User = l.user
+	entry.SudoUser = l.sudoUser
+	if entry.Host == "" {
+		entry.Host = l.host
+	}
+
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false) // Keep command lines readable
+	if err := encoder.Encode(entry); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// A single write keeps lines whole when several pulse instances share the file
+	if _, err := l.file.Write(line.Bytes()); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
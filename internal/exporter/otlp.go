@@ -0,0 +1,290 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// OTLP/HTTP encodings
+const (
+	OTLPProtobuf = "http/protobuf"
+	OTLPJSON     = "http/json"
+)
+
+// otlpScopeName identifies pulse as the instrumentation scope
+const otlpScopeName = "hyperbyte-pulse"
+
+// otlpMaxAttempts bounds the retries of one export; the next interval
+// brings fresh values anyway
+const otlpMaxAttempts = 3
+
+// OTLPOptions configures the OTLP exporter
+type OTLPOptions struct {
+	Endpoint string            // Collector URL; /v1/metrics is appended when it has no path
+	Protocol string            // OTLPProtobuf or OTLPJSON
+	Headers  map[string]string // Extra request headers, e.g. for authentication
+	Interval time.Duration     // How often metrics are exported
+	Timeout  time.Duration     // Per request
+}
+
+// DefaultOTLPOptions returns the settings used for the given endpoint
+func DefaultOTLPOptions(endpoint string) OTLPOptions {
+	return OTLPOptions{
+		Endpoint: endpoint,
+		Protocol: OTLPProtobuf,
+		Interval: 15 * time.Second,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// OTLPExporter periodically pushes monitor data to an OpenTelemetry collector
+// over OTLP/HTTP, using the semantic conventions for host and process metrics.
+// Every running process becomes a resource of its own, identified by process.pid.
+type OTLPExporter struct {
+	monitor  *monitor.Monitor
+	opts     OTLPOptions
+	url      string
+	hostname string
+	client   *http.Client
+	failing  bool // Whether the last export failed, to log only state changes
+	logf     func(format string, args ...any)
+}
+
+// NewOTLPExporter creates an exporter for the given monitor. Call Run to start exporting.
+func NewOTLPExporter(mon *monitor.Monitor, opts OTLPOptions) (*OTLPExporter, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", opts.Endpoint)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = "/v1/metrics"
+	}
+	if opts.Protocol != OTLPProtobuf && opts.Protocol != OTLPJSON {
+		return nil, fmt.Errorf("unknown OTLP protocol %q (available: %s, %s)", opts.Protocol, OTLPProtobuf, OTLPJSON)
+	}
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("OTLP interval must be positive")
+	}
+
+	hostname, _ := os.Hostname()
+	return &OTLPExporter{
+		monitor:  mon,
+		opts:     opts,
+		url:      endpoint.String(),
+		hostname: hostname,
+		client:   &http.Client{Timeout: opts.Timeout},
+		logf:     logToStderr,
+	}, nil
+}
+
+// SetLogf sets where export problems are reported, stderr by default.
+// Call it before Run.
+func (e *OTLPExporter) SetLogf(logf func(format string, args ...any)) {
+	e.logf = logf
+}
+
+// Run exports metrics every interval until the context is cancelled
+func (e *OTLPExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.export(ctx)
+		}
+	}
+}
+
+// export sends the current state, retrying temporary failures a few times
+func (e *OTLPExporter) export(ctx context.Context) {
+	system := e.monitor.GetSystemMetrics()
+	if system.Timestamp.IsZero() {
+		return
+	}
+	detailed := make(map[monitor.ProcessKey]bool)
+	for _, proc := range e.monitor.GetProcesses() {
+		detailed[proc.Key()] = true
+	}
+	request := e.buildRequest(system, e.monitor.GetAllProcesses(), detailed, e.monitor.GetCPUMode())
+
+	var body []byte
+	contentType := "application/x-protobuf"
+	if e.opts.Protocol == OTLPJSON {
+		contentType = "application/json"
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			e.logf("OTLP export: %v", err)
+			return
+		}
+	} else {
+		body = request.marshalProto()
+	}
+
+	delay := minRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := e.send(ctx, body, contentType)
+		if err == nil {
+			if e.failing {
+				e.logf("OTLP export: %s reachable again", e.url)
+				e.failing = false
+			}
+			return
+		}
+		if !retry || attempt == otlpMaxAttempts {
+			if !e.failing {
+				e.logf("OTLP export: %v", err)
+				e.failing = true
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// send posts one export request and reports whether a failure is worth retrying
+func (e *OTLPExporter) send(ctx context.Context, body []byte, contentType string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "hyperbyte-pulse")
+	for name, value := range e.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return false, nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, fmt.Errorf("collector returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("collector returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+}
+
+// buildRequest maps a monitor snapshot to OTLP: one resource for the host
+// and one per process. Network counters are only known for the detailed
+// processes; the others would appear to reset whenever they leave the top list.
+func (e *OTLPExporter) buildRequest(system monitor.SystemMetrics, processes []monitor.ProcessInfo, detailed map[monitor.ProcessKey]bool, mode monitor.CPUMode) *otlpRequest {
+	now := uint64(system.Timestamp.UnixNano())
+	scope := otlpScope{Name: otlpScopeName}
+	gaugePoint := func(value float64, attributes ...otlpKeyValue) otlpDataPoint {
+		return otlpDataPoint{Attributes: attributes, TimeUnixNano: now, AsDouble: value}
+	}
+
+	host := []otlpKeyValue{stringAttr("host.name", e.hostname)}
+	request := &otlpRequest{ResourceMetrics: make([]otlpResourceMetrics, 0, len(processes)+1)}
+	request.ResourceMetrics = append(request.ResourceMetrics, otlpResourceMetrics{
+		Resource: otlpResource{Attributes: host},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: scope, Metrics: []otlpMetric{
+			{
+				Name: "system.cpu.utilization", Unit: "1",
+				Description: "Share of CPU time spent busy, averaged over all logical CPUs.",
+				Gauge:       &otlpGauge{DataPoints: []otlpDataPoint{gaugePoint(system.CPUPercent / 100)}},
+			},
+			{
+				Name: "system.cpu.logical.count", Unit: "{cpu}",
+				Description: "Number of logical CPUs.",
+				Sum:         &otlpSum{DataPoints: []otlpDataPoint{gaugePoint(float64(system.NumCPU))}, AggregationTemporality: otlpTemporalityCumulative},
+			},
+			{
+				Name: "system.memory.usage", Unit: "By",
+				Description: "Physical memory in use and free.",
+				Sum: &otlpSum{DataPoints: []otlpDataPoint{
+					gaugePoint(system.UsedMemoryMB*1024*1024, stringAttr("system.memory.state", "used")),
+					gaugePoint((system.TotalMemoryMB-system.UsedMemoryMB)*1024*1024, stringAttr("system.memory.state", "free")),
+				}, AggregationTemporality: otlpTemporalityCumulative},
+			},
+			{
+				Name: "system.memory.utilization", Unit: "1",
+				Description: "Share of physical memory in use.",
+				Gauge:       &otlpGauge{DataPoints: []otlpDataPoint{gaugePoint(system.MemoryPercent/100, stringAttr("system.memory.state", "used"))}},
+			},
+		}}},
+	})
+
+	numCPU := system.NumCPU
+	if numCPU < 1 {
+		numCPU = 1
+	}
+	for _, proc := range processes {
+		attributes := append([]otlpKeyValue{
+			intAttr("process.pid", int64(proc.PID)),
+			stringAttr("process.executable.name", proc.Name),
+		}, host...)
+		if proc.Username != "" {
+			attributes = append(attributes, stringAttr("process.owner", proc.Username))
+		}
+
+		// Cumulative counters start with the process
+		start := uint64(proc.CreateTime.UnixNano())
+		counterPoint := func(value float64, attributes ...otlpKeyValue) otlpDataPoint {
+			return otlpDataPoint{Attributes: attributes, StartTimeUnixNano: start, TimeUnixNano: now, AsDouble: value}
+		}
+
+		metrics := []otlpMetric{
+			{
+				Name: "process.cpu.utilization", Unit: "1",
+				Description: "Share of CPU time used by the process, relative to all logical CPUs.",
+				Gauge:       &otlpGauge{DataPoints: []otlpDataPoint{gaugePoint(mode.ToPerCore(proc.CPUPercent, numCPU) / 100 / float64(numCPU))}},
+			},
+			{
+				Name: "process.memory.usage", Unit: "By",
+				Description: "Resident set size of the process.",
+				Sum:         &otlpSum{DataPoints: []otlpDataPoint{gaugePoint(proc.MemoryMB * 1024 * 1024)}, AggregationTemporality: otlpTemporalityCumulative},
+			},
+			{
+				Name: "process.disk.io", Unit: "By",
+				Description: "Bytes the process read from and wrote to storage.",
+				Sum: &otlpSum{DataPoints: []otlpDataPoint{
+					counterPoint(proc.DiskReadKB*1024, stringAttr("disk.io.direction", "read")),
+					counterPoint(proc.DiskWriteKB*1024, stringAttr("disk.io.direction", "write")),
+				}, AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true},
+			},
+		}
+		if detailed[proc.Key()] {
+			metrics = append(metrics, otlpMetric{
+				Name: "process.network.io", Unit: "By",
+				Description: "Network bytes attributed to the process.",
+				Sum: &otlpSum{DataPoints: []otlpDataPoint{
+					counterPoint(proc.NetSentKB*1024, stringAttr("network.io.direction", "transmit")),
+					counterPoint(proc.NetRecvKB*1024, stringAttr("network.io.direction", "receive")),
+				}, AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true},
+			})
+		}
+
+		request.ResourceMetrics = append(request.ResourceMetrics, otlpResourceMetrics{
+			Resource:     otlpResource{Attributes: attributes},
+			ScopeMetrics: []otlpScopeMetrics{{Scope: scope, Metrics: metrics}},
+		})
+	}
+
+	return request
+}
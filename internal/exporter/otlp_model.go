@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+)
+
+// OTLP metrics data model, reduced to what pulse exports. The JSON tags follow
+// the protobuf JSON mapping required by OTLP/HTTP; marshalProto writes the
+// binary encoding with the field numbers of opentelemetry-proto.
+
+// OTLP aggregation temporality
+const otlpTemporalityCumulative = 2
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpMetric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Gauge       *otlpGauge `json:"gauge,omitempty"`
+	Sum         *otlpSum   `json:"sum,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic,omitempty"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64         `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64         `json:"timeUnixNano,string"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue holds a string or an integer attribute value
+type otlpAnyValue struct {
+	str   string
+	int   int64
+	isInt bool
+}
+
+// MarshalJSON encodes the value as stringValue or intValue, the latter as a
+// string like all 64 bit integers in the protobuf JSON mapping
+func (v otlpAnyValue) MarshalJSON() ([]byte, error) {
+	if v.isInt {
+		return json.Marshal(map[string]string{"intValue": strconv.FormatInt(v.int, 10)})
+	}
+	return json.Marshal(map[string]string{"stringValue": v.str})
+}
+
+func stringAttr(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{str: value}}
+}
+
+func intAttr(key string, value int64) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{int: value, isInt: true}}
+}
+
+func (r *otlpRequest) marshalProto() []byte {
+	var b protoBuffer
+	for _, rm := range r.ResourceMetrics {
+		b.message(1, rm.marshalProto)
+	}
+	return b.buf
+}
+
+func (rm *otlpResourceMetrics) marshalProto(b *protoBuffer) {
+	b.message(1, func(resource *protoBuffer) {
+		marshalAttributes(resource, 1, rm.Resource.Attributes)
+	})
+	for _, sm := range rm.ScopeMetrics {
+		b.message(2, func(scopeMetrics *protoBuffer) {
+			scopeMetrics.message(1, func(scope *protoBuffer) {
+				scope.string(1, sm.Scope.Name)
+				scope.string(2, sm.Scope.Version)
+			})
+			for _, metric := range sm.Metrics {
+				scopeMetrics.message(2, metric.marshalProto)
+			}
+		})
+	}
+}
+
+func (m *otlpMetric) marshalProto(b *protoBuffer) {
+	b.string(1, m.Name)
+	b.string(2, m.Description)
+	b.string(3, m.Unit)
+	if m.Gauge != nil {
+		b.message(5, func(gauge *protoBuffer) {
+			marshalDataPoints(gauge, m.Gauge.DataPoints)
+		})
+	}
+	if m.Sum != nil {
+		b.message(7, func(sum *protoBuffer) {
+			marshalDataPoints(sum, m.Sum.DataPoints)
+			sum.varint(2, uint64(m.Sum.AggregationTemporality))
+			if m.Sum.IsMonotonic {
+				sum.varint(3, 1)
+			}
+		})
+	}
+}
+
+func marshalDataPoints(b *protoBuffer, points []otlpDataPoint) {
+	for _, point := range points {
+		b.message(1, func(dp *protoBuffer) {
+			dp.fixed64(2, point.StartTimeUnixNano)
+			dp.fixed64(3, point.TimeUnixNano)
+			dp.double(4, point.AsDouble)
+			marshalAttributes(dp, 7, point.Attributes)
+		})
+	}
+}
+
+func marshalAttributes(b *protoBuffer, field int, attributes []otlpKeyValue) {
+	for _, attr := range attributes {
+		b.message(field, func(kv *protoBuffer) {
+			kv.string(1, attr.Key)
+			kv.message(2, func(value *protoBuffer) {
+				if attr.Value.isInt {
+					// Zero must still be written, otherwise the value would be empty
+					value.tag(3, wireVarint)
+					value.buf = binary.AppendUvarint(value.buf, uint64(attr.Value.int))
+				} else {
+					value.tag(1, wireBytes)
+					value.buf = binary.AppendUvarint(value.buf, uint64(len(attr.Value.str)))
+					value.buf = append(value.buf, attr.Value.str...)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package exporter publishes monitor data to metrics systems.
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// PrometheusExporter serves monitor data in the Prometheus text exposition format.
+// It only reads what the monitor already collected, so scrapes never touch /proc.
+type PrometheusExporter struct {
+	monitor *monitor.Monitor
+	series  seriesBuilder
+}
+
+// NewPrometheusExporter creates an exporter for the given monitor
+func NewPrometheusExporter(mon *monitor.Monitor, opts SeriesOptions) (*PrometheusExporter, error) {
+	series, err := newSeriesBuilder(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusExporter{monitor: mon, series: series}, nil
+}
+
+// ServeHTTP writes the current metrics
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := e.WriteMetrics(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteMetrics writes system and process metrics in the text exposition format
+func (e *PrometheusExporter) WriteMetrics(out io.Writer) error {
+	families := e.series.families(e.monitor.GetSystemMetrics(), e.monitor.GetProcesses(), e.monitor.GetCPUMode())
+
+	w := bufio.NewWriter(out)
+	var line strings.Builder
+	for _, family := range families {
+		line.Reset()
+		line.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		line.WriteString("# TYPE " + family.name + " " + family.kind + "\n")
+		for _, sample := range family.samples {
+			writeSample(&line, family.name, sample)
+		}
+		if _, err := w.WriteString(line.String()); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// writeSample formats one series line of the text exposition format
+func writeSample(line *strings.Builder, name string, sample metricSample) {
+	line.WriteString(name)
+	if len(sample.labels) > 0 {
+		line.WriteByte('{')
+		for i := 0; i+1 < len(sample.labels); i += 2 {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(sample.labels[i])
+			line.WriteString(`="`)
+			line.WriteString(labelEscaper.Replace(sample.labels[i+1]))
+			line.WriteByte('"')
+		}
+		line.WriteByte('}')
+	}
+	line.WriteByte(' ')
+	line.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+	line.WriteByte('\n')
+}
+
+// labelEscaper escapes label values as required by the exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
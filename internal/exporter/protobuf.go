@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// protoBuffer encodes protobuf messages by hand. Only the few wire types used
+// by the remote-write and OTLP messages are supported.
+type protoBuffer struct {
+	buf []byte
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (b *protoBuffer) tag(field, wireType int) {
+	b.buf = binary.AppendUvarint(b.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// varint writes an integer field, skipping zero values like proto3 does
+func (b *protoBuffer) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	b.buf = binary.AppendUvarint(b.buf, v)
+}
+
+// double writes a float field. Zero is written too, since a missing value
+// would be indistinguishable from an unset one.
+func (b *protoBuffer) double(field int, v float64) {
+	b.tag(field, wireFixed64)
+	b.buf = binary.LittleEndian.AppendUint64(b.buf, math.Float64bits(v))
+}
+
+// fixed64 writes an unsigned 64 bit field, skipping zero values
+func (b *protoBuffer) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireFixed64)
+	b.buf = binary.LittleEndian.AppendUint64(b.buf, v)
+}
+
+// string writes a string field, skipping empty strings
+func (b *protoBuffer) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	b.tag(field, wireBytes)
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+// message writes an embedded message built by fn
+func (b *protoBuffer) message(field int, fn func(*protoBuffer)) {
+	var inner protoBuffer
+	fn(&inner)
+	b.tag(field, wireBytes)
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(inner.buf)))
+	b.buf = append(b.buf, inner.buf...)
+}
@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// spoolExt is the file extension of queued request bodies
+const spoolExt = ".rw"
+
+// spool is a bounded FIFO of encoded requests that could not be sent yet.
+// With a directory, entries are also kept on disk so an outage survives restarts.
+// When the size limit is exceeded the oldest entries are dropped.
+type spool struct {
+	mu       sync.Mutex
+	dir      string // Empty keeps entries in memory only
+	maxBytes int64
+	entries  []spoolEntry
+	size     int64
+	seq      uint64 // Sequence number of the next entry, keeps file names ordered
+}
+
+type spoolEntry struct {
+	body []byte
+	path string
+}
+
+// openSpool creates a spool, loading entries left over from a previous run
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	s := &spool{dir: dir, maxBytes: maxBytes}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), spoolExt) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 16, 64); err == nil && seq >= s.seq {
+			s.seq = seq + 1
+		}
+		s.entries = append(s.entries, spoolEntry{body: body, path: path})
+		s.size += int64(len(body))
+	}
+	s.trim()
+	return s, nil
+}
+
+// push appends an encoded request and returns how many old ones were dropped
+func (s *spool) push(body []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := spoolEntry{body: body}
+	if s.dir != "" {
+		entry.path = filepath.Join(s.dir, fmt.Sprintf("%016x%s", s.seq, spoolExt))
+		if err := writeFileAtomic(entry.path, body); err != nil {
+			return 0, err
+		}
+	}
+	s.seq++
+	s.entries = append(s.entries, entry)
+	s.size += int64(len(body))
+	return s.trim(), nil
+}
+
+// peek returns the oldest request
+func (s *spool) peek() ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) == 0 {
+		return nil, false
+	}
+	return s.entries[0].body, true
+}
+
+// pop removes the oldest request
+func (s *spool) pop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) > 0 {
+		s.remove()
+	}
+}
+
+// len returns the number of queued requests
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// trim drops the oldest entries until the spool fits its limit, always keeping
+// the newest one, and returns how many were dropped. Callers must hold the lock.
+func (s *spool) trim() int {
+	dropped := 0
+	for s.maxBytes > 0 && s.size > s.maxBytes && len(s.entries) > 1 {
+		s.remove()
+		dropped++
+	}
+	return dropped
+}
+
+// remove deletes the oldest entry. Callers must hold the lock.
+func (s *spool) remove() {
+	entry := s.entries[0]
+	s.entries[0] = spoolEntry{}
+	s.entries = s.entries[1:]
+	s.size -= int64(len(entry.body))
+	if entry.path != "" {
+		_ = os.Remove(entry.path)
+	}
+}
+
+// writeFileAtomic writes a file under a temporary name first, so a crash
+// never leaves a partial request behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,155 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Relabel actions, following Prometheus' relabel_config
+const (
+	RelabelReplace   = "replace"
+	RelabelKeep      = "keep"
+	RelabelDrop      = "drop"
+	RelabelLabelDrop = "labeldrop"
+	RelabelLabelKeep = "labelkeep"
+)
+
+// RelabelRule rewrites the labels of pushed series. Rules use the same fields
+// and defaults as Prometheus' write_relabel_configs.
+type RelabelRule struct {
+	SourceLabels []string `json:"source_labels"`
+	Separator    string   `json:"separator"`
+	Regex        string   `json:"regex"`
+	TargetLabel  string   `json:"target_label"`
+	Replacement  *string  `json:"replacement"` // Nil for the default "$1"; "" removes the target label
+	Action       string   `json:"action"`
+
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// LoadRelabelRules reads a JSON array of rules from a file
+func LoadRelabelRules(path string) ([]RelabelRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []RelabelRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := compileRelabelRules(rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// compileRelabelRules fills in defaults and validates the rules
+func compileRelabelRules(rules []RelabelRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Action == "" {
+			rule.Action = RelabelReplace
+		}
+		if rule.Separator == "" {
+			rule.Separator = ";"
+		}
+		if rule.Regex == "" {
+			rule.Regex = "(.*)"
+		}
+		rule.replacement = "$1"
+		if rule.Replacement != nil {
+			rule.replacement = *rule.Replacement
+		}
+
+		switch rule.Action {
+		case RelabelReplace:
+			if rule.TargetLabel == "" {
+				return fmt.Errorf("rule %d: replace needs a target_label", i+1)
+			}
+		case RelabelKeep, RelabelDrop:
+			if len(rule.SourceLabels) == 0 {
+				return fmt.Errorf("rule %d: %s needs source_labels", i+1, rule.Action)
+			}
+		case RelabelLabelDrop, RelabelLabelKeep:
+		default:
+			return fmt.Errorf("rule %d: unknown action %q", i+1, rule.Action)
+		}
+
+		// Like Prometheus, the expression has to match the whole value
+		regex, err := regexp.Compile("^(?:" + rule.Regex + ")$")
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		rule.regex = regex
+	}
+	return nil
+}
+
+// relabel applies the rules to a label set, given as a map including __name__.
+// The metric name survives labeldrop and labelkeep. It returns false when the
+// series is dropped.
+func relabel(labels map[string]string, rules []RelabelRule) bool {
+	for _, rule := range rules {
+		values := make([]string, len(rule.SourceLabels))
+		for i, name := range rule.SourceLabels {
+			values[i] = labels[name]
+		}
+		value := strings.Join(values, rule.Separator)
+
+		switch rule.Action {
+		case RelabelKeep:
+			if !rule.regex.MatchString(value) {
+				return false
+			}
+		case RelabelDrop:
+			if rule.regex.MatchString(value) {
+				return false
+			}
+		case RelabelReplace:
+			match := rule.regex.FindStringSubmatchIndex(value)
+			if match == nil {
+				continue
+			}
+			target := string(rule.regex.ExpandString(nil, rule.TargetLabel, value, match))
+			replaced := string(rule.regex.ExpandString(nil, rule.replacement, value, match))
+			if replaced == "" {
+				delete(labels, target)
+			} else {
+				labels[target] = replaced
+			}
+		case RelabelLabelDrop, RelabelLabelKeep:
+			for name := range labels {
+				if name == nameLabel {
+					continue
+				}
+				if rule.regex.MatchString(name) == (rule.Action == RelabelLabelDrop) {
+					delete(labels, name)
+				}
+			}
+		}
+	}
+	return labels[nameLabel] != ""
+}
+
+// nameLabel holds the metric name in remote-write label sets
+const nameLabel = "__name__"
+
+// sortedLabels returns label pairs ordered by name, as remote-write requires
+func sortedLabels(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		pairs = append(pairs, name, labels[name])
+	}
+	return pairs
+}
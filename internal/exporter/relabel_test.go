@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelabelReplacement(t *testing.T) {
+	empty, fixed := "", "yes"
+	tests := []struct {
+		name string
+		rule RelabelRule
+		want map[string]string
+	}{
+		{
+			name: "default copies the first group",
+			rule: RelabelRule{SourceLabels: []string{"user"}, Regex: "(ro)ot", TargetLabel: "short"},
+			want: map[string]string{nameLabel: "m", "user": "root", "short": "ro"},
+		},
+		{
+			name: "fixed value",
+			rule: RelabelRule{SourceLabels: []string{"user"}, Regex: "root", TargetLabel: "privileged", Replacement: &fixed},
+			want: map[string]string{nameLabel: "m", "user": "root", "privileged": "yes"},
+		},
+		{
+			name: "empty removes the target",
+			rule: RelabelRule{SourceLabels: []string{"user"}, Regex: "root", TargetLabel: "user", Replacement: &empty},
+			want: map[string]string{nameLabel: "m"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []RelabelRule{tt.rule}
+			if err := compileRelabelRules(rules); err != nil {
+				t.Fatal(err)
+			}
+			labels := map[string]string{nameLabel: "m", "user": "root"}
+			if !relabel(labels, rules) {
+				t.Fatal("series was dropped")
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("labels = %v, want %v", labels, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Retry delays after failed pushes; variables so tests can shorten them
+var (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// RemoteWriteOptions configures the remote-write client
+type RemoteWriteOptions struct {
+	URL            string
+	Series         SeriesOptions
+	Relabel        []RelabelRule     // Applied to every series before it is queued
+	ExternalLabels map[string]string // Added to every series unless already set
+	Interval       time.Duration     // How often batches are sent
+	MaxSamples     int               // Send early once this many samples are pending
+	QueueDir       string            // Keeps unsent batches across restarts, empty for memory only
+	MaxQueueBytes  int64             // Oldest batches are dropped beyond this
+	Timeout        time.Duration     // Per request
+}
+
+// DefaultRemoteWriteOptions returns the settings used for the given endpoint
+func DefaultRemoteWriteOptions(url string) RemoteWriteOptions {
+	external := make(map[string]string)
+	if hostname, err := os.Hostname(); err == nil {
+		external["instance"] = hostname
+	}
+	return RemoteWriteOptions{
+		URL:            url,
+		Series:         SeriesOptions{Labels: DefaultLabels},
+		ExternalLabels: external,
+		Interval:       15 * time.Second,
+		MaxSamples:     20000,
+		MaxQueueBytes:  64 << 20,
+		Timeout:        30 * time.Second,
+	}
+}
+
+// remoteSeries is one relabeled sample waiting to be sent
+type remoteSeries struct {
+	labels    []string // Sorted name/value pairs including __name__
+	value     float64
+	timestamp int64 // Milliseconds since the epoch
+}
+
+// RemoteWriter pushes monitor updates to a Prometheus remote-write endpoint.
+// Updates are converted on arrival and batched; batches that can't be
+// delivered are queued and retried with exponential backoff.
+type RemoteWriter struct {
+	opts   RemoteWriteOptions
+	series seriesBuilder
+	client *http.Client
+	queue  *spool
+
+	mu      sync.Mutex
+	pending []remoteSeries
+	full    chan struct{} // Signals that MaxSamples were reached
+
+	failing bool // Whether the last attempt failed, to log only state changes
+	logf    func(format string, args ...any)
+}
+
+// NewRemoteWriter creates a remote-write client. Call Run to start sending.
+func NewRemoteWriter(opts RemoteWriteOptions) (*RemoteWriter, error) {
+	if !strings.HasPrefix(opts.URL, "http://") && !strings.HasPrefix(opts.URL, "https://") {
+		return nil, fmt.Errorf("invalid remote-write URL %q", opts.URL)
+	}
+	if opts.Interval <= 0 {
+		return nil, errors.New("remote-write interval must be positive")
+	}
+	series, err := newSeriesBuilder(opts.Series)
+	if err != nil {
+		return nil, err
+	}
+	if err := compileRelabelRules(opts.Relabel); err != nil {
+		return nil, err
+	}
+	queue, err := openSpool(opts.QueueDir, opts.MaxQueueBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteWriter{
+		opts:   opts,
+		series: series,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  queue,
+		full:   make(chan struct{}, 1),
+		logf:   logToStderr,
+	}, nil
+}
+
+// SetLogf sets where delivery problems are reported, stderr by default.
+// Call it before Run.
+func (w *RemoteWriter) SetLogf(logf func(format string, args ...any)) {
+	w.logf = logf
+}
+
+// Observe converts a monitor update into pending samples. It never blocks on
+// the network, so it can be passed to Monitor.Subscribe directly.
+func (w *RemoteWriter) Observe(update monitor.Update) {
+	if update.System.Timestamp.IsZero() {
+		return
+	}
+	timestamp := update.System.Timestamp.UnixMilli()
+
+	var samples []remoteSeries
+	for _, family := range w.series.families(update.System, update.Processes, update.CPUMode) {
+		for _, sample := range family.samples {
+			labels := make(map[string]string, len(sample.labels)/2+len(w.opts.ExternalLabels)+1)
+			for name, value := range w.opts.ExternalLabels {
+				labels[name] = value
+			}
+			for i := 0; i+1 < len(sample.labels); i += 2 {
+				labels[sample.labels[i]] = sample.labels[i+1]
+			}
+			labels[nameLabel] = family.name
+			if !relabel(labels, w.opts.Relabel) {
+				continue
+			}
+			samples = append(samples, remoteSeries{
+				labels:    sortedLabels(labels),
+				value:     sample.value,
+				timestamp: timestamp,
+			})
+		}
+	}
+
+	w.mu.Lock()
+	w.pending = append(w.pending, samples...)
+	full := w.opts.MaxSamples > 0 && len(w.pending) >= w.opts.MaxSamples
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run sends batches until the context is cancelled. Pending samples are then
+// sent once more or, failing that, left in the queue.
+func (w *RemoteWriter) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	// The retry timer first fires right away to send what a previous run queued
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+	var backoff time.Duration
+	waiting := false // Whether the endpoint failed and the retry timer runs
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			w.enqueuePending()
+			if !waiting {
+				w.drain(shutdownCtx)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		case <-w.full:
+		case <-retry.C:
+			waiting = false
+		}
+
+		w.enqueuePending()
+		if waiting {
+			continue
+		}
+		if w.drain(ctx) {
+			backoff = 0
+			continue
+		}
+
+		backoff = nextDelay(backoff)
+		waiting = true
+		retry.Reset(backoff)
+	}
+}
+
+// logToStderr is the default log function of the exporters
+func logToStderr(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// nextDelay doubles the retry delay within its bounds
+func nextDelay(last time.Duration) time.Duration {
+	if last < minRetryDelay {
+		return minRetryDelay
+	}
+	if last*2 > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return last * 2
+}
+
+// enqueuePending encodes the pending samples as one request and queues it
+func (w *RemoteWriter) enqueuePending() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	dropped, err := w.queue.push(snappy.Encode(nil, encodeWriteRequest(pending)))
+	if err != nil {
+		w.logf("Remote write: failed to queue samples: %v", err)
+	}
+	if dropped > 0 {
+		w.logf("Remote write: queue full, dropped %d old batches", dropped)
+	}
+}
+
+// drain sends queued requests oldest first. It returns false when the
+// endpoint should be retried later.
+func (w *RemoteWriter) drain(ctx context.Context) bool {
+	for {
+		body, ok := w.queue.peek()
+		if !ok {
+			return true
+		}
+
+		err := w.send(ctx, body)
+		var permanent *permanentError
+		switch {
+		case err == nil:
+			if w.failing {
+				w.logf("Remote write: %s reachable again", w.opts.URL)
+				w.failing = false
+			}
+		case errors.As(err, &permanent):
+			// Retrying won't help, e.g. the receiver rejected the samples
+			w.logf("Remote write: dropping batch: %v", err)
+		default:
+			if !w.failing {
+				w.logf("Remote write: %v (queued %d batches, retrying)", err, w.queue.len())
+				w.failing = true
+			}
+			return false
+		}
+		w.queue.pop()
+	}
+}
+
+// permanentError marks responses that will fail again when retried
+type permanentError struct {
+	status int
+	body   string
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.status, e.body)
+}
+
+// send posts one snappy-compressed WriteRequest
+func (w *RemoteWriter) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "hyperbyte-pulse")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	default:
+		return &permanentError{status: resp.StatusCode, body: strings.TrimSpace(string(message))}
+	}
+}
+
+// encodeWriteRequest builds a prometheus.WriteRequest. Samples of the same
+// series are merged into one TimeSeries, in the order they were observed.
+func encodeWriteRequest(samples []remoteSeries) []byte {
+	type timeSeries struct {
+		labels  []string
+		samples []remoteSeries
+	}
+	var series []*timeSeries
+	byLabels := make(map[string]*timeSeries)
+	for _, sample := range samples {
+		id := strings.Join(sample.labels, "\x00")
+		ts, exists := byLabels[id]
+		if !exists {
+			ts = &timeSeries{labels: sample.labels}
+			byLabels[id] = ts
+			series = append(series, ts)
+		}
+		ts.samples = append(ts.samples, sample)
+	}
+
+	var req protoBuffer
+	for _, ts := range series {
+		req.message(1, func(b *protoBuffer) {
+			for i := 0; i+1 < len(ts.labels); i += 2 {
+				b.message(1, func(label *protoBuffer) {
+					label.string(1, ts.labels[i])
+					label.string(2, ts.labels[i+1])
+				})
+			}
+			for _, sample := range ts.samples {
+				b.message(2, func(s *protoBuffer) {
+					s.double(1, sample.value)
+					s.varint(2, uint64(sample.timestamp))
+				})
+			}
+		})
+	}
+	return req.buf
+}
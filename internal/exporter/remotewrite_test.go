@@ -0,0 +1,275 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// receivedSeries is one TimeSeries as decoded by the test receiver
+type receivedSeries struct {
+	labels map[string]string
+	values []float64
+	stamps []int64
+}
+
+// receiver is a stand-in remote-write endpoint answering with the given
+// status codes in turn, then 204
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	attempts []time.Time
+	accepted chan []receivedSeries
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	r := &receiver{t: t, statuses: statuses, accepted: make(chan []receivedSeries, 4)}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	r.attempts = append(r.attempts, time.Now())
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+
+	if status/100 != 2 {
+		http.Error(w, "try again", status)
+		return
+	}
+	if enc := req.Header.Get("Content-Encoding"); enc != "snappy" {
+		r.t.Errorf("Content-Encoding = %q, want snappy", enc)
+	}
+	body, _ := io.ReadAll(req.Body)
+	raw, err := snappy.Decode(nil, body)
+	if err != nil {
+		r.t.Errorf("decoding snappy body: %v", err)
+	}
+	series, err := decodeWriteRequest(raw)
+	if err != nil {
+		r.t.Errorf("decoding WriteRequest: %v", err)
+	}
+	w.WriteHeader(status)
+	r.accepted <- series
+}
+
+func (r *receiver) attemptTimes() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time(nil), r.attempts...)
+}
+
+// shortenRetryDelays makes the backoff fast for the duration of a test
+func shortenRetryDelays(t *testing.T, min, max time.Duration) {
+	oldMin, oldMax := minRetryDelay, maxRetryDelay
+	minRetryDelay, maxRetryDelay = min, max
+	t.Cleanup(func() { minRetryDelay, maxRetryDelay = oldMin, oldMax })
+}
+
+// startWriter observes one update and runs the writer until the test ends
+func startWriter(t *testing.T, url string) *RemoteWriter {
+	writer, err := NewRemoteWriter(RemoteWriteOptions{
+		URL:            url,
+		Series:         SeriesOptions{Labels: []string{LabelName}},
+		ExternalLabels: map[string]string{"instance": "test-host"},
+		Interval:       time.Hour, // Only the retry timer sends
+		Timeout:        time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Observe(monitor.Update{
+		System:    monitor.SystemMetrics{Timestamp: testStart, NumCPU: 2},
+		Processes: []monitor.ProcessInfo{{PID: 1, Name: "web", CPUPercent: 42, CreateTime: testStart.Add(-time.Hour)}},
+		CPUMode:   monitor.CPUModePerCore,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		writer.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return writer
+}
+
+func TestRemoteWriteRetriesWithBackoff(t *testing.T) {
+	shortenRetryDelays(t, 20*time.Millisecond, 40*time.Millisecond)
+	r, server := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusInternalServerError)
+	startWriter(t, server.URL)
+
+	var series []receivedSeries
+	select {
+	case series = <-r.accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not delivered after the receiver recovered")
+	}
+
+	attempts := r.attemptTimes()
+	if len(attempts) != 4 {
+		t.Fatalf("got %d attempts, want 3 failures and 1 success", len(attempts))
+	}
+	for i, min := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond} {
+		if gap := attempts[i+1].Sub(attempts[i]); gap < min {
+			t.Errorf("retry %d after %v, want at least %v", i+1, gap, min)
+		}
+	}
+
+	var cpu *receivedSeries
+	for i := range series {
+		if series[i].labels[nameLabel] == "pulse_process_cpu_percent" {
+			cpu = &series[i]
+		}
+	}
+	if cpu == nil {
+		t.Fatal("no pulse_process_cpu_percent series in the request")
+	}
+	if cpu.labels[LabelName] != "web" || cpu.labels["instance"] != "test-host" {
+		t.Errorf("labels = %v, want name web and instance test-host", cpu.labels)
+	}
+	if len(cpu.values) != 1 || cpu.values[0] != 42 || cpu.stamps[0] != testStart.UnixMilli() {
+		t.Errorf("samples = %v at %v, want 42 at %d", cpu.values, cpu.stamps, testStart.UnixMilli())
+	}
+}
+
+func TestRemoteWriteDropsRejectedBatch(t *testing.T) {
+	shortenRetryDelays(t, 10*time.Millisecond, 10*time.Millisecond)
+	r, server := newReceiver(t, http.StatusBadRequest)
+	writer := startWriter(t, server.URL)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.attemptTimes()) == 0 || writer.queue.len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("rejected batch was not dropped")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Several retry delays later the batch must not have been sent again
+	time.Sleep(100 * time.Millisecond)
+	if n := len(r.attemptTimes()); n != 1 {
+		t.Errorf("got %d attempts, want the rejected batch sent once", n)
+	}
+	select {
+	case <-r.accepted:
+		t.Error("rejected batch was delivered")
+	default:
+	}
+}
+
+// writeRequestType describes prometheus.WriteRequest as defined by the
+// remote-write protocol (prompb/types.proto and remote.proto), so the bodies
+// are decoded by the reference protobuf implementation
+var writeRequestType = func() protoreflect.MessageType {
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     kind.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	message := func(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+	}
+	const (
+		typeMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		typeString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		typeDouble  = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+		typeInt64   = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	)
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("prompb/remote.proto"),
+		Package: proto.String("prometheus"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("WriteRequest", field("timeseries", 1, typeMessage, ".prometheus.TimeSeries", true)),
+			message("TimeSeries",
+				field("labels", 1, typeMessage, ".prometheus.Label", true),
+				field("samples", 2, typeMessage, ".prometheus.Sample", true)),
+			message("Label", field("name", 1, typeString, "", false), field("value", 2, typeString, "", false)),
+			message("Sample", field("value", 1, typeDouble, "", false), field("timestamp", 2, typeInt64, "", false)),
+		},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return dynamicpb.NewMessageType(file.Messages().ByName("WriteRequest"))
+}()
+
+// decodeWriteRequest decodes the series of a prometheus.WriteRequest. Fields
+// the schema does not know, e.g. from a wrong number or wire type, are errors.
+func decodeWriteRequest(buf []byte) ([]receivedSeries, error) {
+	req := writeRequestType.New().Interface()
+	if err := proto.Unmarshal(buf, req); err != nil {
+		return nil, err
+	}
+
+	var series []receivedSeries
+	var unknown error
+	checkKnown := func(msg protoreflect.Message) {
+		if len(msg.GetUnknown()) > 0 && unknown == nil {
+			unknown = fmt.Errorf("%s has unknown fields", msg.Descriptor().Name())
+		}
+	}
+	reqMsg := req.ProtoReflect()
+	checkKnown(reqMsg)
+	list := reqMsg.Get(reqMsg.Descriptor().Fields().ByName("timeseries")).List()
+	for i := 0; i < list.Len(); i++ {
+		tsMsg := list.Get(i).Message()
+		checkKnown(tsMsg)
+		fields := tsMsg.Descriptor().Fields()
+		ts := receivedSeries{labels: make(map[string]string)}
+
+		labels := tsMsg.Get(fields.ByName("labels")).List()
+		for j := 0; j < labels.Len(); j++ {
+			label := labels.Get(j).Message()
+			checkKnown(label)
+			labelFields := label.Descriptor().Fields()
+			ts.labels[label.Get(labelFields.ByName("name")).String()] = label.Get(labelFields.ByName("value")).String()
+		}
+		samples := tsMsg.Get(fields.ByName("samples")).List()
+		for j := 0; j < samples.Len(); j++ {
+			sample := samples.Get(j).Message()
+			checkKnown(sample)
+			sampleFields := sample.Descriptor().Fields()
+			ts.values = append(ts.values, sample.Get(sampleFields.ByName("value")).Float())
+			ts.stamps = append(ts.stamps, sample.Get(sampleFields.ByName("timestamp")).Int())
+		}
+		series = append(series, ts)
+	}
+	return series, unknown
+}
@@ -0,0 +1,375 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Process labels that can be attached to per-process series
+const (
+	LabelPID  = "pid"
+	LabelName = "name"
+	LabelUser = "user"
+)
+
+// otherLabelValue labels the series that processes beyond the cardinality limit are folded into
+const otherLabelValue = "_other"
+
+// DefaultLabels are the process labels used when none are configured
+var DefaultLabels = []string{LabelPID, LabelName, LabelUser}
+
+// SeriesOptions configures the per-process series of the exporters
+type SeriesOptions struct {
+	// Labels attached to process series. Processes sharing all label values,
+	// e.g. every worker of a service when pid is left out, are summed up.
+	Labels []string
+	// MaxSeries limits the number of process label sets; the processes using
+	// least CPU beyond it are summed into one series labelled _other. Zero means no limit.
+	MaxSeries int
+}
+
+// metricFamily is a named group of series of one metric type
+type metricFamily struct {
+	name    string
+	kind    string // "gauge" or "counter"
+	help    string
+	samples []metricSample
+}
+
+// metricSample is one series of a family; labels are name/value pairs
+type metricSample struct {
+	labels []string
+	value  float64
+}
+
+// seriesBuilder turns a monitor snapshot into metric families
+type seriesBuilder struct {
+	labels []string
+	max    int
+	totals *counterTotals // Shared by copies of the builder
+}
+
+// byteCounters are the cumulative byte counts exported as *_bytes_total
+type byteCounters struct {
+	diskRead  float64
+	diskWrite float64
+	netSent   float64
+	netRecv   float64
+}
+
+// add adds the counts of c to t
+func (t *byteCounters) add(c byteCounters) {
+	t.diskRead += c.diskRead
+	t.diskWrite += c.diskWrite
+	t.netSent += c.netSent
+	t.netRecv += c.netRecv
+}
+
+// counterTotals keeps the *_bytes_total series of process groups monotonic.
+// Summing the cumulative counts of the current members would drop whenever a
+// process exits or moves to another group, e.g. into _other because of the
+// series limit, which Prometheus takes for a counter reset. Instead each group
+// accumulates what its live members added since the previous update.
+type counterTotals struct {
+	mu         sync.Mutex
+	lastUpdate time.Time
+	processes  map[monitor.ProcessKey]byteCounters // Cumulative counts seen last
+	groups     map[string]*byteCounters            // Accumulated per group
+}
+
+func newCounterTotals() *counterTotals {
+	return &counterTotals{
+		processes: make(map[monitor.ProcessKey]byteCounters),
+		groups:    make(map[string]*byteCounters),
+	}
+}
+
+// delta returns how much the counters of a process grew since the previous
+// update. A process seen for the first time counts in full if it started
+// since then, otherwise it only sets the baseline. Callers must hold the lock.
+func (t *counterTotals) delta(proc monitor.ProcessInfo, seen map[monitor.ProcessKey]byteCounters) byteCounters {
+	current := byteCounters{
+		diskRead:  proc.DiskReadKB * 1024,
+		diskWrite: proc.DiskWriteKB * 1024,
+		netSent:   proc.NetSentKB * 1024,
+		netRecv:   proc.NetRecvKB * 1024,
+	}
+	seen[proc.Key()] = current
+
+	last, known := t.processes[proc.Key()]
+	if !known {
+		if t.lastUpdate.IsZero() || proc.CreateTime.Before(t.lastUpdate) {
+			return byteCounters{}
+		}
+		return current
+	}
+	grown := func(now, before float64) float64 {
+		return math.Max(now-before, 0)
+	}
+	return byteCounters{
+		diskRead:  grown(current.diskRead, last.diskRead),
+		diskWrite: grown(current.diskWrite, last.diskWrite),
+		netSent:   grown(current.netSent, last.netSent),
+		netRecv:   grown(current.netRecv, last.netRecv),
+	}
+}
+
+// accumulate adds the growth of each group's processes to its totals and
+// returns the totals by group. Groups without processes are forgotten; if one
+// comes back, its series starts again from zero, which reads as a reset.
+func (t *counterTotals) accumulate(members map[string][]monitor.ProcessInfo, at time.Time) map[string]byteCounters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[monitor.ProcessKey]byteCounters, len(t.processes))
+	totals := make(map[string]byteCounters, len(members))
+	groups := make(map[string]*byteCounters, len(members))
+	for id, processes := range members {
+		group := t.groups[id]
+		if group == nil {
+			group = &byteCounters{}
+		}
+		for _, proc := range processes {
+			group.add(t.delta(proc, seen))
+		}
+		groups[id] = group
+		totals[id] = *group
+	}
+	t.processes = seen
+	t.groups = groups
+	t.lastUpdate = at
+	return totals
+}
+
+// newSeriesBuilder validates the series options
+func newSeriesBuilder(opts SeriesOptions) (seriesBuilder, error) {
+	labels := opts.Labels
+	if labels == nil {
+		labels = DefaultLabels
+	}
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		switch label {
+		case LabelPID, LabelName, LabelUser:
+		default:
+			return seriesBuilder{}, fmt.Errorf("unknown process label %q (available: pid, name, user)", label)
+		}
+		if seen[label] {
+			return seriesBuilder{}, fmt.Errorf("duplicate process label %q", label)
+		}
+		seen[label] = true
+	}
+	if opts.MaxSeries < 0 {
+		return seriesBuilder{}, fmt.Errorf("invalid series limit %d", opts.MaxSeries)
+	}
+
+	return seriesBuilder{labels: labels, max: opts.MaxSeries, totals: newCounterTotals()}, nil
+}
+
+// processGroup is one process series: all processes sharing its label values
+type processGroup struct {
+	id           string
+	labels       []string
+	members      []monitor.ProcessInfo
+	processes    int
+	cpuPercent   float64
+	memoryBytes  float64
+	memoryPerc   float64
+	bytes        byteCounters // Accumulated, see counterTotals
+	ctxSwitches  float64      // Per second
+	minorFaults  float64
+	majorFaults  float64
+	startSeconds float64 // Only meaningful for single processes
+}
+
+// families builds all system and process metrics of a snapshot
+func (b seriesBuilder) families(system monitor.SystemMetrics, processes []monitor.ProcessInfo, mode monitor.CPUMode) []metricFamily {
+	families := []metricFamily{
+		gauge("pulse_system_cpu_percent", "System-wide CPU usage in percent of all cores.",
+			metricSample{value: system.CPUPercent}),
+		gauge("pulse_system_cpus", "Number of logical CPUs.",
+			metricSample{value: float64(system.NumCPU)}),
+		gauge("pulse_system_memory_total_bytes", "Total physical memory.",
+			metricSample{value: system.TotalMemoryMB * 1024 * 1024}),
+		gauge("pulse_system_memory_used_bytes", "Used physical memory.",
+			metricSample{value: system.UsedMemoryMB * 1024 * 1024}),
+		gauge("pulse_system_memory_percent", "Used physical memory in percent.",
+			metricSample{value: system.MemoryPercent}),
+	}
+
+	diskRead := gauge("pulse_disk_read_bytes_per_second", "Disk read throughput per whole disk.")
+	diskWrite := gauge("pulse_disk_write_bytes_per_second", "Disk write throughput per whole disk.")
+	diskOps := gauge("pulse_disk_operations_per_second", "Completed disk operations per whole disk.")
+	diskBusy := gauge("pulse_disk_busy_percent", "Share of time the disk had I/O in flight.")
+	for _, disk := range system.Disks {
+		device := []string{"device", disk.Name}
+		diskRead.add(device, disk.ReadRate*1024)
+		diskWrite.add(device, disk.WriteRate*1024)
+		diskOps.add(withLabel(device, "op", "read"), disk.ReadIOPS)
+		diskOps.add(withLabel(device, "op", "write"), disk.WriteIOPS)
+		diskBusy.add(device, disk.BusyPercent)
+	}
+	families = append(families, diskRead, diskWrite, diskOps, diskBusy)
+
+	groups, folded := b.groupProcesses(processes, mode, system.NumCPU)
+	members := make(map[string][]monitor.ProcessInfo, len(groups))
+	for _, g := range groups {
+		members[g.id] = g.members
+	}
+	totals := b.totals.accumulate(members, system.Timestamp)
+	for _, g := range groups {
+		g.bytes = totals[g.id]
+	}
+
+	cpu := gauge("pulse_process_cpu_percent", "Process CPU usage in percent of one core.")
+	rss := gauge("pulse_process_resident_memory_bytes", "Process resident set size.")
+	memPerc := gauge("pulse_process_memory_percent", "Process resident memory in percent of physical memory.")
+	readBytes := counter("pulse_process_disk_read_bytes_total", "Bytes the processes of the series read from storage since they were first exported.")
+	writtenBytes := counter("pulse_process_disk_written_bytes_total", "Bytes the processes of the series wrote to storage since they were first exported.")
+	sentBytes := counter("pulse_process_network_sent_bytes_total", "Network bytes attributed to the processes of the series as sent since they were first exported.")
+	recvBytes := counter("pulse_process_network_received_bytes_total", "Network bytes attributed to the processes of the series as received since they were first exported.")
+	ctxSwitches := gauge("pulse_process_context_switches_per_second", "Voluntary and involuntary context switches.")
+	faults := gauge("pulse_process_page_faults_per_second", "Page faults by type.")
+	count := gauge("pulse_process_count", "Number of processes in the series.")
+	start := gauge("pulse_process_start_time_seconds", "Process start time since the epoch.")
+	for _, g := range groups {
+		cpu.add(g.labels, g.cpuPercent)
+		rss.add(g.labels, g.memoryBytes)
+		memPerc.add(g.labels, g.memoryPerc)
+		readBytes.add(g.labels, g.bytes.diskRead)
+		writtenBytes.add(g.labels, g.bytes.diskWrite)
+		sentBytes.add(g.labels, g.bytes.netSent)
+		recvBytes.add(g.labels, g.bytes.netRecv)
+		ctxSwitches.add(g.labels, g.ctxSwitches)
+		faults.add(withLabel(g.labels, "type", "minor"), g.minorFaults)
+		faults.add(withLabel(g.labels, "type", "major"), g.majorFaults)
+		count.add(g.labels, float64(g.processes))
+		if b.hasLabel(LabelPID) && g.processes == 1 {
+			start.add(g.labels, g.startSeconds)
+		}
+	}
+	families = append(families, cpu, rss, memPerc, readBytes, writtenBytes, sentBytes, recvBytes, ctxSwitches, faults, count)
+	if b.hasLabel(LabelPID) {
+		families = append(families, start)
+	}
+
+	return append(families,
+		gauge("pulse_exporter_folded_processes", "Processes summed into the _other series because of the series limit.",
+			metricSample{value: float64(folded)}),
+		gauge("pulse_last_update_timestamp_seconds", "Time of the last monitor update.",
+			metricSample{value: float64(system.Timestamp.UnixMilli()) / 1000}),
+	)
+}
+
+// groupProcesses sums processes by label values, busiest first, folding groups
+// beyond the series limit into one. It also returns how many processes were folded.
+func (b seriesBuilder) groupProcesses(processes []monitor.ProcessInfo, mode monitor.CPUMode, numCPU int) ([]*processGroup, int) {
+	sorted := make([]monitor.ProcessInfo, len(processes))
+	copy(sorted, processes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CPUPercent > sorted[j].CPUPercent
+	})
+
+	var other *processGroup
+	folded := 0
+	groups := make([]*processGroup, 0, len(sorted))
+	byLabels := make(map[string]*processGroup, len(sorted))
+	for _, proc := range sorted {
+		labels := b.processLabels(proc)
+		id := strings.Join(labels, "\x00")
+
+		group, exists := byLabels[id]
+		if !exists {
+			if b.max > 0 && len(byLabels) >= b.max {
+				if other == nil {
+					labels := b.otherLabels()
+					other = &processGroup{id: strings.Join(labels, "\x00"), labels: labels}
+				}
+				group = other
+				folded++
+			} else {
+				group = &processGroup{id: id, labels: labels}
+				byLabels[id] = group
+				groups = append(groups, group)
+			}
+		}
+
+		group.members = append(group.members, proc)
+		group.processes++
+		group.cpuPercent += mode.ToPerCore(proc.CPUPercent, numCPU)
+		group.memoryBytes += proc.MemoryMB * 1024 * 1024
+		group.memoryPerc += float64(proc.MemoryPerc)
+		group.ctxSwitches += proc.CtxSwitchRate
+		group.minorFaults += proc.MinorFaultPS
+		group.majorFaults += proc.MajorFaultPS
+		group.startSeconds = float64(proc.CreateTime.UnixMilli()) / 1000
+	}
+	if other != nil {
+		groups = append(groups, other)
+	}
+
+	return groups, folded
+}
+
+// processLabels returns the label name/value pairs of a process
+func (b seriesBuilder) processLabels(proc monitor.ProcessInfo) []string {
+	labels := make([]string, 0, 2*len(b.labels))
+	for _, label := range b.labels {
+		var value string
+		switch label {
+		case LabelPID:
+			value = strconv.Itoa(int(proc.PID))
+		case LabelName:
+			value = proc.Name
+		case LabelUser:
+			value = proc.Username
+		}
+		labels = append(labels, label, value)
+	}
+	return labels
+}
+
+// otherLabels returns the label pairs of the series for folded processes
+func (b seriesBuilder) otherLabels() []string {
+	labels := make([]string, 0, 2*len(b.labels))
+	for _, label := range b.labels {
+		labels = append(labels, label, otherLabelValue)
+	}
+	return labels
+}
+
+func (b seriesBuilder) hasLabel(name string) bool {
+	for _, label := range b.labels {
+		if label == name {
+			return true
+		}
+	}
+	return false
+}
+
+func gauge(name, help string, samples ...metricSample) metricFamily {
+	return metricFamily{name: name, kind: "gauge", help: help, samples: samples}
+}
+
+func counter(name, help string, samples ...metricSample) metricFamily {
+	return metricFamily{name: name, kind: "counter", help: help, samples: samples}
+}
+
+// add appends a series to the family
+func (f *metricFamily) add(labels []string, value float64) {
+	f.samples = append(f.samples, metricSample{labels: labels, value: value})
+}
+
+// withLabel returns a copy of labels with one more name/value pair
+func withLabel(labels []string, name, value string) []string {
+	extended := make([]string, len(labels), len(labels)+2)
+	copy(extended, labels)
+	return append(extended, name, value)
+}
@@ -0,0 +1,82 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+// seriesValue returns the value of the series of a family with the given name label
+func seriesValue(t *testing.T, families []metricFamily, family, name string) float64 {
+	t.Helper()
+	for _, f := range families {
+		if f.name != family {
+			continue
+		}
+		for _, sample := range f.samples {
+			for i := 0; i+1 < len(sample.labels); i += 2 {
+				if sample.labels[i] == LabelName && sample.labels[i+1] == name {
+					return sample.value
+				}
+			}
+		}
+	}
+	t.Fatalf("no %s series for %q", family, name)
+	return 0
+}
+
+func TestByteCountersStayMonotonic(t *testing.T) {
+	builder, err := newSeriesBuilder(SeriesOptions{Labels: []string{LabelName}, MaxSeries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc := func(pid int32, name string, cpu, readKB float64) monitor.ProcessInfo {
+		return monitor.ProcessInfo{PID: pid, Name: name, CPUPercent: cpu, DiskReadKB: readKB, CreateTime: testStart.Add(-time.Hour)}
+	}
+	const family = "pulse_process_disk_read_bytes_total"
+
+	steps := []struct {
+		processes []monitor.ProcessInfo
+		want      map[string]float64 // Series by name label, in bytes
+	}{
+		// Baseline: counts before the exporter saw the processes are not included
+		{[]monitor.ProcessInfo{proc(1, "web", 50, 1000), proc(2, "db", 10, 5000), proc(3, "cron", 1, 100)},
+			map[string]float64{"web": 0, otherLabelValue: 0}},
+		{[]monitor.ProcessInfo{proc(1, "web", 50, 1001), proc(2, "db", 10, 5002), proc(3, "cron", 1, 103)},
+			map[string]float64{"web": 1024, otherLabelValue: 5 * 1024}},
+		// db becomes the busiest and takes the only series, web is folded
+		{[]monitor.ProcessInfo{proc(1, "web", 5, 1002), proc(2, "db", 60, 5004), proc(3, "cron", 1, 103)},
+			map[string]float64{"db": 2 * 1024, otherLabelValue: 6 * 1024}},
+		// cron exits: _other keeps its total
+		{[]monitor.ProcessInfo{proc(1, "web", 5, 1004), proc(2, "db", 60, 5004)},
+			map[string]float64{"db": 2 * 1024, otherLabelValue: 8 * 1024}},
+	}
+
+	at := testStart
+	for i, step := range steps {
+		at = at.Add(10 * time.Second)
+		families := builder.families(monitor.SystemMetrics{Timestamp: at}, step.processes, monitor.CPUModePerCore)
+		for name, want := range step.want {
+			if got := seriesValue(t, families, family, name); got != want {
+				t.Errorf("step %d: %s = %v, want %v", i, name, got, want)
+			}
+		}
+	}
+}
+
+func TestNewProcessCountsFromItsStart(t *testing.T) {
+	builder, err := newSeriesBuilder(SeriesOptions{Labels: []string{LabelName}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder.families(monitor.SystemMetrics{Timestamp: testStart}, nil, monitor.CPUModePerCore)
+
+	started := monitor.ProcessInfo{PID: 7, Name: "job", DiskReadKB: 3, CreateTime: testStart.Add(time.Second)}
+	families := builder.families(monitor.SystemMetrics{Timestamp: testStart.Add(2 * time.Second)}, []monitor.ProcessInfo{started}, monitor.CPUModePerCore)
+	if got := seriesValue(t, families, "pulse_process_disk_read_bytes_total", "job"); got != 3*1024 {
+		t.Errorf("new process read %v bytes, want all %d", got, 3*1024)
+	}
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"context"
+	"syscall"
+	"time"
+)
+
+// SystemSample is a raw system-wide snapshot returned by a Collector
+type SystemSample struct {
+	CPUPercent       float64
+	NumCPU           int // Logical CPUs, the divisor for normalized CPU usage
+	MemoryPercent    float64
+	TotalMemoryBytes uint64
+	UsedMemoryBytes  uint64
+	Disks            []DiskDeviceMetrics
+	Load             LoadAverage
+}
+
+// ProcessSample holds the raw values a Collector reports for a single process.
+// Counters are cumulative, the Monitor turns them into rates.
+type ProcessSample struct {
+	PID           int32
+	PPID          int32
+	Name          string
+	CPUTime       float64 // User + system CPU seconds since process start
+	MemoryRSS     uint64  // Bytes
+	MemoryPercent float32
+	CreateTime    time.Time // Together with PID this identifies the process
+	State         string    // Run state letter as shown by ps: R, S, D, Z, T, ...
+	Threads       int32
+	Nice          int32
+	Priority      int32  // Scheduling priority as shown by top
+	TTY           string // Controlling terminal, e.g. pts/0, empty for none
+	Username      string // Effective user
+	HasIO         bool
+	ReadBytes     uint64
+	WriteBytes    uint64
+
+	// Only filled in by ProcessDetails
+	Cmdline string // Arguments joined by spaces
+	Cwd     string
+	Exe     string
+	HasNet  bool
+	Net     ProcessNetUsage
+
+	// Scheduler and memory activity, also only filled in by ProcessDetails
+	HasActivity bool
+	CtxSwitches uint64 // Voluntary + involuntary
+	MinorFaults uint64
+	MajorFaults uint64
+}
+
+// Collector is the data source behind a Monitor
+type Collector interface {
+	// System returns a system-wide snapshot
+	System(ctx context.Context, now time.Time) (SystemSample, error)
+
+	// Pids lists the processes currently running
+	Pids(ctx context.Context) ([]int32, error)
+
+	// Process returns the lightweight values used to rank processes
+	Process(ctx context.Context, pid int32) (ProcessSample, error)
+
+	// ProcessDetails returns the full sample including I/O and network counters
+	ProcessDetails(ctx context.Context, pid int32) (ProcessSample, error)
+
+	// UpdateNetwork refreshes network accounting for the given processes
+	UpdateNetwork(pids []int32, now time.Time)
+
+	// Signal sends a signal to a process
+	Signal(ctx context.Context, pid int32, sig syscall.Signal) error
+
+	// Scheduling returns the nice value, I/O priority and CPU affinity of a process
+	Scheduling(ctx context.Context, pid int32) (Scheduling, error)
+
+	// SetNice, SetIOPriority and SetAffinity change the scheduling of a process
+	SetNice(ctx context.Context, pid int32, nice int) error
+	SetIOPriority(ctx context.Context, pid int32, prio IOPriority) error
+	SetAffinity(ctx context.Context, pid int32, cpus []int) error
+
+	// OpenFiles lists the file descriptors of a process
+	OpenFiles(ctx context.Context, pid int32) (OpenFiles, error)
+}
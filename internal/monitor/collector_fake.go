@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FakeCollector is an in-memory Collector whose samples are set by the caller.
+// Combined with a FakeClock it makes Monitor behaviour fully deterministic.
+type FakeCollector struct {
+	mu        sync.Mutex
+	system    SystemSample
+	processes map[int32]ProcessSample
+	signals   []SentSignal
+	sched     map[int32]Scheduling
+	tuneErr   error // Returned by the scheduling changes when set
+	files     map[int32]OpenFiles
+}
+
+// SentSignal records a signal delivered through a FakeCollector
+type SentSignal struct {
+	PID    int32
+	Signal syscall.Signal
+}
+
+// NewFakeCollector creates an empty fake collector
+func NewFakeCollector() *FakeCollector {
+	return &FakeCollector{
+		processes: make(map[int32]ProcessSample),
+		sched:     make(map[int32]Scheduling),
+		files:     make(map[int32]OpenFiles),
+	}
+}
+
+// SetSystem sets the sample returned by System
+func (c *FakeCollector) SetSystem(sample SystemSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.system = sample
+}
+
+// SetProcess adds or replaces a process sample
+func (c *FakeCollector) SetProcess(sample ProcessSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.processes[sample.PID] = sample
+}
+
+// RemoveProcess simulates a process exiting
+func (c *FakeCollector) RemoveProcess(pid int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.processes, pid)
+}
+
+// System returns the configured system sample
+func (c *FakeCollector) System(ctx context.Context, now time.Time) (SystemSample, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.system, nil
+}
+
+// Pids returns the PIDs of all configured processes in ascending order
+func (c *FakeCollector) Pids(ctx context.Context) ([]int32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pids := make([]int32, 0, len(c.processes))
+	for pid := range c.processes {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	return pids, nil
+}
+
+// Process returns the configured sample without detail fields
+func (c *FakeCollector) Process(ctx context.Context, pid int32) (ProcessSample, error) {
+	sample, err := c.ProcessDetails(ctx, pid)
+	if err != nil {
+		return sample, err
+	}
+	return ProcessSample{
+		PID:           sample.PID,
+		PPID:          sample.PPID,
+		Name:          sample.Name,
+		State:         sample.State,
+		Threads:       sample.Threads,
+		Nice:          sample.Nice,
+		Priority:      sample.Priority,
+		TTY:           sample.TTY,
+		Username:      sample.Username,
+		HasIO:         sample.HasIO,
+		ReadBytes:     sample.ReadBytes,
+		WriteBytes:    sample.WriteBytes,
+		CPUTime:       sample.CPUTime,
+		MemoryRSS:     sample.MemoryRSS,
+		MemoryPercent: sample.MemoryPercent,
+		CreateTime:    sample.CreateTime,
+	}, nil
+}
+
+// ProcessDetails returns the configured sample
+func (c *FakeCollector) ProcessDetails(ctx context.Context, pid int32) (ProcessSample, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sample, exists := c.processes[pid]
+	if !exists {
+		return ProcessSample{}, fmt.Errorf("process %d not found", pid)
+	}
+	return sample, nil
+}
+
+// UpdateNetwork is a no-op, network usage is part of the configured samples
+func (c *FakeCollector) UpdateNetwork(pids []int32, now time.Time) {}
+
+// Signal records the signal; the process keeps running until removed
+func (c *FakeCollector) Signal(ctx context.Context, pid int32, sig syscall.Signal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.processes[pid]; !exists {
+		return fmt.Errorf("process %d not found", pid)
+	}
+	c.signals = append(c.signals, SentSignal{PID: pid, Signal: sig})
+	return nil
+}
+
+// Signals returns the signals sent so far
+func (c *FakeCollector) Signals() []SentSignal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]SentSignal(nil), c.signals...)
+}
+
+// Scheduling returns the scheduling set through the collector, the zero
+// value with CPU 0 until then
+func (c *FakeCollector) Scheduling(ctx context.Context, pid int32) (Scheduling, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.processes[pid]; !exists {
+		return Scheduling{}, fmt.Errorf("process %d not found", pid)
+	}
+	sched, exists := c.sched[pid]
+	if !exists {
+		sched.Affinity = []int{0}
+	}
+	return sched, nil
+}
+
+// SetNice records the nice value of a process
+func (c *FakeCollector) SetNice(ctx context.Context, pid int32, nice int) error {
+	return c.updateScheduling(pid, func(sched *Scheduling) { sched.Nice = int32(nice) })
+}
+
+// SetIOPriority records the I/O priority of a process
+func (c *FakeCollector) SetIOPriority(ctx context.Context, pid int32, prio IOPriority) error {
+	return c.updateScheduling(pid, func(sched *Scheduling) { sched.IOPriority = prio })
+}
+
+// SetAffinity records the CPU affinity of a process
+func (c *FakeCollector) SetAffinity(ctx context.Context, pid int32, cpus []int) error {
+	return c.updateScheduling(pid, func(sched *Scheduling) { sched.Affinity = append([]int(nil), cpus...) })
+}
+
+// SetTuningError makes the scheduling changes fail with err, e.g.
+// syscall.EPERM, until it is reset with nil
+func (c *FakeCollector) SetTuningError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tuneErr = err
+}
+
+// updateScheduling changes the recorded scheduling of a process
+func (c *FakeCollector) updateScheduling(pid int32, update func(sched *Scheduling)) error {
+	sched, err := c.Scheduling(context.Background(), pid)
+	if err != nil {
+		return err
+	}
+	update(&sched)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tuneErr != nil {
+		return c.tuneErr
+	}
+	c.sched[pid] = sched
+	return nil
+}
+
+// SetOpenFiles sets the file descriptors returned for a process
+func (c *FakeCollector) SetOpenFiles(pid int32, files OpenFiles) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[pid] = files
+}
+
+// OpenFiles returns the file descriptors set for a process, none with no
+// limit until then
+func (c *FakeCollector) OpenFiles(ctx context.Context, pid int32) (OpenFiles, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.processes[pid]; !exists {
+		return OpenFiles{}, fmt.Errorf("process %d not found", pid)
+	}
+	files, exists := c.files[pid]
+	if !exists {
+		return OpenFiles{SoftLimit: -1, HardLimit: -1}, nil
+	}
+	return files, nil
+}
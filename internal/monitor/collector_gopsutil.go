@@ -0,0 +1,291 @@
+package monitor
+
+import (
+	"context"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// procStat holds the scheduler fields of /proc/<pid>/stat
+type procStat struct {
+	state    string
+	ppid     int32
+	tty      string
+	priority int32
+	nice     int32
+	threads  int32
+}
+
+// GopsutilCollector collects samples from the local host using gopsutil and /proc
+type GopsutilCollector struct {
+	numCPU        int
+	netAccountant *NetAccountant
+	diskSampler   *DiskSampler
+	usersMu       sync.Mutex
+	users         map[int32]string // User names by UID, lookups read /etc/passwd
+}
+
+// NewGopsutilCollector creates a collector for the local host
+func NewGopsutilCollector() *GopsutilCollector {
+	return &GopsutilCollector{
+		netAccountant: NewNetAccountant(),
+		diskSampler:   NewDiskSampler(),
+		users:         make(map[int32]string),
+	}
+}
+
+// System returns CPU, memory and disk usage of the host
+func (c *GopsutilCollector) System(ctx context.Context, now time.Time) (SystemSample, error) {
+	// Get CPU usage
+	cpuPercent, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil {
+		return SystemSample{}, err
+	}
+	var cpuValue float64
+	if len(cpuPercent) > 0 {
+		cpuValue = cpuPercent[0]
+	}
+
+	// Logical CPU count only changes with hotplug, read it once
+	if c.numCPU == 0 {
+		if count, err := cpu.CountsWithContext(ctx, true); err == nil && count > 0 {
+			c.numCPU = count
+		} else {
+			c.numCPU = 1
+		}
+	}
+
+	// Get memory usage
+	memStat, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return SystemSample{}, err
+	}
+
+	// Get disk throughput, which is the baseline for per-process I/O percentages
+	disks, err := c.diskSampler.Update(now)
+	if err != nil {
+		disks = nil // Disk stats are optional (e.g. non-Linux systems)
+	}
+
+	// Load average is optional as well (e.g. Windows)
+	var loadAvg LoadAverage
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		loadAvg = LoadAverage{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15}
+	}
+
+	return SystemSample{
+		CPUPercent:       cpuValue,
+		NumCPU:           c.numCPU,
+		MemoryPercent:    memStat.UsedPercent,
+		TotalMemoryBytes: memStat.Total,
+		UsedMemoryBytes:  memStat.Used,
+		Disks:            disks,
+		Load:             loadAvg,
+	}, nil
+}
+
+// Pids lists running processes
+func (c *GopsutilCollector) Pids(ctx context.Context) ([]int32, error) {
+	return process.PidsWithContext(ctx)
+}
+
+// Process returns name, start time, CPU and memory usage of a process
+func (c *GopsutilCollector) Process(ctx context.Context, pid int32) (ProcessSample, error) {
+	// A fresh handle every time: gopsutil caches the creation time on the
+	// handle, which would hide a recycled PID
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return ProcessSample{}, err // Process might have disappeared
+	}
+	return c.sample(ctx, proc)
+}
+
+// ProcessDetails returns the full sample of a process including network counters
+func (c *GopsutilCollector) ProcessDetails(ctx context.Context, pid int32) (ProcessSample, error) {
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return ProcessSample{}, err
+	}
+
+	sample, err := c.sample(ctx, proc)
+	if err != nil {
+		return sample, err
+	}
+
+	// What the process is and where it runs; unreadable for other users' processes
+	if cmdline, err := proc.CmdlineWithContext(ctx); err == nil {
+		sample.Cmdline = cmdline
+	}
+	if cwd, err := proc.CwdWithContext(ctx); err == nil {
+		sample.Cwd = cwd
+	}
+	if exe, err := proc.ExeWithContext(ctx); err == nil {
+		sample.Exe = exe
+	}
+
+	// Get context switches and page faults
+	if ctxSwitches, err := proc.NumCtxSwitchesWithContext(ctx); err == nil {
+		if faults, err := proc.PageFaultsWithContext(ctx); err == nil {
+			sample.HasActivity = true
+			sample.CtxSwitches = uint64(ctxSwitches.Voluntary + ctxSwitches.Involuntary)
+			sample.MinorFaults = faults.MinorFaults
+			sample.MajorFaults = faults.MajorFaults
+		}
+	}
+
+	// Network I/O attributed during the last network update
+	sample.Net, sample.HasNet = c.netAccountant.Usage(pid)
+
+	return sample, nil
+}
+
+// sample reads the lightweight values shared by Process and ProcessDetails
+func (c *GopsutilCollector) sample(ctx context.Context, proc *process.Process) (ProcessSample, error) {
+	sample := ProcessSample{PID: proc.Pid}
+
+	// The start time is part of the process identity, skip processes without one
+	createTime, err := proc.CreateTimeWithContext(ctx)
+	if err != nil {
+		return sample, err
+	}
+	sample.CreateTime = time.UnixMilli(createTime)
+
+	// Get only the essential info for sorting
+	if name, err := proc.NameWithContext(ctx); err == nil {
+		sample.Name = name
+	}
+	c.fillStat(ctx, proc, &sample)
+
+	// Real, effective, saved and filesystem UIDs; ps shows the effective one
+	if uids, err := proc.UidsWithContext(ctx); err == nil && len(uids) > 1 {
+		sample.Username = c.username(uids[1])
+	}
+
+	// Raw CPU times; the monitor computes usage over its own sampling interval
+	if times, err := proc.TimesWithContext(ctx); err == nil {
+		sample.CPUTime = times.User + times.System
+	}
+
+	if memPercent, err := proc.MemoryPercentWithContext(ctx); err == nil {
+		sample.MemoryPercent = memPercent
+	}
+
+	if memInfo, err := proc.MemoryInfoWithContext(ctx); err == nil {
+		sample.MemoryRSS = memInfo.RSS
+	}
+
+	// I/O counters, so disk usage is known beyond the top list; unreadable for
+	// other users' processes
+	if ioCounters, err := proc.IOCountersWithContext(ctx); err == nil {
+		sample.HasIO = true
+		sample.ReadBytes = ioCounters.ReadBytes
+		sample.WriteBytes = ioCounters.WriteBytes
+	}
+
+	return sample, nil
+}
+
+// fillStat reads the parent, run state and scheduling values of a process,
+// from a single /proc read where available
+func (c *GopsutilCollector) fillStat(ctx context.Context, proc *process.Process, sample *ProcessSample) {
+	if stat, err := readProcStat(proc.Pid); err == nil {
+		sample.PPID = stat.ppid
+		sample.State = stat.state
+		sample.TTY = stat.tty
+		sample.Priority = stat.priority
+		sample.Nice = stat.nice
+		sample.Threads = stat.threads
+		return
+	}
+
+	if ppid, err := proc.PpidWithContext(ctx); err == nil {
+		sample.PPID = ppid
+	}
+	if status, err := proc.StatusWithContext(ctx); err == nil && len(status) > 0 {
+		sample.State = statusLetters[status[0]]
+	}
+	if terminal, err := proc.TerminalWithContext(ctx); err == nil {
+		sample.TTY = strings.TrimPrefix(terminal, "/dev/")
+	}
+	if nice, err := proc.NiceWithContext(ctx); err == nil {
+		sample.Nice = nice
+	}
+	if threads, err := proc.NumThreadsWithContext(ctx); err == nil {
+		sample.Threads = threads
+	}
+}
+
+// statusLetters maps gopsutil's status names to the letters shown by ps
+var statusLetters = map[string]string{
+	process.Running: "R",
+	process.Sleep:   "S",
+	process.Stop:    "T",
+	process.Idle:    "I",
+	process.Zombie:  "Z",
+	process.Wait:    "D",
+	process.Lock:    "L",
+}
+
+// username resolves a UID to a user name, falling back to the number
+func (c *GopsutilCollector) username(uid int32) string {
+	c.usersMu.Lock()
+	defer c.usersMu.Unlock()
+
+	if name, exists := c.users[uid]; exists {
+		return name
+	}
+	name := strconv.Itoa(int(uid))
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
+
+// UpdateNetwork attributes network traffic to the given processes
+func (c *GopsutilCollector) UpdateNetwork(pids []int32, now time.Time) {
+	c.netAccountant.Update(pids, now)
+}
+
+// Signal sends a signal to a local process
+func (c *GopsutilCollector) Signal(ctx context.Context, pid int32, sig syscall.Signal) error {
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return err
+	}
+	return proc.SendSignalWithContext(ctx, sig)
+}
+
+// Scheduling reads the scheduling of a local process
+func (c *GopsutilCollector) Scheduling(ctx context.Context, pid int32) (Scheduling, error) {
+	return readScheduling(pid)
+}
+
+// SetNice changes the nice value of a local process
+func (c *GopsutilCollector) SetNice(ctx context.Context, pid int32, nice int) error {
+	return setNice(pid, nice)
+}
+
+// SetIOPriority changes the I/O priority of a local process
+func (c *GopsutilCollector) SetIOPriority(ctx context.Context, pid int32, prio IOPriority) error {
+	return setIOPriority(pid, prio)
+}
+
+// SetAffinity changes the CPU affinity of a local process
+func (c *GopsutilCollector) SetAffinity(ctx context.Context, pid int32, cpus []int) error {
+	return setAffinity(pid, cpus)
+}
+
+// OpenFiles lists the file descriptors of a local process
+func (c *GopsutilCollector) OpenFiles(ctx context.Context, pid int32) (OpenFiles, error) {
+	return readOpenFiles(pid)
+}
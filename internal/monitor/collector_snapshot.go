@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// errSnapshotOnly is returned by monitors that are fed with ApplySnapshot
+var errSnapshotOnly = errors.New("process data comes from snapshots, not from this host")
+
+// snapshotCollector backs a monitor that only receives snapshots, e.g. from
+// an agent or a recording. There is nothing local to read or act on.
+type snapshotCollector struct{}
+
+func (snapshotCollector) System(ctx context.Context, now time.Time) (SystemSample, error) {
+	return SystemSample{}, errSnapshotOnly
+}
+
+func (snapshotCollector) Pids(ctx context.Context) ([]int32, error) {
+	return nil, errSnapshotOnly
+}
+
+func (snapshotCollector) Process(ctx context.Context, pid int32) (ProcessSample, error) {
+	return ProcessSample{}, errSnapshotOnly
+}
+
+func (snapshotCollector) ProcessDetails(ctx context.Context, pid int32) (ProcessSample, error) {
+	return ProcessSample{}, errSnapshotOnly
+}
+
+func (snapshotCollector) UpdateNetwork(pids []int32, now time.Time) {}
+
+func (snapshotCollector) Signal(ctx context.Context, pid int32, sig syscall.Signal) error {
+	return errSnapshotOnly
+}
+
+func (snapshotCollector) Scheduling(ctx context.Context, pid int32) (Scheduling, error) {
+	return Scheduling{}, errSnapshotOnly
+}
+
+func (snapshotCollector) SetNice(ctx context.Context, pid int32, nice int) error {
+	return errSnapshotOnly
+}
+
+func (snapshotCollector) SetIOPriority(ctx context.Context, pid int32, prio IOPriority) error {
+	return errSnapshotOnly
+}
+
+func (snapshotCollector) SetAffinity(ctx context.Context, pid int32, cpus []int) error {
+	return errSnapshotOnly
+}
+
+func (snapshotCollector) OpenFiles(ctx context.Context, pid int32) (OpenFiles, error) {
+	return OpenFiles{}, errSnapshotOnly
+}
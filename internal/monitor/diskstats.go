@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"bufio"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diskSectorSize is the unit used for sector counts in /proc/diskstats,
+// independent of the device's real sector size
+const diskSectorSize = 512
+
+// skippedDiskPrefixes lists block devices backed by files or memory, which
+// don't represent real disk throughput
+var skippedDiskPrefixes = []string{"loop", "ram", "zram"}
+
+// DiskDeviceMetrics represents throughput of a single block device
+type DiskDeviceMetrics struct {
+	Name        string
+	ReadRate    float64 // KB/s
+	WriteRate   float64 // KB/s
+	ReadIOPS    float64
+	WriteIOPS   float64
+	BusyPercent float64 // Share of wall time the device had I/O in flight
+}
+
+// diskCounters holds the cumulative counters of one /proc/diskstats line
+type diskCounters struct {
+	readsCompleted  uint64
+	sectorsRead     uint64
+	writesCompleted uint64
+	sectorsWritten  uint64
+	ioTicksMs       uint64
+}
+
+// Names of the per-device counters tracked by the rate engine
+const (
+	counterSectorsRead    = "sectors_read"
+	counterSectorsWritten = "sectors_written"
+	counterReads          = "reads"
+	counterWrites         = "writes"
+	counterIOTicks        = "io_ticks"
+)
+
+// DiskSampler computes per-device throughput from consecutive /proc/diskstats samples.
+//
+// Only whole disks are included: partitions, loop/ram devices and stacked
+// devices (device-mapper, md) are skipped so traffic is counted exactly once.
+type DiskSampler struct {
+	procRoot string
+	sysRoot  string
+	rates    *RateTracker[string]
+}
+
+// NewDiskSampler creates a new disk sampler reading from /proc and /sys
+func NewDiskSampler() *DiskSampler {
+	// The kernel prints I/O and sector counts as unsigned long, which wraps at
+	// 32 bits on 32-bit kernels, and the busy time always as a 32-bit value
+	rates := NewRateTracker[string]()
+	rates.Register(counterSectorsRead, CounterConfig{Scale: diskSectorSize / 1024.0, Width: bits.UintSize}) // KB/s
+	rates.Register(counterSectorsWritten, CounterConfig{Scale: diskSectorSize / 1024.0, Width: bits.UintSize})
+	rates.Register(counterReads, CounterConfig{Width: bits.UintSize})
+	rates.Register(counterWrites, CounterConfig{Width: bits.UintSize})
+	rates.Register(counterIOTicks, CounterConfig{Scale: 0.1, Width: 32}) // Busy ms per second -> %
+
+	return &DiskSampler{
+		procRoot: "/proc",
+		sysRoot:  "/sys",
+		rates:    rates,
+	}
+}
+
+// Update reads /proc/diskstats and returns device rates since the previous update
+func (ds *DiskSampler) Update(now time.Time) ([]DiskDeviceMetrics, error) {
+	current, err := ds.readDiskStats()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]DiskDeviceMetrics, 0, len(current))
+	for name, counters := range current {
+		devices = append(devices, DiskDeviceMetrics{
+			Name:        name,
+			ReadRate:    ds.rates.Observe(name, counterSectorsRead, counters.sectorsRead, now),
+			WriteRate:   ds.rates.Observe(name, counterSectorsWritten, counters.sectorsWritten, now),
+			ReadIOPS:    ds.rates.Observe(name, counterReads, counters.readsCompleted, now),
+			WriteIOPS:   ds.rates.Observe(name, counterWrites, counters.writesCompleted, now),
+			BusyPercent: math.Min(ds.rates.Observe(name, counterIOTicks, counters.ioTicksMs, now), 100),
+		})
+	}
+
+	// Forget devices that were detached
+	ds.rates.Retain(func(name string) bool {
+		_, exists := current[name]
+		return exists
+	})
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+
+	return devices, nil
+}
+
+// readDiskStats parses /proc/diskstats, keeping whole disks only
+func (ds *DiskSampler) readDiskStats() (map[string]diskCounters, error) {
+	file, err := os.Open(filepath.Join(ds.procRoot, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := make(map[string]diskCounters)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 {
+			continue
+		}
+		name := fields[2]
+		if !ds.isWholeDisk(name) {
+			continue
+		}
+
+		var values [10]uint64
+		valid := true
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[3+i], 10, 64)
+			if err != nil {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+
+		stats[name] = diskCounters{
+			readsCompleted:  values[0],
+			sectorsRead:     values[2],
+			writesCompleted: values[4],
+			sectorsWritten:  values[6],
+			ioTicksMs:       values[9],
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// isWholeDisk reports whether a device is a physical disk rather than a
+// partition, a loop/ram device or a device stacked on other disks
+func (ds *DiskSampler) isWholeDisk(name string) bool {
+	for _, prefix := range skippedDiskPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+
+	// Partitions don't get an entry of their own under /sys/block
+	blockDir := filepath.Join(ds.sysRoot, "block", strings.ReplaceAll(name, "/", "!"))
+	if _, err := os.Stat(blockDir); err != nil {
+		return false
+	}
+
+	// Device-mapper and md devices list their backing disks as slaves
+	if slaves, err := os.ReadDir(filepath.Join(blockDir, "slaves")); err == nil && len(slaves) > 0 {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+package monitor
+
+// FileDescriptor is an open file descriptor of a process
+type FileDescriptor struct {
+	FD     int
+	Type   string // file, dir, char, block, fifo, pipe, anon, tcp, tcp6, udp, udp6, unix or socket
+	Target string // Path, socket addresses or kernel object, e.g. "anon_inode:[eventfd]"
+	Flags  string // Open flags from fdinfo, e.g. "O_RDWR|O_NONBLOCK"
+	Pos    int64  // File position from fdinfo
+
+	// Sockets found in the socket tables of the process' network namespace
+	Local  string
+	Remote string
+	State  string // e.g. "ESTABLISHED" or "LISTEN"
+}
+
+// OpenFiles lists the file descriptors of a process with its limit
+type OpenFiles struct {
+	FDs       []FileDescriptor // Ascending by FD
+	SoftLimit int64            // RLIMIT_NOFILE, -1 when unlimited
+	HardLimit int64
+}
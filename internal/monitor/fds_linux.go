@@ -0,0 +1,306 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpStates names the TCP states of /proc/net/tcp (include/net/tcp_states.h)
+var tcpStates = map[uint64]string{
+	0x01: "ESTABLISHED",
+	0x02: "SYN_SENT",
+	0x03: "SYN_RECV",
+	0x04: "FIN_WAIT1",
+	0x05: "FIN_WAIT2",
+	0x06: "TIME_WAIT",
+	0x07: "CLOSE",
+	0x08: "CLOSE_WAIT",
+	0x09: "LAST_ACK",
+	0x0A: "LISTEN",
+	0x0B: "CLOSING",
+}
+
+// unixStates names the socket states of /proc/net/unix (include/uapi/linux/net.h)
+var unixStates = map[uint64]string{
+	1: "UNCONNECTED",
+	2: "CONNECTING",
+	3: "CONNECTED",
+	4: "DISCONNECTING",
+}
+
+// unixAcceptCon is the /proc/net/unix flag of listening sockets (__SO_ACCEPTCON)
+const unixAcceptCon = 0x10000
+
+// openFlags are the fdinfo flags shown besides the access mode. O_SYNC comes
+// before O_DSYNC, whose bit it includes.
+var openFlags = []struct {
+	flag int
+	name string
+}{
+	{unix.O_APPEND, "O_APPEND"},
+	{unix.O_NONBLOCK, "O_NONBLOCK"},
+	{unix.O_CLOEXEC, "O_CLOEXEC"},
+	{unix.O_SYNC, "O_SYNC"},
+	{unix.O_DSYNC, "O_DSYNC"},
+	{unix.O_DIRECT, "O_DIRECT"},
+	{unix.O_NOATIME, "O_NOATIME"},
+	{unix.O_ASYNC, "O_ASYNC"},
+	{unix.O_PATH, "O_PATH"},
+	{unix.O_TMPFILE, "O_TMPFILE"},
+}
+
+// readOpenFiles lists the file descriptors of a process from /proc/<pid>/fd,
+// with flags and positions from fdinfo and sockets resolved through the
+// socket tables of the process' network namespace
+func readOpenFiles(pid int32) (OpenFiles, error) {
+	procDir := "/proc/" + strconv.Itoa(int(pid))
+	entries, err := os.ReadDir(filepath.Join(procDir, "fd"))
+	if err != nil {
+		return OpenFiles{}, err
+	}
+
+	var files OpenFiles
+	files.SoftLimit, files.HardLimit = readOpenFilesLimit(procDir)
+
+	var sockets map[uint64]FileDescriptor // Loaded on the first socket
+	for _, entry := range entries {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(procDir, "fd", entry.Name())
+		target, err := os.Readlink(path)
+		if err != nil {
+			continue // Closed meanwhile
+		}
+
+		desc := FileDescriptor{FD: fd, Target: target}
+		switch {
+		case strings.HasPrefix(target, "socket:["):
+			desc.Type = "socket"
+			if sockets == nil {
+				sockets = readSocketDetails(filepath.Join(procDir, "net"))
+			}
+			inode, _ := strconv.ParseUint(strings.TrimSuffix(target[len("socket:["):], "]"), 10, 64)
+			if socket, exists := sockets[inode]; exists {
+				desc.Type = socket.Type
+				desc.Local, desc.Remote, desc.State = socket.Local, socket.Remote, socket.State
+				desc.Target = socketTarget(socket, target)
+			}
+		case strings.HasPrefix(target, "pipe:["):
+			desc.Type = "pipe"
+		case strings.HasPrefix(target, "anon_inode:"):
+			desc.Type = "anon"
+		default:
+			desc.Type = fileType(path)
+		}
+		desc.Flags, desc.Pos = readFDInfo(filepath.Join(procDir, "fdinfo", entry.Name()))
+		files.FDs = append(files.FDs, desc)
+	}
+
+	sort.Slice(files.FDs, func(i, j int) bool { return files.FDs[i].FD < files.FDs[j].FD })
+	return files, nil
+}
+
+// fileType classifies the file behind a descriptor by its mode
+func fileType(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "file"
+	}
+	mode := info.Mode()
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeCharDevice != 0:
+		return "char"
+	case mode&os.ModeDevice != 0:
+		return "block"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	}
+	return "file"
+}
+
+// socketTarget describes a socket by its addresses, or the path of a unix socket
+func socketTarget(socket FileDescriptor, fallback string) string {
+	switch {
+	case socket.Remote != "":
+		return socket.Local + " -> " + socket.Remote
+	case socket.Local != "":
+		return socket.Local
+	}
+	return fallback
+}
+
+// readFDInfo returns the decoded open flags and the file position of a descriptor
+func readFDInfo(path string) (string, int64) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0
+	}
+	var flags string
+	var pos int64
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "pos":
+			pos, _ = strconv.ParseInt(value, 10, 64)
+		case "flags":
+			if bits, err := strconv.ParseUint(value, 8, 64); err == nil {
+				flags = decodeOpenFlags(int(bits))
+			}
+		}
+	}
+	return flags, pos
+}
+
+// decodeOpenFlags names the access mode and flags of an open file
+func decodeOpenFlags(bits int) string {
+	names := []string{"O_RDONLY"}
+	switch bits & unix.O_ACCMODE {
+	case unix.O_WRONLY:
+		names[0] = "O_WRONLY"
+	case unix.O_RDWR:
+		names[0] = "O_RDWR"
+	}
+	for _, flag := range openFlags {
+		if bits&flag.flag == flag.flag {
+			names = append(names, flag.name)
+			bits &^= flag.flag
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// readOpenFilesLimit reads the RLIMIT_NOFILE soft and hard limits from
+// /proc/<pid>/limits, -1 when unlimited or unknown
+func readOpenFilesLimit(procDir string) (soft, hard int64) {
+	file, err := os.Open(filepath.Join(procDir, "limits"))
+	if err != nil {
+		return -1, -1
+	}
+	defer file.Close()
+
+	parse := func(value string) int64 {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return -1 // "unlimited"
+		}
+		return limit
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) >= 2 {
+			return parse(fields[0]), parse(fields[1])
+		}
+	}
+	return -1, -1
+}
+
+// readSocketDetails reads the sockets of a network namespace by inode, with
+// their addresses and state
+func readSocketDetails(netDir string) map[uint64]FileDescriptor {
+	sockets := make(map[uint64]FileDescriptor)
+	for _, table := range socketTables {
+		file, err := os.Open(filepath.Join(netDir, table))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // Skip header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if table == "unix" {
+				parseUnixSocket(fields, sockets)
+			} else {
+				parseInetSocket(table, fields, sockets)
+			}
+		}
+		file.Close()
+	}
+	return sockets
+}
+
+// parseInetSocket adds a line of /proc/net/{tcp,tcp6,udp,udp6}
+func parseInetSocket(proto string, fields []string, sockets map[uint64]FileDescriptor) {
+	if len(fields) < 10 {
+		return
+	}
+	inode, err := strconv.ParseUint(fields[9], 10, 64)
+	if err != nil || inode == 0 {
+		return
+	}
+	state, _ := strconv.ParseUint(fields[3], 16, 8)
+	socket := FileDescriptor{Type: proto, Local: decodeAddress(fields[1])}
+	if remote := decodeAddress(fields[2]); !strings.HasSuffix(remote, ":0") {
+		socket.Remote = remote
+	}
+	switch {
+	case strings.HasPrefix(proto, "tcp"):
+		socket.State = tcpStates[state]
+	case socket.Remote != "":
+		socket.State = "CONNECTED"
+	}
+	sockets[inode] = socket
+}
+
+// parseUnixSocket adds a line of /proc/net/unix
+func parseUnixSocket(fields []string, sockets map[uint64]FileDescriptor) {
+	if len(fields) < 7 {
+		return
+	}
+	inode, err := strconv.ParseUint(fields[6], 10, 64)
+	if err != nil || inode == 0 {
+		return
+	}
+	state, _ := strconv.ParseUint(fields[5], 16, 8)
+	socket := FileDescriptor{Type: "unix", State: unixStates[state]}
+	if flags, _ := strconv.ParseUint(fields[3], 16, 32); flags&unixAcceptCon != 0 {
+		socket.State = "LISTEN"
+	}
+	if len(fields) > 7 {
+		socket.Local = fields[7] // Bound path, "@" for the abstract namespace
+	}
+	sockets[inode] = socket
+}
+
+// decodeAddress formats an address of the socket tables, e.g. "0100007F:1F90"
+// as "127.0.0.1:8080". The address is stored as 32 bit words in host byte order.
+func decodeAddress(value string) string {
+	hexIP, hexPort, found := strings.Cut(value, ":")
+	if !found {
+		return value
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || len(raw)%4 != 0 {
+		return value
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return value
+	}
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		binary.BigEndian.PutUint32(ip[word:], binary.NativeEndian.Uint32(raw[word:]))
+	}
+	return net.JoinHostPort(ip.String(), strconv.FormatUint(port, 10))
+}
@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestDecodeOpenFlags(t *testing.T) {
+	octal := func(bits int) string { return "0" + strconv.FormatInt(int64(bits), 8) }
+	tests := []struct {
+		flags string // As in fdinfo
+		want  string
+	}{
+		{"00", "O_RDONLY"},
+		{"01", "O_WRONLY"},
+		{"02", "O_RDWR"},
+		{octal(unix.O_WRONLY | unix.O_APPEND | unix.O_CLOEXEC), "O_WRONLY|O_APPEND|O_CLOEXEC"},
+		{octal(unix.O_RDWR | unix.O_SYNC), "O_RDWR|O_SYNC"}, // O_SYNC includes the O_DSYNC bit
+		{octal(unix.O_WRONLY | unix.O_DSYNC), "O_WRONLY|O_DSYNC"},
+		{octal(unix.O_RDONLY | unix.O_NONBLOCK | unix.O_LARGEFILE), "O_RDONLY|O_NONBLOCK"}, // Flags without a name are left out
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "fdinfo")
+		if err := os.WriteFile(path, []byte("pos:\t42\nflags:\t"+tt.flags+"\nmnt_id:\t25\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		flags, pos := readFDInfo(path)
+		if flags != tt.want || pos != 42 {
+			t.Errorf("flags %s: got %q at %d, want %q at 42", tt.flags, flags, pos, tt.want)
+		}
+	}
+}
+
+func TestReadOpenFilesLimit(t *testing.T) {
+	tests := []struct {
+		line       string
+		soft, hard int64
+	}{
+		{"Max open files            1024                 524288               files     ", 1024, 524288},
+		{"Max open files            unlimited            unlimited            files     ", -1, -1},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		limits := "Limit                     Soft Limit           Hard Limit           Units     \n" +
+			"Max processes             63432                63432                processes \n" +
+			tt.line + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "limits"), []byte(limits), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if soft, hard := readOpenFilesLimit(dir); soft != tt.soft || hard != tt.hard {
+			t.Errorf("%q: got %d/%d, want %d/%d", tt.line, soft, hard, tt.soft, tt.hard)
+		}
+	}
+	if soft, hard := readOpenFilesLimit(t.TempDir()); soft != -1 || hard != -1 {
+		t.Errorf("missing limits file: got %d/%d, want -1/-1", soft, hard)
+	}
+}
+
+func TestReadOpenFilesOfThisProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	files, err := readOpenFiles(int32(os.Getpid()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var foundFile, foundSocket bool
+	for _, fd := range files.FDs {
+		if fd.FD == int(file.Fd()) {
+			foundFile = true
+			if fd.Type != "file" || fd.Target != path || fd.Flags != "O_WRONLY|O_APPEND|O_CLOEXEC" {
+				t.Errorf("file descriptor = %+v", fd)
+			}
+		}
+		if fd.Type == "tcp" && fd.Local == listener.Addr().String() {
+			foundSocket = true
+			if fd.State != "LISTEN" {
+				t.Errorf("listening socket in state %q", fd.State)
+			}
+		}
+	}
+	if !foundFile {
+		t.Errorf("open file %s not listed", path)
+	}
+	if !foundSocket {
+		t.Errorf("listening socket %s not listed", listener.Addr())
+	}
+	if files.SoftLimit == 0 {
+		t.Error("open files limit not read")
+	}
+}
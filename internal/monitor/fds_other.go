@@ -0,0 +1,10 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// readOpenFiles is only implemented on Linux
+func readOpenFiles(pid int32) (OpenFiles, error) {
+	return OpenFiles{}, errors.New("open files are only supported on Linux")
+}
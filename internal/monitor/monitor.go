@@ -2,16 +2,16 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"math/bits"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
-
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
-	"github.com/shirou/gopsutil/v3/net"
-	"github.com/shirou/gopsutil/v3/process"
 )
 
 // SortBy represents different sorting options
@@ -24,40 +24,162 @@ const (
 	SortByMemory
 )
 
-// ProcessIOCounters holds I/O counters for a process
-type ProcessIOCounters struct {
-	ReadBytes  uint64
-	WriteBytes uint64
-	Timestamp  time.Time
+// sortByNames maps command line names to sort orders
+var sortByNames = map[string]SortBy{
+	"pid":    SortByPID,
+	"name":   SortByName,
+	"cpu":    SortByCPU,
+	"memory": SortByMemory,
+	"mem":    SortByMemory,
+}
+
+// ParseSortBy parses a sort order name (pid, name, cpu or memory)
+func ParseSortBy(name string) (SortBy, error) {
+	if sortBy, exists := sortByNames[strings.ToLower(name)]; exists {
+		return sortBy, nil
+	}
+	return SortByPID, fmt.Errorf("unknown sort order %q (available: pid, name, cpu, memory)", name)
+}
+
+// DefaultDescending reports whether a sort order is descending by default,
+// as in the interactive UI: usage columns descend, identifiers ascend
+func (s SortBy) DefaultDescending() bool {
+	return s == SortByCPU || s == SortByMemory
+}
+
+// CPUMode selects how process CPU usage is expressed
+type CPUMode int
+
+const (
+	// CPUModePerCore expresses usage relative to a single core, so multi-threaded processes can exceed 100%
+	CPUModePerCore CPUMode = iota
+	// CPUModeNormalized expresses usage relative to all cores, so 100% means the whole machine
+	CPUModeNormalized
+)
+
+// String returns the name of the mode as used in the JSON API
+func (c CPUMode) String() string {
+	if c == CPUModeNormalized {
+		return "normalized"
+	}
+	return "per-core"
+}
+
+// FromPerCore converts a per-core CPU percentage to this mode
+func (c CPUMode) FromPerCore(perCore float64, numCPU int) float64 {
+	if c == CPUModeNormalized && numCPU > 0 {
+		return perCore / float64(numCPU)
+	}
+	return perCore
+}
+
+// ToPerCore converts a CPU percentage expressed in this mode to per-core
+func (c CPUMode) ToPerCore(value float64, numCPU int) float64 {
+	if c == CPUModeNormalized && numCPU > 0 {
+		return value * float64(numCPU)
+	}
+	return value
+}
+
+// Names of the per-process counters tracked by the rate engine
+const (
+	counterCPU         = "cpu"
+	counterDiskRead    = "disk_read"
+	counterDiskWrite   = "disk_write"
+	counterNetSent     = "net_sent"
+	counterNetRecv     = "net_recv"
+	counterCtxSwitches = "ctx_switches"
+	counterMinorFaults = "minor_faults"
+	counterMajorFaults = "major_faults"
+)
+
+// rateMinInterval is the shortest interval process rates are computed over;
+// samples closer together reuse the previous rate instead of a noisy delta
+const rateMinInterval = 500 * time.Millisecond
+
+// netRateSmoothing is the EWMA weight of the newest per-process network rate
+const netRateSmoothing = 0.5
+
+// newProcessRateTracker creates the rate engine with all per-process counters registered
+func newProcessRateTracker() *RateTracker[ProcessKey] {
+	rates := NewRateTracker[ProcessKey]()
+	rates.Register(counterCPU, CounterConfig{Scale: 0.1, MinInterval: rateMinInterval}) // CPU ms per second -> per-core %
+	rates.Register(counterDiskRead, CounterConfig{Scale: 1.0 / 1024, MinInterval: rateMinInterval})
+	rates.Register(counterDiskWrite, CounterConfig{Scale: 1.0 / 1024, MinInterval: rateMinInterval})
+	// Network traffic is partly estimated from shares of the interface
+	// counters, which jump between ticks, so it is smoothed
+	rates.Register(counterNetSent, CounterConfig{Scale: 1.0 / 1024, MinInterval: rateMinInterval, Smoothing: netRateSmoothing})
+	rates.Register(counterNetRecv, CounterConfig{Scale: 1.0 / 1024, MinInterval: rateMinInterval, Smoothing: netRateSmoothing})
+	// The kernel keeps these as unsigned long, 32 bits wide on 32-bit kernels
+	rates.Register(counterCtxSwitches, CounterConfig{MinInterval: rateMinInterval, Width: bits.UintSize})
+	rates.Register(counterMinorFaults, CounterConfig{MinInterval: rateMinInterval, Width: bits.UintSize})
+	rates.Register(counterMajorFaults, CounterConfig{MinInterval: rateMinInterval, Width: bits.UintSize})
+	return rates
+}
+
+// ProcessReplacedError is returned when a PID now belongs to a different
+// process than the one that was asked for
+type ProcessReplacedError struct {
+	Old  ProcessKey
+	New  ProcessKey
+	Name string // Name of the new process
+}
+
+func (e *ProcessReplacedError) Error() string {
+	return fmt.Sprintf("process %d was replaced by %q (started %s)",
+		e.Old.PID, e.Name, time.UnixMilli(e.New.StartTime).Format("15:04:05"))
 }
 
 // Monitor handles system monitoring
 type Monitor struct {
-	mu               sync.RWMutex
-	processes        []ProcessInfo
-	systemMetrics    SystemMetrics
-	processMetrics   map[int32]*ProcessMetrics
-	sortBy           SortBy
-	sortDesc         bool
-	metricsCapacity  int
-	lastNetStats     map[string]net.IOCountersStat
-	lastProcessStats map[int32]*process.Process
-	lastProcessIO    map[int32]*ProcessIOCounters
-	systemIOTotal    float64 // Total system I/O rate for percentage calculation
-}
-
-// NewMonitor creates a new monitor instance
+	mu             sync.RWMutex
+	collector      Collector
+	clock          Clock
+	processes      []ProcessInfo
+	tree           *ProcessTree // All processes of the last update, not only the top ones
+	systemMetrics  SystemMetrics
+	processMetrics map[ProcessKey]*ProcessMetrics
+	sortBy         SortBy
+	sortDesc       bool
+	cpuMode        CPUMode
+	filter         string // Only processes matching it are monitored in detail
+	metricTiers    []TierConfig
+	pidOwners      map[int32]pidOwner // Process currently holding each PID
+	rates          *RateTracker[ProcessKey]
+	subscribers    []func(Update)
+	events         []ProcessEvent // Noticed since the last publish
+	eventHandlers  []func(ProcessEvent)
+}
+
+// pidOwner is the process holding a PID
+type pidOwner struct {
+	key  ProcessKey
+	name string
+}
+
+// NewMonitor creates a new monitor instance collecting from the local host
 func NewMonitor() *Monitor {
+	return NewMonitorWithCollector(NewGopsutilCollector(), systemClock{})
+}
+
+// NewSnapshotMonitor creates a monitor whose data only comes from ApplySnapshot
+func NewSnapshotMonitor(clock Clock) *Monitor {
+	return NewMonitorWithCollector(snapshotCollector{}, clock)
+}
+
+// NewMonitorWithCollector creates a monitor backed by the given collector and clock
+func NewMonitorWithCollector(collector Collector, clock Clock) *Monitor {
 	return &Monitor{
-		processes:        make([]ProcessInfo, 0),
-		processMetrics:   make(map[int32]*ProcessMetrics),
-		sortBy:           SortByCPU,
-		sortDesc:         true,
-		metricsCapacity:  60, // Keep 60 seconds of data
-		lastNetStats:     make(map[string]net.IOCountersStat),
-		lastProcessStats: make(map[int32]*process.Process),
-		lastProcessIO:    make(map[int32]*ProcessIOCounters),
-		systemIOTotal:    0,
+		collector:      collector,
+		clock:          clock,
+		processes:      make([]ProcessInfo, 0),
+		tree:           BuildProcessTree(nil),
+		processMetrics: make(map[ProcessKey]*ProcessMetrics),
+		sortBy:         SortByCPU,
+		sortDesc:       true,
+		metricTiers:    DefaultMetricTiers,
+		pidOwners:      make(map[int32]pidOwner),
+		rates:          newProcessRateTracker(),
 	}
 }
 
@@ -71,6 +193,22 @@ func (m *Monitor) GetProcesses() []ProcessInfo {
 	return processes
 }
 
+// GetProcessTree returns the parent/child hierarchy of all processes seen in
+// the last update. Processes outside the top list have no network usage,
+// command line or activity rates.
+func (m *Monitor) GetProcessTree() *ProcessTree {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tree
+}
+
+// GetAllProcesses returns every process of the last update ordered by PID,
+// regardless of the top list and the process filter. Processes outside the top
+// list have the values described at GetProcessTree.
+func (m *Monitor) GetAllProcesses() []ProcessInfo {
+	return m.GetProcessTree().processes()
+}
+
 // GetSystemMetrics returns the current system metrics
 func (m *Monitor) GetSystemMetrics() SystemMetrics {
 	m.mu.RLock()
@@ -78,57 +216,56 @@ func (m *Monitor) GetSystemMetrics() SystemMetrics {
 	return m.systemMetrics
 }
 
-// GetProcessMetrics returns metrics for a specific process
-func (m *Monitor) GetProcessMetrics(pid int32) *ProcessMetrics {
+// GetProcessMetrics returns the finest-resolution samples of a specific process
+func (m *Monitor) GetProcessMetrics(key ProcessKey) []MetricSample {
+	return m.GetProcessHistory(key, 0)
+}
+
+// GetProcessHistory returns the samples of a process at one of the resolution
+// tiers from MetricTiers, oldest first
+func (m *Monitor) GetProcessHistory(key ProcessKey, tier int) []MetricSample {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if metrics, exists := m.processMetrics[pid]; exists {
-		// Return a deep copy to avoid race conditions with shared slice headers
-		metricsCopy := &ProcessMetrics{
-			Timestamps:    append([]time.Time(nil), metrics.Timestamps...),
-			CPUPercent:    append([]float64(nil), metrics.CPUPercent...),
-			MemoryMB:      append([]float64(nil), metrics.MemoryMB...),
-			DiskReadRate:  append([]float64(nil), metrics.DiskReadRate...),
-			DiskWriteRate: append([]float64(nil), metrics.DiskWriteRate...),
-			DiskReadPerc:  append([]float64(nil), metrics.DiskReadPerc...),
-			DiskWritePerc: append([]float64(nil), metrics.DiskWritePerc...),
-			NetSentRate:   append([]float64(nil), metrics.NetSentRate...),
-			NetRecvRate:   append([]float64(nil), metrics.NetRecvRate...),
-		}
-		return metricsCopy
+	if metrics, exists := m.processMetrics[key]; exists {
+		return metrics.Samples(tier)
 	}
 	return nil
 }
 
+// MetricTiers returns the resolution tiers kept for each process
+func (m *Monitor) MetricTiers() []TierConfig {
+	return m.metricTiers
+}
+
 // EnsureProcessMetrics ensures that a process has time series metrics tracking
 // This is called when a user selects a process that might not be in the top 150
-func (m *Monitor) EnsureProcessMetrics(pid int32) {
+func (m *Monitor) EnsureProcessMetrics(key ProcessKey) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// If we don't have metrics for this process, create them
-	if _, exists := m.processMetrics[pid]; !exists {
-		m.processMetrics[pid] = NewProcessMetrics(m.metricsCapacity)
+	if _, exists := m.processMetrics[key]; !exists {
+		m.processMetrics[key] = NewProcessMetrics(m.metricTiers)
 	}
 }
 
-// GetCurrentProcessData gets current data for a specific process and updates its time series
-func (m *Monitor) GetCurrentProcessData(pid int32) (*ProcessInfo, error) {
-	// Ensure we have metrics tracking for this process
-	m.EnsureProcessMetrics(pid)
-
-	// Try to get the process
-	proc, err := process.NewProcess(pid)
-	if err != nil {
-		return nil, err
-	}
-
+// GetCurrentProcessData gets current data for a specific process and updates its time series.
+// A *ProcessReplacedError is returned when the PID has been reused by another process.
+func (m *Monitor) GetCurrentProcessData(key ProcessKey) (*ProcessInfo, error) {
 	// Get detailed process info
-	processInfo, err := m.getDetailedProcessInfo(proc)
+	sample, err := m.collector.ProcessDetails(context.Background(), key.PID)
 	if err != nil {
 		return nil, err
 	}
+	processInfo := m.getBasicProcessInfo(sample)
+	m.addProcessDetails(&processInfo, sample)
+	if current := processInfo.Key(); current != key {
+		return nil, &ProcessReplacedError{Old: key, New: current, Name: processInfo.Name}
+	}
+
+	// Ensure we have metrics tracking for this process
+	m.EnsureProcessMetrics(key)
 
 	// Update time series metrics for this process
 	m.updateProcessTimeSeriesMetrics(processInfo)
@@ -136,6 +273,89 @@ func (m *Monitor) GetCurrentProcessData(pid int32) (*ProcessInfo, error) {
 	return &processInfo, nil
 }
 
+// Signal sends a signal to a process after checking that its PID still
+// belongs to it. A *ProcessReplacedError is returned when the PID was reused.
+func (m *Monitor) Signal(ctx context.Context, key ProcessKey, sig syscall.Signal) error {
+	if err := m.checkProcess(ctx, key); err != nil {
+		return err
+	}
+	return m.collector.Signal(ctx, key.PID, sig)
+}
+
+// Scheduling returns the nice value, I/O priority and CPU affinity of a process
+func (m *Monitor) Scheduling(ctx context.Context, key ProcessKey) (Scheduling, error) {
+	if err := m.checkProcess(ctx, key); err != nil {
+		return Scheduling{}, err
+	}
+	return m.collector.Scheduling(ctx, key.PID)
+}
+
+// SetNice changes the nice value of a process, see Signal for the PID check
+func (m *Monitor) SetNice(ctx context.Context, key ProcessKey, nice int) error {
+	if err := ValidateNice(nice); err != nil {
+		return err
+	}
+	if err := m.checkProcess(ctx, key); err != nil {
+		return err
+	}
+	return m.collector.SetNice(ctx, key.PID, nice)
+}
+
+// SetIOPriority changes the I/O class and level of a process, see Signal for
+// the PID check
+func (m *Monitor) SetIOPriority(ctx context.Context, key ProcessKey, prio IOPriority) error {
+	if err := prio.Validate(); err != nil {
+		return err
+	}
+	if err := m.checkProcess(ctx, key); err != nil {
+		return err
+	}
+	return m.collector.SetIOPriority(ctx, key.PID, prio)
+}
+
+// SetAffinity restricts a process to the given CPUs, see Signal for the PID check
+func (m *Monitor) SetAffinity(ctx context.Context, key ProcessKey, cpus []int) error {
+	if len(cpus) == 0 {
+		return errors.New("no CPUs given")
+	}
+	if err := m.checkProcess(ctx, key); err != nil {
+		return err
+	}
+	return m.collector.SetAffinity(ctx, key.PID, cpus)
+}
+
+// OpenFiles lists the file descriptors of a process, see Signal for the PID check
+func (m *Monitor) OpenFiles(ctx context.Context, key ProcessKey) (OpenFiles, error) {
+	if err := m.checkProcess(ctx, key); err != nil {
+		return OpenFiles{}, err
+	}
+	return m.collector.OpenFiles(ctx, key.PID)
+}
+
+// Cmdline reads the command line of a process, see Signal for the PID check
+func (m *Monitor) Cmdline(ctx context.Context, key ProcessKey) (string, error) {
+	sample, err := m.collector.ProcessDetails(ctx, key.PID)
+	if err != nil {
+		return "", fmt.Errorf("process %d has exited", key.PID)
+	}
+	if current := NewProcessKey(sample.PID, sample.CreateTime); current != key {
+		return "", &ProcessReplacedError{Old: key, New: current, Name: sample.Name}
+	}
+	return sample.Cmdline, nil
+}
+
+// checkProcess returns an error unless the PID of a process still belongs to it
+func (m *Monitor) checkProcess(ctx context.Context, key ProcessKey) error {
+	sample, err := m.collector.Process(ctx, key.PID)
+	if err != nil {
+		return fmt.Errorf("process %d has exited", key.PID)
+	}
+	if current := NewProcessKey(sample.PID, sample.CreateTime); current != key {
+		return &ProcessReplacedError{Old: key, New: current, Name: sample.Name}
+	}
+	return nil
+}
+
 // SetSorting sets the sorting criteria
 func (m *Monitor) SetSorting(sortBy SortBy, desc bool) {
 	m.mu.Lock()
@@ -144,10 +364,62 @@ func (m *Monitor) SetSorting(sortBy SortBy, desc bool) {
 	m.sortDesc = desc
 }
 
+// GetSorting returns the sorting criteria
+func (m *Monitor) GetSorting() (SortBy, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sortBy, m.sortDesc
+}
+
+// SetCPUMode switches between per-core and normalized CPU usage.
+// Current values and recorded time series are rescaled so that the table,
+// graphs and sort order stay consistent.
+func (m *Monitor) SetCPUMode(mode CPUMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if mode == m.cpuMode {
+		return
+	}
+
+	numCPU := float64(m.numCPU())
+	factor := numCPU
+	if mode == CPUModeNormalized {
+		factor = 1 / numCPU
+	}
+	m.cpuMode = mode
+
+	for i := range m.processes {
+		m.processes[i].CPUPercent *= factor
+	}
+	m.tree = m.tree.scaleCPU(factor)
+	for _, metrics := range m.processMetrics {
+		metrics.scaleCPU(factor)
+	}
+	m.sortProcesses()
+}
+
+// SetProcessFilter limits the processes monitored in detail, and returned by
+// GetProcesses, to those matching the query like FilterProcesses. The top
+// processes are then chosen among all matching ones, so a match is never
+// lost to busier processes that don't match.
+func (m *Monitor) SetProcessFilter(query string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.filter = query
+}
+
+// GetCPUMode returns the current CPU usage mode
+func (m *Monitor) GetCPUMode() CPUMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cpuMode
+}
+
 // UpdateMetrics updates all system and process metrics
 func (m *Monitor) UpdateMetrics(ctx context.Context) error {
 	// Update system metrics
-	if err := m.updateSystemMetrics(); err != nil {
+	if err := m.updateSystemMetrics(ctx); err != nil {
 		return fmt.Errorf("failed to update system metrics: %w", err)
 	}
 
@@ -159,30 +431,32 @@ func (m *Monitor) UpdateMetrics(ctx context.Context) error {
 	return nil
 }
 
-func (m *Monitor) updateSystemMetrics() error {
-	// Get CPU usage
-	cpuPercent, err := cpu.Percent(0, false)
+func (m *Monitor) updateSystemMetrics(ctx context.Context) error {
+	now := m.clock.Now()
+	sample, err := m.collector.System(ctx, now)
 	if err != nil {
 		return err
 	}
-	var cpuValue float64
-	if len(cpuPercent) > 0 {
-		cpuValue = cpuPercent[0]
-	}
 
-	// Get memory usage
-	memStat, err := mem.VirtualMemory()
-	if err != nil {
-		return err
+	// Aggregate disk throughput is the baseline for per-process I/O percentages
+	var diskReadRate, diskWriteRate float64
+	for _, disk := range sample.Disks {
+		diskReadRate += disk.ReadRate
+		diskWriteRate += disk.WriteRate
 	}
 
 	m.mu.Lock()
 	m.systemMetrics = SystemMetrics{
-		CPUPercent:    cpuValue,
-		MemoryPercent: memStat.UsedPercent,
-		TotalMemoryMB: float64(memStat.Total) / 1024 / 1024,
-		UsedMemoryMB:  float64(memStat.Used) / 1024 / 1024,
-		Timestamp:     time.Now(),
+		CPUPercent:    sample.CPUPercent,
+		NumCPU:        sample.NumCPU,
+		MemoryPercent: sample.MemoryPercent,
+		TotalMemoryMB: float64(sample.TotalMemoryBytes) / 1024 / 1024,
+		UsedMemoryMB:  float64(sample.UsedMemoryBytes) / 1024 / 1024,
+		DiskReadRate:  diskReadRate,
+		DiskWriteRate: diskWriteRate,
+		Disks:         sample.Disks,
+		Load:          sample.Load,
+		Timestamp:     now,
 	}
 	m.mu.Unlock()
 
@@ -190,20 +464,13 @@ func (m *Monitor) updateSystemMetrics() error {
 }
 
 func (m *Monitor) updateProcessMetrics(ctx context.Context) error {
-	pids, err := process.Pids()
+	pids, err := m.collector.Pids(ctx)
 	if err != nil {
 		return err
 	}
 
 	// Performance optimization: Process in batches and prioritize interesting processes
 	allProcesses := make([]ProcessInfo, 0, len(pids))
-	newProcessStats := make(map[int32]*process.Process)
-
-	// Calculate system I/O total for percentage calculations
-	systemIOTotal := m.calculateSystemIOTotal()
-	m.mu.Lock()
-	m.systemIOTotal = systemIOTotal
-	m.mu.Unlock()
 
 	// First pass: Quick scan to get basic info for all processes
 	for i, pid := range pids {
@@ -219,160 +486,253 @@ func (m *Monitor) updateProcessMetrics(ctx context.Context) error {
 			time.Sleep(1 * time.Millisecond)
 		}
 
-		// Reuse existing process object if available
-		var proc *process.Process
-		if existingProc, exists := m.lastProcessStats[pid]; exists {
-			proc = existingProc
-		} else {
-			var err error
-			proc, err = process.NewProcess(pid)
-			if err != nil {
-				continue // Process might have disappeared
-			}
-		}
-
-		newProcessStats[pid] = proc
-
 		// Get basic process info (lightweight operations only)
-		processInfo, err := m.getBasicProcessInfo(proc)
+		sample, err := m.collector.Process(ctx, pid)
 		if err != nil {
 			continue // Skip processes we can't read
 		}
 
-		allProcesses = append(allProcesses, processInfo)
+		allProcesses = append(allProcesses, m.getBasicProcessInfo(sample))
 	}
 
-	// Sort by resource usage and keep top processes
+	// Forget state of processes that exited
+	m.pruneExitedProcesses(pids)
+
+	// Sort by resource usage and keep top processes; the tree keeps them all
 	m.sortProcessesByResourceUsage(allProcesses)
+	everyProcess := allProcesses
+	m.mu.RLock()
+	filter := m.filter
+	m.mu.RUnlock()
+	allProcesses = FilterProcesses(allProcesses, filter)
 	maxProcesses := 150 // Keep top 150 processes for detailed monitoring
 	if len(allProcesses) > maxProcesses {
 		allProcesses = allProcesses[:maxProcesses]
 	}
 
+	// Attribute network traffic to the tracked processes, including any the
+	// user selected outside of the top list
+	m.collector.UpdateNetwork(m.trackedPIDs(allProcesses), m.clock.Now())
+
 	// Second pass: Detailed monitoring for top processes only
 	processes := make([]ProcessInfo, 0, len(allProcesses))
 	for _, basicInfo := range allProcesses {
-		if proc, exists := newProcessStats[basicInfo.PID]; exists {
-			// Get detailed info including I/O metrics
-			processInfo, err := m.getDetailedProcessInfo(proc)
-			if err != nil {
-				continue
-			}
-			processes = append(processes, processInfo)
-
-			// Update time-series metrics for this process
-			m.updateProcessTimeSeriesMetrics(processInfo)
+		// Get detailed info including I/O metrics
+		processInfo, err := m.getDetailedProcessInfo(ctx, basicInfo)
+		if err != nil {
+			continue
 		}
+		processes = append(processes, processInfo)
+
+		// Update time-series metrics for this process
+		m.updateProcessTimeSeriesMetrics(processInfo)
 	}
 
+	// The tree shows the detailed info of the top processes
+	detailed := make(map[ProcessKey]ProcessInfo, len(processes))
+	for _, proc := range processes {
+		detailed[proc.Key()] = proc
+	}
+	treeProcesses := make([]ProcessInfo, len(everyProcess))
+	for i, proc := range everyProcess {
+		if info, exists := detailed[proc.Key()]; exists {
+			proc = info
+		}
+		treeProcesses[i] = proc
+	}
+	tree := BuildProcessTree(treeProcesses)
+
 	m.mu.Lock()
 	m.processes = processes
-	m.lastProcessStats = newProcessStats
+	m.tree = tree
 	m.sortProcesses()
 	m.mu.Unlock()
 
+	m.publish()
 	return nil
 }
 
-// calculateSystemIOTotal calculates total system I/O for percentage calculations
-func (m *Monitor) calculateSystemIOTotal() float64 {
-	// Get system-wide disk I/O stats
-	// This is a simplified approach - in reality you'd want to get disk stats
-	// For now, we'll use a reasonable baseline of 100 MB/s as "100%" I/O
-	return 100 * 1024 // 100 MB/s in KB/s
-}
+// getBasicProcessInfo converts the lightweight sample used for initial sorting
+func (m *Monitor) getBasicProcessInfo(sample ProcessSample) ProcessInfo {
+	key := NewProcessKey(sample.PID, sample.CreateTime)
+	m.observeProcess(key, sample.Name)
+	now := m.clock.Now()
+
+	info := ProcessInfo{
+		PID:        sample.PID,
+		PPID:       sample.PPID,
+		Name:       sample.Name,
+		CPUPercent: m.cpuPercent(key, sample.CPUTime, now),
+		MemoryPerc: sample.MemoryPercent,
+		MemoryMB:   float64(sample.MemoryRSS) / 1024 / 1024,
+		CreateTime: sample.CreateTime,
+		State:      sample.State,
+		Threads:    sample.Threads,
+		Nice:       sample.Nice,
+		Priority:   sample.Priority,
+		TTY:        sample.TTY,
+		Username:   sample.Username,
+	}
+
+	// Calculate I/O rates from the cumulative counters
+	if sample.HasIO {
+		// Read a consistent snapshot of system disk throughput under lock
+		m.mu.RLock()
+		systemReadRate := m.systemMetrics.DiskReadRate
+		systemWriteRate := m.systemMetrics.DiskWriteRate
+		m.mu.RUnlock()
 
-// getBasicProcessInfo gets lightweight process info for initial sorting
-func (m *Monitor) getBasicProcessInfo(proc *process.Process) (ProcessInfo, error) {
-	info := ProcessInfo{PID: proc.Pid}
+		info.DiskReadKB = float64(sample.ReadBytes) / 1024
+		info.DiskWriteKB = float64(sample.WriteBytes) / 1024
+		info.DiskReadRate = m.rates.Observe(key, counterDiskRead, sample.ReadBytes, now)    // KB/s
+		info.DiskWriteRate = m.rates.Observe(key, counterDiskWrite, sample.WriteBytes, now) // KB/s
 
-	// Get only the essential info for sorting
-	if name, err := proc.Name(); err == nil {
-		info.Name = name
+		// Calculate percentage of system I/O
+		info.DiskReadPerc = diskPercentage(info.DiskReadRate, systemReadRate)
+		info.DiskWritePerc = diskPercentage(info.DiskWriteRate, systemWriteRate)
 	}
+	return info
+}
+
+// observeProcess records which process holds a PID. When the PID was
+// recycled, everything recorded for the previous holder is discarded.
+func (m *Monitor) observeProcess(key ProcessKey, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if cpuPercent, err := proc.CPUPercent(); err == nil {
-		info.CPUPercent = cpuPercent
+	if owner, exists := m.pidOwners[key.PID]; exists && owner.key != key {
+		m.forgetProcess(owner.key)
+		now := m.clock.Now()
+		m.events = append(m.events, ProcessEvent{Kind: PIDReused, Time: now, Process: owner.key, Name: owner.name, Replacement: key, ReplacementName: name})
 	}
+	m.pidOwners[key.PID] = pidOwner{key: key, name: name}
+}
+
+// forgetProcess drops all state kept for a process. Callers must hold the lock.
+func (m *Monitor) forgetProcess(key ProcessKey) {
+	delete(m.processMetrics, key)
+	m.rates.Forget(key)
+}
 
-	if memPercent, err := proc.MemoryPercent(); err == nil {
-		info.MemoryPerc = memPercent
+// cpuPercent computes CPU usage from the CPU time consumed since the previous
+// sample of the same process, expressed in the current CPU mode
+func (m *Monitor) cpuPercent(key ProcessKey, cpuTime float64, now time.Time) float64 {
+	perCore := m.rates.Observe(key, counterCPU, uint64(cpuTime*1000), now)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.scaleCPU(perCore)
+}
+
+// scaleCPU converts a per-core percentage to the current CPU mode.
+// Callers must hold the lock.
+func (m *Monitor) scaleCPU(perCore float64) float64 {
+	return m.cpuMode.FromPerCore(perCore, m.numCPU())
+}
+
+// numCPU returns the logical CPU count reported by the collector, at least 1.
+// Callers must hold the lock.
+func (m *Monitor) numCPU() int {
+	if m.systemMetrics.NumCPU > 0 {
+		return m.systemMetrics.NumCPU
 	}
+	return 1
+}
 
-	if memInfo, err := proc.MemoryInfo(); err == nil {
-		info.MemoryMB = float64(memInfo.RSS) / 1024 / 1024
+// pruneExitedProcesses drops rate baselines for processes that are no longer running.
+// Time series are kept until CleanupOldMetrics so the detail view can still show them.
+func (m *Monitor) pruneExitedProcesses(pids []int32) {
+	live := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		live[pid] = true
 	}
 
-	return info, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	for pid, owner := range m.pidOwners {
+		if !live[pid] {
+			delete(m.pidOwners, pid)
+			m.events = append(m.events, ProcessEvent{Kind: ProcessExited, Time: now, Process: owner.key, Name: owner.name})
+		}
+	}
+	m.rates.Retain(func(key ProcessKey) bool {
+		return m.pidOwners[key.PID].key == key
+	})
 }
 
-// getDetailedProcessInfo gets comprehensive process info including I/O
-func (m *Monitor) getDetailedProcessInfo(proc *process.Process) (ProcessInfo, error) {
-	// Start with basic info
-	info, err := m.getBasicProcessInfo(proc)
+// getDetailedProcessInfo adds the detail fields to the info of the basic pass,
+// whose CPU and disk rates are already observed for this tick
+func (m *Monitor) getDetailedProcessInfo(ctx context.Context, basic ProcessInfo) (ProcessInfo, error) {
+	sample, err := m.collector.ProcessDetails(ctx, basic.PID)
 	if err != nil {
-		return info, err
+		return basic, err
+	}
+	if key := NewProcessKey(sample.PID, sample.CreateTime); key != basic.Key() {
+		return basic, &ProcessReplacedError{Old: basic.Key(), New: key, Name: sample.Name}
 	}
 
-	now := time.Now()
+	info := basic
+	m.addProcessDetails(&info, sample)
+	return info, nil
+}
 
-	// Read a consistent snapshot of system I/O total under lock
-	m.mu.RLock()
-	systemIOTotal := m.systemIOTotal
-	m.mu.RUnlock()
+// addProcessDetails fills in the fields only the detailed sample carries
+func (m *Monitor) addProcessDetails(info *ProcessInfo, sample ProcessSample) {
+	info.Username = sample.Username
+	info.Cmdline = sample.Cmdline
+	info.Cwd = sample.Cwd
+	info.Exe = sample.Exe
+	key := info.Key()
+
+	now := m.clock.Now()
+
+	// Network counters only advance when the collector attributes traffic,
+	// so they are observed at the time of that attribution
+	if sample.HasNet {
+		info.NetSentKB = float64(sample.Net.SentBytes) / 1024
+		info.NetRecvKB = float64(sample.Net.RecvBytes) / 1024
+		info.NetSentRate = m.rates.Observe(key, counterNetSent, sample.Net.SentBytes, sample.Net.Timestamp) // KB/s
+		info.NetRecvRate = m.rates.Observe(key, counterNetRecv, sample.Net.RecvBytes, sample.Net.Timestamp) // KB/s
+	}
 
-	// Get process creation time
-	if createTime, err := proc.CreateTime(); err == nil {
-		info.CreateTime = time.Unix(createTime/1000, 0)
+	// Scheduler and memory activity
+	if sample.HasActivity {
+		info.CtxSwitchRate = m.rates.Observe(key, counterCtxSwitches, sample.CtxSwitches, now)
+		info.MinorFaultPS = m.rates.Observe(key, counterMinorFaults, sample.MinorFaults, now)
+		info.MajorFaultPS = m.rates.Observe(key, counterMajorFaults, sample.MajorFaults, now)
 	}
+}
 
-	// Get I/O counters and calculate rates
-	if ioCounters, err := proc.IOCounters(); err == nil {
-		info.DiskReadKB = float64(ioCounters.ReadBytes) / 1024
-		info.DiskWriteKB = float64(ioCounters.WriteBytes) / 1024
+// diskPercentage returns a process disk rate as a percentage of the system-wide rate
+func diskPercentage(processRate, systemRate float64) float64 {
+	if systemRate > 0 {
+		// Process counters and disk counters are sampled at different moments,
+		// so the share can briefly exceed the whole
+		return math.Min((processRate/systemRate)*100, 100)
+	}
+	// Fallback when the disks were idle or unreadable: treat 1 MB/s as 1%
+	return math.Min(processRate/1024, 100)
+}
 
-		// Calculate rates if we have previous data
-		m.mu.RLock()
-		lastIO, exists := m.lastProcessIO[proc.Pid]
-		m.mu.RUnlock()
-		if exists {
-			timeDiff := now.Sub(lastIO.Timestamp).Seconds()
-			if timeDiff > 0 {
-				readDiff := float64(ioCounters.ReadBytes - lastIO.ReadBytes)
-				writeDiff := float64(ioCounters.WriteBytes - lastIO.WriteBytes)
-
-				info.DiskReadRate = (readDiff / 1024) / timeDiff   // KB/s
-				info.DiskWriteRate = (writeDiff / 1024) / timeDiff // KB/s
-
-				// Calculate percentage of system I/O
-				if systemIOTotal > 0 {
-					info.DiskReadPerc = (info.DiskReadRate / systemIOTotal) * 100
-					info.DiskWritePerc = (info.DiskWriteRate / systemIOTotal) * 100
-				} else {
-					// Fallback: use rate relative to a reasonable baseline
-					info.DiskReadPerc = math.Min(info.DiskReadRate/1024, 100)
-					info.DiskWritePerc = math.Min(info.DiskWriteRate/1024, 100)
-				}
-			}
-		}
+// trackedPIDs returns the PIDs of the top processes plus any process with time series tracking
+func (m *Monitor) trackedPIDs(top []ProcessInfo) []int32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-		// Store current I/O counters for next calculation
-		m.mu.Lock()
-		m.lastProcessIO[proc.Pid] = &ProcessIOCounters{
-			ReadBytes:  ioCounters.ReadBytes,
-			WriteBytes: ioCounters.WriteBytes,
-			Timestamp:  now,
+	seen := make(map[int32]bool, len(top)+len(m.processMetrics))
+	pids := make([]int32, 0, len(top)+len(m.processMetrics))
+	for _, proc := range top {
+		seen[proc.PID] = true
+		pids = append(pids, proc.PID)
+	}
+	for key := range m.processMetrics {
+		if !seen[key.PID] {
+			seen[key.PID] = true
+			pids = append(pids, key.PID)
 		}
-		m.mu.Unlock()
 	}
-
-	// Network I/O - simplified implementation (removed expensive connection enumeration)
-	info.NetSentKB = 0
-	info.NetRecvKB = 0
-	info.NetSentRate = 0
-	info.NetRecvRate = 0
-
-	return info, nil
+	return pids
 }
 
 // sortProcessesByResourceUsage sorts processes by CPU and memory usage for prioritization
@@ -385,137 +745,180 @@ func (m *Monitor) sortProcessesByResourceUsage(processes []ProcessInfo) {
 	})
 }
 
-func (m *Monitor) getProcessInfo(proc *process.Process) (ProcessInfo, error) {
-	info := ProcessInfo{PID: proc.Pid}
-	now := time.Now()
+func (m *Monitor) updateProcessTimeSeriesMetrics(processInfo ProcessInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Get process name
-	if name, err := proc.Name(); err == nil {
-		info.Name = name
+	key := processInfo.Key()
+	if _, exists := m.processMetrics[key]; !exists {
+		m.processMetrics[key] = NewProcessMetrics(m.metricTiers)
 	}
 
-	// Get CPU percentage
-	if cpuPercent, err := proc.CPUPercent(); err == nil {
-		info.CPUPercent = cpuPercent
-	}
+	m.processMetrics[key].AddMetric(MetricSample{
+		Timestamp:     m.clock.Now(),
+		CPUPercent:    processInfo.CPUPercent,
+		MemoryMB:      processInfo.MemoryMB,
+		DiskReadRate:  processInfo.DiskReadRate,
+		DiskWriteRate: processInfo.DiskWriteRate,
+		DiskReadPerc:  processInfo.DiskReadPerc,
+		DiskWritePerc: processInfo.DiskWritePerc,
+		NetSentRate:   processInfo.NetSentRate,
+		NetRecvRate:   processInfo.NetRecvRate,
+	})
+}
 
-	// Get memory info
-	if memInfo, err := proc.MemoryInfo(); err == nil {
-		info.MemoryMB = float64(memInfo.RSS) / 1024 / 1024
+func (m *Monitor) sortProcesses() {
+	SortProcesses(m.processes, m.sortBy, m.sortDesc)
+}
+
+// SortProcesses orders processes like the process table
+func SortProcesses(processes []ProcessInfo, sortBy SortBy, desc bool) {
+	sort.Slice(processes, func(i, j int) bool {
+		if desc {
+			return !sortBy.Less(processes[i], processes[j])
+		}
+		return sortBy.Less(processes[i], processes[j])
+	})
+}
+
+// Less reports whether a sorts before b in ascending order
+func (s SortBy) Less(a, b ProcessInfo) bool {
+	switch s {
+	case SortByName:
+		return a.Name < b.Name
+	case SortByCPU:
+		return a.CPUPercent < b.CPUPercent
+	case SortByMemory:
+		return a.MemoryMB < b.MemoryMB
+	default:
+		return a.PID < b.PID
 	}
+}
 
-	if memPercent, err := proc.MemoryPercent(); err == nil {
-		info.MemoryPerc = memPercent
+// FilterProcesses keeps processes whose name or PID contains the
+// case-insensitive query, matching the search of the interactive UI
+func FilterProcesses(processes []ProcessInfo, query string) []ProcessInfo {
+	if query == "" {
+		return processes
 	}
 
-	// Get process creation time
-	if createTime, err := proc.CreateTime(); err == nil {
-		info.CreateTime = time.Unix(createTime/1000, 0)
+	query = strings.ToLower(query)
+	filtered := make([]ProcessInfo, 0, len(processes))
+	for _, proc := range processes {
+		if strings.Contains(strings.ToLower(proc.Name), query) ||
+			strings.Contains(strconv.Itoa(int(proc.PID)), query) {
+			filtered = append(filtered, proc)
+		}
 	}
+	return filtered
+}
 
-	// Get I/O counters and calculate rates
-	if ioCounters, err := proc.IOCounters(); err == nil {
-		info.DiskReadKB = float64(ioCounters.ReadBytes) / 1024
-		info.DiskWriteKB = float64(ioCounters.WriteBytes) / 1024
+// Subscribe registers a function called with every completed process update,
+// right after the time series were extended. It runs on the updating goroutine
+// and must not block.
+func (m *Monitor) Subscribe(fn func(Update)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
 
-		// Calculate rates if we have previous data
-		m.mu.RLock()
-		lastIO, exists := m.lastProcessIO[proc.Pid]
-		m.mu.RUnlock()
-		if exists {
-			timeDiff := now.Sub(lastIO.Timestamp).Seconds()
-			if timeDiff > 0 {
-				readDiff := float64(ioCounters.ReadBytes - lastIO.ReadBytes)
-				writeDiff := float64(ioCounters.WriteBytes - lastIO.WriteBytes)
-
-				info.DiskReadRate = (readDiff / 1024) / timeDiff   // KB/s
-				info.DiskWriteRate = (writeDiff / 1024) / timeDiff // KB/s
-
-				// Calculate percentage of system I/O (simplified)
-				m.mu.RLock()
-				systemIOTotal := m.systemIOTotal
-				m.mu.RUnlock()
-				if systemIOTotal > 0 {
-					info.DiskReadPerc = (info.DiskReadRate / systemIOTotal) * 100
-					info.DiskWritePerc = (info.DiskWriteRate / systemIOTotal) * 100
-				} else {
-					// If no system total, use absolute rate as percentage (cap at 100%)
-					info.DiskReadPerc = math.Min(info.DiskReadRate/1024, 100) // MB/s as rough percentage
-					info.DiskWritePerc = math.Min(info.DiskWriteRate/1024, 100)
-				}
-			}
+// SubscribeEvents registers a function called with every process exit and
+// PID reuse, after the update that noticed it was published. Like Subscribe,
+// it runs on the updating goroutine and must not block.
+func (m *Monitor) SubscribeEvents(fn func(ProcessEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventHandlers = append(m.eventHandlers, fn)
+}
+
+// publish hands a copy of the current state to all subscribers, followed by
+// the process events noticed since the previous publish
+func (m *Monitor) publish() {
+	m.mu.Lock()
+	events := m.events
+	m.events = nil
+	subscribers := m.subscribers
+	eventHandlers := m.eventHandlers
+	var update Update
+	if len(subscribers) > 0 {
+		update = Update{
+			System:    m.systemMetrics,
+			Processes: make([]ProcessInfo, len(m.processes)),
+			All:       m.tree.processes(),
+			CPUMode:   m.cpuMode,
 		}
+		copy(update.Processes, m.processes)
+	}
+	m.mu.Unlock()
 
-		// Store current I/O counters for next calculation
-		m.mu.Lock()
-		m.lastProcessIO[proc.Pid] = &ProcessIOCounters{
-			ReadBytes:  ioCounters.ReadBytes,
-			WriteBytes: ioCounters.WriteBytes,
-			Timestamp:  now,
+	for _, fn := range subscribers {
+		fn(update)
+	}
+	for _, event := range events {
+		for _, fn := range eventHandlers {
+			fn(event)
 		}
-		m.mu.Unlock()
 	}
+}
 
-	// Network I/O - simplified implementation (removed expensive connection enumeration)
-	// Per-process network stats are very difficult to get accurately and efficiently
-	// For now, we'll provide placeholder values to avoid expensive operations
-	info.NetSentKB = 0
-	info.NetRecvKB = 0
-	info.NetSentRate = 0
-	info.NetRecvRate = 0
+// Now returns the current time of the monitor's clock
+func (m *Monitor) Now() time.Time {
+	return m.clock.Now()
+}
 
-	// Note: Real per-process network monitoring would require:
-	// 1. Parsing /proc/net/* files and correlating with process file descriptors
-	// 2. Using eBPF or other kernel-level monitoring
-	// 3. Or using tools like netstat/ss which are also expensive
-	// For this demo, we'll leave network monitoring as a placeholder
+// ApplySnapshot replaces the current state with a previously captured one, as
+// if UpdateMetrics had produced it, and records it in the time series. Others
+// are the running processes outside the top list, which only become part of
+// the tree. Process CPU usage must be per-core. The clock should be set to the
+// snapshot time first.
+func (m *Monitor) ApplySnapshot(system SystemMetrics, processes, others []ProcessInfo) {
+	pids := make([]int32, 0, len(processes)+len(others))
+	for _, procs := range [][]ProcessInfo{processes, others} {
+		for _, proc := range procs {
+			m.observeProcess(proc.Key(), proc.Name)
+			pids = append(pids, proc.PID)
+		}
+	}
 
-	return info, nil
-}
+	// Forget state of processes missing from the snapshot
+	m.pruneExitedProcesses(pids)
 
-func (m *Monitor) updateProcessTimeSeriesMetrics(processInfo ProcessInfo) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	pid := processInfo.PID
-	if _, exists := m.processMetrics[pid]; !exists {
-		m.processMetrics[pid] = NewProcessMetrics(m.metricsCapacity)
+	m.systemMetrics = system
+	m.processes = make([]ProcessInfo, len(processes))
+	copy(m.processes, processes)
+	for i := range m.processes {
+		m.processes[i].CPUPercent = m.scaleCPU(m.processes[i].CPUPercent)
 	}
+	m.sortProcesses()
+	treeProcesses := append(make([]ProcessInfo, 0, len(processes)+len(others)), m.processes...)
+	for _, proc := range others {
+		proc.CPUPercent = m.scaleCPU(proc.CPUPercent)
+		treeProcesses = append(treeProcesses, proc)
+	}
+	m.tree = BuildProcessTree(treeProcesses)
+	current := make([]ProcessInfo, len(m.processes))
+	copy(current, m.processes)
+	m.mu.Unlock()
 
-	m.processMetrics[pid].AddMetric(
-		time.Now(),
-		processInfo.CPUPercent,
-		processInfo.MemoryMB,
-		processInfo.DiskReadRate,
-		processInfo.DiskWriteRate,
-		processInfo.DiskReadPerc,
-		processInfo.DiskWritePerc,
-		processInfo.NetSentRate,
-		processInfo.NetRecvRate,
-	)
+	for _, proc := range current {
+		m.updateProcessTimeSeriesMetrics(proc)
+	}
+	m.publish()
 }
 
-func (m *Monitor) sortProcesses() {
-	sort.Slice(m.processes, func(i, j int) bool {
-		var result bool
-		switch m.sortBy {
-		case SortByPID:
-			result = m.processes[i].PID < m.processes[j].PID
-		case SortByName:
-			result = m.processes[i].Name < m.processes[j].Name
-		case SortByCPU:
-			result = m.processes[i].CPUPercent < m.processes[j].CPUPercent
-		case SortByMemory:
-			result = m.processes[i].MemoryMB < m.processes[j].MemoryMB
-		default:
-			result = m.processes[i].PID < m.processes[j].PID
-		}
+// Reset discards all collected state, keeping the sort order and CPU mode
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		if m.sortDesc {
-			return !result
-		}
-		return result
-	})
+	m.processes = make([]ProcessInfo, 0)
+	m.tree = BuildProcessTree(nil)
+	m.systemMetrics = SystemMetrics{}
+	m.processMetrics = make(map[ProcessKey]*ProcessMetrics)
+	m.pidOwners = make(map[int32]pidOwner)
+	m.events = nil
+	m.rates.Retain(func(ProcessKey) bool { return false })
 }
 
 // CleanupOldMetrics removes metrics for processes that no longer exist
@@ -523,16 +926,16 @@ func (m *Monitor) CleanupOldMetrics() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Get current PIDs
-	currentPIDs := make(map[int32]bool)
+	// Get current processes
+	currentKeys := make(map[ProcessKey]bool)
 	for _, proc := range m.processes {
-		currentPIDs[proc.PID] = true
+		currentKeys[proc.Key()] = true
 	}
 
 	// Remove metrics for non-existent processes
-	for pid := range m.processMetrics {
-		if !currentPIDs[pid] {
-			delete(m.processMetrics, pid)
+	for key := range m.processMetrics {
+		if !currentKeys[key] {
+			delete(m.processMetrics, key)
 		}
 	}
 }
@@ -0,0 +1,282 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+// newTestMonitor returns a monitor on a fake collector and clock with two CPUs
+func newTestMonitor() (*Monitor, *FakeCollector, *FakeClock) {
+	collector := NewFakeCollector()
+	collector.SetSystem(SystemSample{NumCPU: 2, TotalMemoryBytes: 8 << 30})
+	clock := NewFakeClock(testStart)
+	return NewMonitorWithCollector(collector, clock), collector, clock
+}
+
+// tick advances the clock and runs one update
+func tick(t *testing.T, mon *Monitor, clock *FakeClock, d time.Duration) {
+	t.Helper()
+	clock.Advance(d)
+	if err := mon.UpdateMetrics(context.Background()); err != nil {
+		t.Fatalf("UpdateMetrics: %v", err)
+	}
+}
+
+// findProcess returns the process with a PID from the current list
+func findProcess(mon *Monitor, pid int32) (ProcessInfo, bool) {
+	for _, proc := range mon.GetProcesses() {
+		if proc.PID == pid {
+			return proc, true
+		}
+	}
+	return ProcessInfo{}, false
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestRatesBetweenTicks(t *testing.T) {
+	mon, collector, clock := newTestMonitor()
+	sample := ProcessSample{
+		PID:        100,
+		Name:       "worker",
+		CreateTime: testStart.Add(-time.Hour),
+		CPUTime:    10,
+		HasIO:      true,
+		ReadBytes:  1 << 20,
+		WriteBytes: 0,
+	}
+	collector.SetProcess(sample)
+	tick(t, mon, clock, time.Second)
+
+	proc, ok := findProcess(mon, 100)
+	if !ok {
+		t.Fatal("process missing after the first tick")
+	}
+	if proc.CPUPercent != 0 || proc.DiskReadRate != 0 {
+		t.Errorf("first tick: got CPU %v%%, read %v KB/s, want 0 (baseline only)", proc.CPUPercent, proc.DiskReadRate)
+	}
+
+	// 1.5 CPU seconds and 4 MB read over 2 seconds
+	sample.CPUTime += 1.5
+	sample.ReadBytes += 4 << 20
+	sample.WriteBytes += 1 << 20
+	collector.SetProcess(sample)
+	tick(t, mon, clock, 2*time.Second)
+
+	proc, _ = findProcess(mon, 100)
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"CPU per-core %", proc.CPUPercent, 75},
+		{"read KB/s", proc.DiskReadRate, 2048},
+		{"write KB/s", proc.DiskWriteRate, 512},
+	}
+	for _, tt := range tests {
+		if !approx(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	mon.SetCPUMode(CPUModeNormalized)
+	proc, _ = findProcess(mon, 100)
+	if !approx(proc.CPUPercent, 37.5) {
+		t.Errorf("normalized CPU = %v%%, want 37.5%%", proc.CPUPercent)
+	}
+}
+
+func TestExitedProcessIsRemoved(t *testing.T) {
+	mon, collector, clock := newTestMonitor()
+	collector.SetProcess(ProcessSample{PID: 100, Name: "stays", CreateTime: testStart, CPUTime: 1})
+	collector.SetProcess(ProcessSample{PID: 200, Name: "exits", CreateTime: testStart, CPUTime: 1})
+	tick(t, mon, clock, time.Second)
+	tick(t, mon, clock, time.Second)
+
+	exited, ok := findProcess(mon, 200)
+	if !ok {
+		t.Fatal("process 200 missing before it exited")
+	}
+
+	var events []ProcessEvent
+	mon.SubscribeEvents(func(event ProcessEvent) { events = append(events, event) })
+	collector.RemoveProcess(200)
+	tick(t, mon, clock, time.Second)
+
+	if _, ok := findProcess(mon, 200); ok {
+		t.Error("exited process still listed")
+	}
+	if _, ok := findProcess(mon, 100); !ok {
+		t.Error("running process no longer listed")
+	}
+	if mon.GetProcessTree().Node(exited.Key()) != nil {
+		t.Error("exited process still in the tree")
+	}
+	if _, ok := mon.rates.Rate(exited.Key(), counterCPU); ok {
+		t.Error("rate baseline of the exited process was kept")
+	}
+	if len(events) != 1 || events[0].Kind != ProcessExited || events[0].Process != exited.Key() {
+		t.Errorf("events = %+v, want one exit of PID 200", events)
+	}
+
+	// Time series stay for the detail view until cleaned up
+	if len(mon.GetProcessMetrics(exited.Key())) == 0 {
+		t.Error("time series dropped before CleanupOldMetrics")
+	}
+	mon.CleanupOldMetrics()
+	if len(mon.GetProcessMetrics(exited.Key())) != 0 {
+		t.Error("time series kept after CleanupOldMetrics")
+	}
+}
+
+func TestPIDReuseReplacesExitEvent(t *testing.T) {
+	mon, collector, clock := newTestMonitor()
+	collector.SetProcess(ProcessSample{PID: 200, Name: "old", CreateTime: testStart})
+	tick(t, mon, clock, time.Second)
+	old, _ := findProcess(mon, 200)
+
+	var events []ProcessEvent
+	mon.SubscribeEvents(func(event ProcessEvent) { events = append(events, event) })
+	collector.SetProcess(ProcessSample{PID: 200, Name: "new", CreateTime: testStart.Add(time.Minute)})
+	tick(t, mon, clock, time.Second)
+	tick(t, mon, clock, time.Second)
+
+	if len(events) != 1 || events[0].Kind != PIDReused || events[0].Process != old.Key() || events[0].ReplacementName != "new" {
+		t.Errorf("events = %+v, want only the reuse of PID 200", events)
+	}
+}
+
+func TestTopProcessesAreTruncated(t *testing.T) {
+	mon, collector, clock := newTestMonitor()
+	mon.SetSorting(SortByMemory, true)
+	for pid := int32(1); pid <= 200; pid++ {
+		collector.SetProcess(ProcessSample{PID: pid, Name: "proc", CreateTime: testStart, MemoryRSS: uint64(pid) << 20, MemoryPercent: float32(pid) / 10})
+	}
+	tick(t, mon, clock, time.Second)
+
+	processes := mon.GetProcesses()
+	if len(processes) != 150 {
+		t.Fatalf("got %d processes, want the top 150", len(processes))
+	}
+	if _, ok := findProcess(mon, 1); ok {
+		t.Error("least busy process was not dropped")
+	}
+	if processes[0].PID != 200 {
+		t.Errorf("first process is PID %d, want PID 200 with the most memory", processes[0].PID)
+	}
+	if got := mon.GetProcessTree().Len(); got != 200 {
+		t.Errorf("tree has %d processes, want all 200 processes", got)
+	}
+	if got := len(mon.GetAllProcesses()); got != 200 {
+		t.Errorf("GetAllProcesses returned %d processes, want all 200", got)
+	}
+}
+
+func TestProcessFilterLooksBeyondTheTop(t *testing.T) {
+	mon, collector, clock := newTestMonitor()
+	mon.SetProcessFilter("idle-match")
+	collector.SetProcess(ProcessSample{PID: 1, Name: "idle-match", CreateTime: testStart})
+	for pid := int32(2); pid <= 200; pid++ {
+		collector.SetProcess(ProcessSample{PID: pid, Name: "busy", CreateTime: testStart, MemoryPercent: 1})
+	}
+	tick(t, mon, clock, time.Second)
+
+	processes := mon.GetProcesses()
+	if len(processes) != 1 || processes[0].PID != 1 {
+		t.Fatalf("got %d processes, want only the matching PID 1", len(processes))
+	}
+}
+
+func TestCmdlineChecksProcess(t *testing.T) {
+	mon, collector, _ := newTestMonitor()
+	collector.SetProcess(ProcessSample{PID: 100, Name: "sleep", CreateTime: testStart, Cmdline: "sleep 60"})
+
+	key := NewProcessKey(100, testStart)
+	if cmdline, err := mon.Cmdline(context.Background(), key); err != nil || cmdline != "sleep 60" {
+		t.Errorf("Cmdline = %q, %v, want sleep 60", cmdline, err)
+	}
+
+	collector.SetProcess(ProcessSample{PID: 100, Name: "sh", CreateTime: testStart.Add(time.Minute), Cmdline: "sh"})
+	var replaced *ProcessReplacedError
+	if _, err := mon.Cmdline(context.Background(), key); !errors.As(err, &replaced) {
+		t.Errorf("Cmdline of a reused PID returned %v, want a ProcessReplacedError", err)
+	}
+}
+
+// slowDetailsCollector takes a second to read the details, during which the
+// process keeps using CPU
+type slowDetailsCollector struct {
+	*FakeCollector
+	clock *FakeClock
+}
+
+func (c *slowDetailsCollector) ProcessDetails(ctx context.Context, pid int32) (ProcessSample, error) {
+	sample, err := c.FakeCollector.ProcessDetails(ctx, pid)
+	c.clock.Advance(time.Second)
+	sample.CPUTime += 5
+	return sample, err
+}
+
+func TestDetailedPassKeepsBasicRates(t *testing.T) {
+	fake := NewFakeCollector()
+	fake.SetSystem(SystemSample{NumCPU: 2, TotalMemoryBytes: 8 << 30})
+	clock := NewFakeClock(testStart)
+	mon := NewMonitorWithCollector(&slowDetailsCollector{FakeCollector: fake, clock: clock}, clock)
+
+	sample := ProcessSample{PID: 100, Name: "worker", CreateTime: testStart, CPUTime: 10, Cmdline: "worker --fast"}
+	fake.SetProcess(sample)
+	tick(t, mon, clock, time.Second)
+	sample.CPUTime += 1
+	fake.SetProcess(sample)
+	tick(t, mon, clock, time.Second)
+
+	// One CPU second over the two seconds between the basic reads; the
+	// detailed read must not feed the CPU counter a second time
+	proc, _ := findProcess(mon, 100)
+	if !approx(proc.CPUPercent, 50) {
+		t.Errorf("CPU = %v%%, want 50%% from the basic pass only", proc.CPUPercent)
+	}
+	if proc.Cmdline != "worker --fast" {
+		t.Errorf("Cmdline = %q, want the detail fields added", proc.Cmdline)
+	}
+}
+
+func TestSnapshotDropsMissingProcesses(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	mon := NewSnapshotMonitor(clock)
+	top := ProcessInfo{PID: 100, Name: "top", CreateTime: testStart}
+	other := ProcessInfo{PID: 200, Name: "other", CreateTime: testStart}
+	mon.ApplySnapshot(SystemMetrics{}, []ProcessInfo{top}, []ProcessInfo{other})
+	mon.rates.Observe(other.Key(), counterCPU, 1, testStart)
+
+	var events []ProcessEvent
+	mon.SubscribeEvents(func(event ProcessEvent) { events = append(events, event) })
+	clock.Advance(time.Second)
+	mon.ApplySnapshot(SystemMetrics{}, []ProcessInfo{top}, nil)
+
+	if mon.GetProcessTree().Node(other.Key()) != nil {
+		t.Error("missing process still in the tree")
+	}
+	if _, exists := mon.pidOwners[other.PID]; exists {
+		t.Error("owner of the missing process was kept")
+	}
+	if _, exists := mon.pidOwners[top.PID]; !exists {
+		t.Error("owner of the listed process was dropped")
+	}
+	mon.rates.mu.Lock()
+	kept := len(mon.rates.state)
+	mon.rates.mu.Unlock()
+	if kept != 0 {
+		t.Errorf("%d rate baselines kept, want those of the missing process dropped", kept)
+	}
+	if len(events) != 1 || events[0].Kind != ProcessExited || events[0].Process != other.Key() {
+		t.Errorf("events = %+v, want one exit of PID 200", events)
+	}
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+)
+
+// Netlink sock_diag constants (linux/sock_diag.h, linux/inet_diag.h)
+const (
+	netlinkSockDiag   = 4
+	sockDiagByFamily  = 20
+	inetDiagInfo      = 2
+	inetDiagReqV2Len  = 56
+	inetDiagMsgLen    = 72
+	inetDiagInodeOff  = 68
+	tcpInfoBytesAcked = 120
+	tcpInfoBytesRecv  = 128
+)
+
+// tcpSocketBytes queries sock_diag for the byte counters of every TCP socket
+// in the current network namespace, keyed by socket inode
+func tcpSocketBytes() (map[uint64]socketBytes, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkSockDiag)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint64]socketBytes)
+	for seq, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		if err := dumpTCPSockets(fd, uint32(seq+1), family, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// dumpTCPSockets sends one inet_diag dump request and collects the replies
+func dumpTCPSockets(fd int, seq uint32, family uint8, result map[uint64]socketBytes) error {
+	req := make([]byte, syscall.NLMSG_HDRLEN+inetDiagReqV2Len)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], seq)
+
+	body := req[syscall.NLMSG_HDRLEN:]
+	body[0] = family
+	body[1] = syscall.IPPROTO_TCP
+	body[2] = 1 << (inetDiagInfo - 1)
+	binary.NativeEndian.PutUint32(body[4:8], 0xFFFFFFFF) // All states
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if msg.Header.Seq != seq {
+				continue
+			}
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				return fmt.Errorf("sock_diag dump failed")
+			}
+			parseInetDiagMsg(msg.Data, result)
+		}
+	}
+}
+
+// parseInetDiagMsg extracts the inode and tcp_info byte counters from one reply
+func parseInetDiagMsg(data []byte, result map[uint64]socketBytes) {
+	if len(data) < inetDiagMsgLen {
+		return
+	}
+	inode := uint64(binary.NativeEndian.Uint32(data[inetDiagInodeOff : inetDiagInodeOff+4]))
+	if inode == 0 {
+		return
+	}
+
+	attrs := data[inetDiagMsgLen:]
+	for len(attrs) >= syscall.SizeofRtAttr {
+		attrLen := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		attrType := binary.NativeEndian.Uint16(attrs[2:4])
+		if attrLen < syscall.SizeofRtAttr || attrLen > len(attrs) {
+			return
+		}
+
+		// Older kernels send a shorter tcp_info without byte counters
+		payload := attrs[syscall.SizeofRtAttr:attrLen]
+		if attrType == inetDiagInfo && len(payload) >= tcpInfoBytesRecv+8 {
+			result[inode] = socketBytes{
+				Sent: binary.NativeEndian.Uint64(payload[tcpInfoBytesAcked : tcpInfoBytesAcked+8]),
+				Recv: binary.NativeEndian.Uint64(payload[tcpInfoBytesRecv : tcpInfoBytesRecv+8]),
+			}
+		}
+
+		aligned := (attrLen + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if aligned >= len(attrs) {
+			return
+		}
+		attrs = attrs[aligned:]
+	}
+}
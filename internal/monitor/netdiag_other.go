@@ -0,0 +1,11 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// tcpSocketBytes is only implemented on Linux, other platforms fall back to
+// interface counters
+func tcpSocketBytes() (map[uint64]socketBytes, error) {
+	return nil, errors.New("sock_diag is not supported on this platform")
+}
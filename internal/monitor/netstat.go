@@ -0,0 +1,373 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tcpEstablished is the ESTABLISHED state as it appears (hex encoded) in /proc/net/tcp and /proc/net/tcp6
+const tcpEstablished = 0x01
+
+// socketTables lists the /proc/net tables that are matched against socket inodes
+var socketTables = []string{"tcp", "tcp6", "udp", "udp6", "unix"}
+
+// socketEntry describes one socket found in a /proc/net table
+type socketEntry struct {
+	Proto string
+	State uint8
+}
+
+// active reports whether the socket can carry interface traffic
+func (s socketEntry) active() bool {
+	switch s.Proto {
+	case "tcp", "tcp6":
+		return s.State == tcpEstablished
+	case "udp", "udp6":
+		return true
+	}
+	// Unix sockets never leave the host, so they are matched but not weighted
+	return false
+}
+
+// socketBytes holds exact cumulative byte counts for a single socket
+type socketBytes struct {
+	Sent uint64
+	Recv uint64
+}
+
+// ProcessNetUsage holds the network usage attributed to a single process
+type ProcessNetUsage struct {
+	SentBytes uint64    // Cumulative bytes attributed since the process was first seen
+	RecvBytes uint64    // Cumulative bytes attributed since the process was first seen
+	Sockets   int       // Sockets matched in /proc/net tables
+	Exact     bool      // True when the process had sockets and all active ones had exact byte counts
+	Timestamp time.Time // When the counters were last updated
+}
+
+// netNamespace holds per-tick socket tables and interface counters for one network namespace
+type netNamespace struct {
+	sockets   map[uint64]socketEntry
+	devSent   uint64
+	devRecv   uint64
+	deltaSent uint64
+	deltaRecv uint64
+	seen      bool
+}
+
+// processSockets holds the sockets a process owned during the current tick
+type processSockets struct {
+	netns   string
+	inodes  []uint64
+	weight  float64
+	exact   int // Sockets with exact byte counts
+	exactTx uint64
+	exactRx uint64
+}
+
+// NetAccountant attributes network traffic to processes.
+//
+// Sockets are found by matching the inodes behind /proc/<pid>/fd against the
+// /proc/net/{tcp,tcp6,udp,udp6,unix} tables of the process' network namespace.
+// TCP sockets in our own namespace get exact byte counts from sock_diag; the
+// remaining interface traffic of each namespace (from /proc/<pid>/net/dev) is
+// split between processes in proportion to their active socket count. A
+// socket shared by several processes, after a fork or when passed over a unix
+// socket, is split evenly between them.
+type NetAccountant struct {
+	mu          sync.Mutex
+	procRoot    string
+	selfNetns   string
+	namespaces  map[string]*netNamespace
+	socketBytes map[uint64]socketBytes
+	usage       map[int32]*ProcessNetUsage
+}
+
+// NewNetAccountant creates a new network accountant reading from /proc
+func NewNetAccountant() *NetAccountant {
+	na := &NetAccountant{
+		procRoot:    "/proc",
+		namespaces:  make(map[string]*netNamespace),
+		socketBytes: make(map[uint64]socketBytes),
+		usage:       make(map[int32]*ProcessNetUsage),
+	}
+	na.selfNetns, _ = os.Readlink(filepath.Join(na.procRoot, "self", "ns", "net"))
+	return na
+}
+
+// Usage returns the network usage attributed to a process during the last update
+func (na *NetAccountant) Usage(pid int32) (ProcessNetUsage, bool) {
+	na.mu.Lock()
+	defer na.mu.Unlock()
+
+	usage, exists := na.usage[pid]
+	if !exists {
+		return ProcessNetUsage{}, false
+	}
+	return *usage, true
+}
+
+// Update refreshes socket tables and attributes traffic to the given processes
+func (na *NetAccountant) Update(pids []int32, now time.Time) {
+	na.mu.Lock()
+	defer na.mu.Unlock()
+
+	for _, ns := range na.namespaces {
+		ns.seen = false
+	}
+
+	// Collect sockets per process and load each namespace once
+	owned := make(map[int32]*processSockets, len(pids))
+	for _, pid := range pids {
+		netns, err := os.Readlink(filepath.Join(na.procRoot, strconv.Itoa(int(pid)), "ns", "net"))
+		if err != nil {
+			continue // Process gone or not ours to inspect
+		}
+
+		ns := na.namespaces[netns]
+		if ns == nil || !ns.seen {
+			if ns == nil {
+				ns = &netNamespace{}
+				na.namespaces[netns] = ns
+			}
+			if err := na.loadNamespace(ns, pid); err != nil {
+				continue
+			}
+		}
+
+		owned[pid] = &processSockets{
+			netns:  netns,
+			inodes: na.socketInodes(pid),
+		}
+	}
+
+	// Forget namespaces that no longer have a tracked process
+	for netns, ns := range na.namespaces {
+		if !ns.seen {
+			delete(na.namespaces, netns)
+		}
+	}
+
+	// Exact byte counts are only available for TCP sockets in our own namespace
+	exact, err := tcpSocketBytes()
+	if err != nil {
+		exact = nil
+	}
+
+	// Count the owners of each socket so shared sockets are not counted twice
+	owners := make(map[uint64]int)
+	for _, ps := range owned {
+		ns := na.namespaces[ps.netns]
+		for _, inode := range ps.inodes {
+			if _, isSocket := ns.sockets[inode]; isSocket {
+				owners[inode]++
+			}
+		}
+	}
+
+	exactPerNetns := make(map[string]socketBytes)
+	weightPerNetns := make(map[string]float64)
+	newSocketBytes := make(map[uint64]socketBytes, len(exact))
+
+	for pid, ps := range owned {
+		ns := na.namespaces[ps.netns]
+		_, known := na.usage[pid]
+
+		for _, inode := range ps.inodes {
+			entry, isSocket := ns.sockets[inode]
+			if !isSocket {
+				continue
+			}
+			share := uint64(owners[inode])
+
+			if ps.netns == na.selfNetns {
+				if counts, ok := exact[inode]; ok {
+					newSocketBytes[inode] = counts
+					ps.exact++
+					// Sockets first seen with a known process were created since the
+					// last tick, so their whole lifetime counts as new traffic
+					last := na.socketBytes[inode]
+					if known && counts.Sent >= last.Sent && counts.Recv >= last.Recv {
+						ps.exactTx += (counts.Sent - last.Sent) / share
+						ps.exactRx += (counts.Recv - last.Recv) / share
+					}
+					continue
+				}
+			}
+
+			if entry.active() {
+				ps.weight += 1 / float64(share)
+			}
+		}
+
+		total := exactPerNetns[ps.netns]
+		total.Sent += ps.exactTx
+		total.Recv += ps.exactRx
+		exactPerNetns[ps.netns] = total
+		weightPerNetns[ps.netns] += ps.weight
+	}
+	na.socketBytes = newSocketBytes
+
+	newUsage := make(map[int32]*ProcessNetUsage, len(owned))
+	for pid, ps := range owned {
+		ns := na.namespaces[ps.netns]
+		usage := &ProcessNetUsage{Exact: ps.exact > 0 && ps.weight == 0, Timestamp: now}
+		if last, exists := na.usage[pid]; exists {
+			usage.SentBytes = last.SentBytes
+			usage.RecvBytes = last.RecvBytes
+		}
+		for _, inode := range ps.inodes {
+			if _, isSocket := ns.sockets[inode]; isSocket {
+				usage.Sockets++
+			}
+		}
+
+		sent := ps.exactTx
+		recv := ps.exactRx
+
+		// Share the namespace traffic not explained by exact counts
+		if ps.weight > 0 && weightPerNetns[ps.netns] > 0 {
+			share := ps.weight / weightPerNetns[ps.netns]
+			exactTotal := exactPerNetns[ps.netns]
+			if ns.deltaSent > exactTotal.Sent {
+				sent += uint64(float64(ns.deltaSent-exactTotal.Sent) * share)
+			}
+			if ns.deltaRecv > exactTotal.Recv {
+				recv += uint64(float64(ns.deltaRecv-exactTotal.Recv) * share)
+			}
+		}
+
+		usage.SentBytes += sent
+		usage.RecvBytes += recv
+		newUsage[pid] = usage
+	}
+	na.usage = newUsage
+}
+
+// loadNamespace reads the socket tables and interface counters of a namespace
+// through one of its member processes
+func (na *NetAccountant) loadNamespace(ns *netNamespace, pid int32) error {
+	netDir := filepath.Join(na.procRoot, strconv.Itoa(int(pid)), "net")
+
+	sent, recv, err := readNetDev(filepath.Join(netDir, "dev"))
+	if err != nil {
+		return err
+	}
+
+	sockets := make(map[uint64]socketEntry)
+	for _, table := range socketTables {
+		if err := readSocketTable(filepath.Join(netDir, table), table, sockets); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Interface counters can be reset when interfaces come and go
+	if ns.sockets != nil && sent >= ns.devSent && recv >= ns.devRecv {
+		ns.deltaSent = sent - ns.devSent
+		ns.deltaRecv = recv - ns.devRecv
+	} else {
+		ns.deltaSent = 0
+		ns.deltaRecv = 0
+	}
+	ns.devSent = sent
+	ns.devRecv = recv
+	ns.sockets = sockets
+	ns.seen = true
+
+	return nil
+}
+
+// socketInodes lists the socket inodes referenced by a process' file descriptors
+func (na *NetAccountant) socketInodes(pid int32) []uint64 {
+	fdDir := filepath.Join(na.procRoot, strconv.Itoa(int(pid)), "fd")
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+
+	inodes := make([]uint64, 0, 8)
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(target[len("socket:["):], "]"), 10, 64)
+		if err != nil {
+			continue
+		}
+		inodes = append(inodes, inode)
+	}
+	return inodes
+}
+
+// readSocketTable parses a /proc/net socket table into inode -> socket entries
+func readSocketTable(path, proto string, sockets map[uint64]socketEntry) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Column positions differ between the inet tables and the unix table
+	stateCol, inodeCol := 3, 9
+	if proto == "unix" {
+		stateCol, inodeCol = 5, 6
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) <= inodeCol {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[inodeCol], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		state, _ := strconv.ParseUint(fields[stateCol], 16, 8)
+		sockets[inode] = socketEntry{Proto: proto, State: uint8(state)}
+	}
+	return scanner.Err()
+}
+
+// readNetDev sums transmitted and received bytes over all non-loopback interfaces
+func readNetDev(path string) (sent, recv uint64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue // Header lines
+		}
+		// Loopback traffic is both sent and received locally, skip it to avoid double counting
+		if strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			return 0, 0, fmt.Errorf("malformed line in %s: %q", path, scanner.Text())
+		}
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		recv += rx
+		sent += tx
+	}
+	return sent, recv, scanner.Err()
+}
@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// readProcStat parses /proc/<pid>/stat, which is a single read for all the
+// fields that gopsutil would otherwise fetch one file at a time
+func readProcStat(pid int32) (procStat, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(int(pid)) + "/stat")
+	if err != nil {
+		return procStat{}, err
+	}
+
+	// The command name is in parentheses and may itself contain spaces and parentheses
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 18 {
+		return procStat{}, fmt.Errorf("malformed stat of process %d", pid)
+	}
+
+	// Fields are numbered from 3 (state) on in proc(5)
+	field := func(n int) int64 {
+		value, _ := strconv.ParseInt(string(fields[n-3]), 10, 64)
+		return value
+	}
+	return procStat{
+		state:    string(fields[0]),
+		ppid:     int32(field(4)),
+		tty:      ttyName(uint32(field(7))),
+		priority: int32(field(18)),
+		nice:     int32(field(19)),
+		threads:  int32(field(20)),
+	}, nil
+}
+
+// ttyName turns the device number of a controlling terminal into its name
+// below /dev, without scanning /dev like gopsutil does
+func ttyName(dev uint32) string {
+	if dev == 0 {
+		return ""
+	}
+	major := (dev >> 8) & 0xfff
+	minor := (dev & 0xff) | ((dev >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("ttyS%d", minor-64)
+	case major == 5 && minor == 1:
+		return "console"
+	}
+	return fmt.Sprintf("%d:%d", major, minor)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// readProcStat is only implemented on Linux, other platforms fall back to
+// gopsutil
+func readProcStat(pid int32) (procStat, error) {
+	return procStat{}, errors.New("/proc is not supported on this platform")
+}
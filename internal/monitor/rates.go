@@ -0,0 +1,165 @@
+package monitor
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// CounterConfig describes how a cumulative counter is turned into a rate
+type CounterConfig struct {
+	// Scale converts the per-second delta into the reported unit (e.g. 1/1024 for KB/s)
+	Scale float64
+	// MinInterval is the shortest interval a rate is computed over; observations
+	// closer to the previous one keep reporting the previous rate
+	MinInterval time.Duration
+	// Smoothing is the EWMA weight of the newest rate in (0, 1]; 0 disables smoothing
+	Smoothing float64
+	// Width is the counter width in bits for wraparound detection, 0 means 64
+	Width uint
+}
+
+// counterID identifies one counter of one tracked entity
+type counterID[K comparable] struct {
+	key  K
+	name string
+}
+
+// counterState holds the last observation of a counter
+type counterState struct {
+	value uint64
+	at    time.Time
+	rate  float64
+	valid bool // A rate has been computed at least once
+}
+
+// RateTracker turns monotonic cumulative counters into per-second rates.
+//
+// Counters are registered once by name and observed per entity key. Counter
+// resets (value going backwards) re-establish the baseline, wraparound of
+// counters narrower than 64 bits is detected, too-short intervals are
+// ignored and rates can optionally be smoothed with an EWMA.
+type RateTracker[K comparable] struct {
+	mu       sync.Mutex
+	counters map[string]CounterConfig
+	state    map[counterID[K]]*counterState
+}
+
+// NewRateTracker creates an empty rate tracker
+func NewRateTracker[K comparable]() *RateTracker[K] {
+	return &RateTracker[K]{
+		counters: make(map[string]CounterConfig),
+		state:    make(map[counterID[K]]*counterState),
+	}
+}
+
+// Register adds a counter; observing an unregistered counter uses a scale of 1
+func (t *RateTracker[K]) Register(name string, config CounterConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if config.Scale == 0 {
+		config.Scale = 1
+	}
+	t.counters[name] = config
+}
+
+// Observe records the counter value measured at the given time and returns
+// the current rate. The first observation only establishes a baseline and
+// returns 0.
+func (t *RateTracker[K]) Observe(key K, name string, value uint64, at time.Time) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config, registered := t.counters[name]
+	if !registered {
+		config = CounterConfig{Scale: 1}
+	}
+
+	id := counterID[K]{key: key, name: name}
+	state, exists := t.state[id]
+	if !exists {
+		t.state[id] = &counterState{value: value, at: at}
+		return 0
+	}
+
+	elapsed := at.Sub(state.at)
+	if elapsed <= 0 || elapsed < config.MinInterval {
+		return state.rate
+	}
+
+	delta, ok := counterDelta(state.value, value, config.Width)
+	state.value = value
+	state.at = at
+	if !ok {
+		// Counter was reset, e.g. by a restart: the new value is only a baseline
+		// and the old rate no longer applies
+		state.rate = 0
+		state.valid = false
+		return 0
+	}
+
+	rate := float64(delta) / elapsed.Seconds() * config.Scale
+	if config.Smoothing > 0 && config.Smoothing < 1 && state.valid {
+		rate = config.Smoothing*rate + (1-config.Smoothing)*state.rate
+	}
+	state.rate = rate
+	state.valid = true
+
+	return rate
+}
+
+// Rate returns the last computed rate of a counter
+func (t *RateTracker[K]) Rate(key K, name string) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, exists := t.state[counterID[K]{key: key, name: name}]
+	if !exists || !state.valid {
+		return 0, false
+	}
+	return state.rate, true
+}
+
+// Forget drops all counters of an entity
+func (t *RateTracker[K]) Forget(key K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id := range t.state {
+		if id.key == key {
+			delete(t.state, id)
+		}
+	}
+}
+
+// Retain drops all counters of entities for which keep returns false
+func (t *RateTracker[K]) Retain(keep func(K) bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id := range t.state {
+		if !keep(id.key) {
+			delete(t.state, id)
+		}
+	}
+}
+
+// counterDelta returns how far a counter advanced. A counter that went
+// backwards is treated as having wrapped when it was close to its maximum
+// and is now close to zero, otherwise as reset (ok is false).
+func counterDelta(last, current uint64, width uint) (delta uint64, ok bool) {
+	if current >= last {
+		return current - last, true
+	}
+
+	max := uint64(math.MaxUint64)
+	if width > 0 && width < 64 {
+		max = 1<<width - 1
+	}
+	quarter := max / 4
+	if last > max-quarter && current < quarter {
+		return (max - last) + current + 1, true
+	}
+	return 0, false
+}
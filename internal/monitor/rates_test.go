@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateTrackerObserve(t *testing.T) {
+	type observation struct {
+		value uint64
+		after time.Duration // Since the previous observation
+		want  float64
+	}
+	tests := []struct {
+		name   string
+		config CounterConfig
+		obs    []observation
+	}{
+		{
+			name:   "steady",
+			config: CounterConfig{},
+			obs:    []observation{{100, 0, 0}, {300, time.Second, 200}, {400, 2 * time.Second, 50}},
+		},
+		{
+			name:   "reset drops the old rate",
+			config: CounterConfig{},
+			obs:    []observation{{1000, 0, 0}, {2000, time.Second, 1000}, {10, time.Second, 0}, {110, time.Second, 100}},
+		},
+		{
+			name:   "32-bit wraparound",
+			config: CounterConfig{Width: 32},
+			obs:    []observation{{1<<32 - 100, 0, 0}, {50, time.Second, 150}},
+		},
+		{
+			name:   "short interval keeps the previous rate",
+			config: CounterConfig{MinInterval: time.Second},
+			obs:    []observation{{0, 0, 0}, {100, time.Second, 100}, {500, 100 * time.Millisecond, 100}, {700, 900 * time.Millisecond, 600}},
+		},
+		{
+			name:   "smoothing",
+			config: CounterConfig{Smoothing: 0.5},
+			obs:    []observation{{0, 0, 0}, {100, time.Second, 100}, {400, time.Second, 200}, {400, time.Second, 100}},
+		},
+		{
+			name:   "no smoothing across a reset",
+			config: CounterConfig{Smoothing: 0.5},
+			obs:    []observation{{0, 0, 0}, {1000, time.Second, 1000}, {5, time.Second, 0}, {105, time.Second, 100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates := NewRateTracker[string]()
+			rates.Register("c", tt.config)
+			at := testStart
+			for i, o := range tt.obs {
+				at = at.Add(o.after)
+				if got := rates.Observe("key", "c", o.value, at); !approx(got, o.want) {
+					t.Errorf("observation %d (%d): rate %v, want %v", i, o.value, got, o.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IOClass is an I/O scheduling class as used by ioprio_set(2)
+type IOClass int
+
+// I/O scheduling classes. Without a class of its own a process gets a
+// best-effort level derived from its nice value.
+const (
+	IOClassNone IOClass = iota
+	IOClassRealtime
+	IOClassBestEffort
+	IOClassIdle
+)
+
+var ioClassNames = []string{"none", "realtime", "best-effort", "idle"}
+
+// IOClassNames returns the names accepted by ParseIOClass, in class order
+func IOClassNames() []string {
+	return append([]string(nil), ioClassNames...)
+}
+
+func (c IOClass) String() string {
+	if c >= 0 && int(c) < len(ioClassNames) {
+		return ioClassNames[c]
+	}
+	return "class " + strconv.Itoa(int(c))
+}
+
+// ParseIOClass parses an I/O class name (none, realtime, best-effort or idle)
+func ParseIOClass(name string) (IOClass, error) {
+	for i, className := range ioClassNames {
+		if strings.EqualFold(name, className) {
+			return IOClass(i), nil
+		}
+	}
+	return IOClassNone, fmt.Errorf("unknown I/O class %q (available: %s)", name, strings.Join(ioClassNames, ", "))
+}
+
+// Nice and I/O priority level limits
+const (
+	MinNice      = -20
+	MaxNice      = 19
+	MaxIOLevel   = 7    // Levels go from 0, the highest priority, to 7
+	MaxCPU       = 1023 // Highest CPU number of an affinity mask
+	niceIOLevels = 5    // Nice values per derived best-effort level
+)
+
+// IOPriority is the I/O class and priority level of a process
+type IOPriority struct {
+	Class IOClass
+	Level int // 0-7 for realtime and best-effort, unused otherwise
+}
+
+// Validate checks the level against the class
+func (p IOPriority) Validate() error {
+	switch p.Class {
+	case IOClassRealtime, IOClassBestEffort:
+		if p.Level < 0 || p.Level > MaxIOLevel {
+			return fmt.Errorf("I/O priority level must be between 0 and %d", MaxIOLevel)
+		}
+	case IOClassNone, IOClassIdle:
+	default:
+		return fmt.Errorf("unknown I/O class %d", p.Class)
+	}
+	return nil
+}
+
+func (p IOPriority) String() string {
+	if p.Class == IOClassRealtime || p.Class == IOClassBestEffort {
+		return fmt.Sprintf("%s %d", p.Class, p.Level)
+	}
+	return p.Class.String()
+}
+
+// Scheduling is the CPU and I/O scheduling of a process
+type Scheduling struct {
+	Nice       int32
+	IOPriority IOPriority
+	Affinity   []int // CPUs the process may run on, ascending
+}
+
+// EffectiveIOPriority returns the I/O priority the kernel applies: processes
+// without a class of their own get a best-effort level from their nice value
+func (s Scheduling) EffectiveIOPriority() IOPriority {
+	if s.IOPriority.Class != IOClassNone {
+		return s.IOPriority
+	}
+	return IOPriority{Class: IOClassBestEffort, Level: int(s.Nice-MinNice) / niceIOLevels}
+}
+
+// ValidateNice checks that a nice value is within the range of the kernel
+func ValidateNice(nice int) error {
+	if nice < MinNice || nice > MaxNice {
+		return fmt.Errorf("nice value must be between %d and %d", MinNice, MaxNice)
+	}
+	return nil
+}
+
+// FormatCPUList formats CPUs like taskset -c, e.g. "0-3,6"
+func FormatCPUList(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseCPUList parses a CPU list like taskset -c, e.g. "0-3,6", into
+// ascending CPU numbers
+func ParseCPUList(list string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if err != nil || from < 0 || to < from {
+			return nil, fmt.Errorf("invalid CPU list entry %q", part)
+		}
+		if to > MaxCPU {
+			return nil, fmt.Errorf("CPU %d is out of range", to)
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			seen[cpu] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, errors.New("CPU list is empty")
+	}
+
+	cpus := make([]int, 0, len(seen))
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
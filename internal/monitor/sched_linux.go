@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioprio_set(2) encoding: the class above a 13 bit level
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioLevelMask  = 1<<ioprioClassShift - 1
+)
+
+// readScheduling reads the nice value, I/O priority and CPU affinity of the
+// main thread of a process
+func readScheduling(pid int32) (Scheduling, error) {
+	// The raw getpriority system call returns 20 - nice
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, int(pid))
+	if err != nil {
+		return Scheduling{}, err
+	}
+	ioprio, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return Scheduling{}, errno
+	}
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &set); err != nil {
+		return Scheduling{}, err
+	}
+
+	sched := Scheduling{
+		Nice: int32(20 - prio),
+		IOPriority: IOPriority{
+			Class: IOClass(ioprio >> ioprioClassShift),
+			Level: int(ioprio & ioprioLevelMask),
+		},
+	}
+	for cpu := 0; cpu <= MaxCPU; cpu++ {
+		if set.IsSet(cpu) {
+			sched.Affinity = append(sched.Affinity, cpu)
+		}
+	}
+	return sched, nil
+}
+
+// setNice changes the nice value of every thread of a process
+func setNice(pid int32, nice int) error {
+	return eachThread(pid, func(tid int) error {
+		return unix.Setpriority(unix.PRIO_PROCESS, tid, nice)
+	})
+}
+
+// setIOPriority changes the I/O priority of every thread of a process
+func setIOPriority(pid int32, prio IOPriority) error {
+	level := prio.Level
+	if prio.Class != IOClassRealtime && prio.Class != IOClassBestEffort {
+		level = 0
+	}
+	value := uintptr(prio.Class)<<ioprioClassShift | uintptr(level)
+	return eachThread(pid, func(tid int) error {
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), value); errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// setAffinity restricts every thread of a process to the given CPUs
+func setAffinity(pid int32, cpus []int) error {
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	err := eachThread(pid, func(tid int) error {
+		return unix.SchedSetaffinity(tid, &set)
+	})
+	if errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("none of the CPUs %s is online and allowed: %w", FormatCPUList(cpus), err)
+	}
+	return err
+}
+
+// eachThread applies a change to the main thread of a process, then to its
+// other threads. The system calls only act on the thread they are given, and
+// a hog is rarely single-threaded. Threads that exit meanwhile are skipped.
+// When a later thread fails, the error tells how many were changed already.
+func eachThread(pid int32, apply func(tid int) error) error {
+	if err := apply(int(pid)); err != nil {
+		return err
+	}
+	tasks, err := os.ReadDir("/proc/" + strconv.Itoa(int(pid)) + "/task")
+	if err != nil {
+		return nil // Only the main thread could be changed
+	}
+	changed := 1
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil || tid == int(pid) {
+			continue
+		}
+		switch err := apply(tid); {
+		case err == nil:
+			changed++
+		case !errors.Is(err, unix.ESRCH):
+			return fmt.Errorf("changed %d of %d threads, thread %d failed: %w", changed, len(tasks), tid, err)
+		}
+	}
+	return nil
+}
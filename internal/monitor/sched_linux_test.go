@@ -0,0 +1,28 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestEachThreadReportsPartialChanges(t *testing.T) {
+	// The test binary runs several threads; the third one refuses
+	calls := 0
+	err := eachThread(int32(os.Getpid()), func(tid int) error {
+		calls++
+		if calls == 3 {
+			return unix.EPERM
+		}
+		return nil
+	})
+	if calls < 3 {
+		t.Skipf("only %d threads", calls)
+	}
+	if !errors.Is(err, os.ErrPermission) || !strings.Contains(err.Error(), "changed 2 of ") {
+		t.Errorf("eachThread = %v, want a permission error after 2 changed threads", err)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+var errSchedulingUnsupported = errors.New("scheduling controls are only supported on Linux")
+
+// readScheduling is only implemented on Linux
+func readScheduling(pid int32) (Scheduling, error) {
+	return Scheduling{}, errSchedulingUnsupported
+}
+
+// setNice is only implemented on Linux
+func setNice(pid int32, nice int) error {
+	return errSchedulingUnsupported
+}
+
+// setIOPriority is only implemented on Linux
+func setIOPriority(pid int32, prio IOPriority) error {
+	return errSchedulingUnsupported
+}
+
+// setAffinity is only implemented on Linux
+func setAffinity(pid int32, cpus []int) error {
+	return errSchedulingUnsupported
+}
@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// SignalInfo describes a signal that can be sent to processes
+type SignalInfo struct {
+	Signal      syscall.Signal
+	Name        string // e.g. "SIGTERM"
+	Description string
+}
+
+// SignalName returns the name of a signal, e.g. "SIGTERM". Signal numbers
+// differ between platforms, so names are what agents and viewers exchange.
+func SignalName(sig syscall.Signal) string {
+	for _, info := range Signals {
+		if info.Signal == sig {
+			return info.Name
+		}
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
+
+// ParseSignal looks up one of the Signals by name, with or without the SIG prefix
+func ParseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for _, info := range Signals {
+		if info.Name == name {
+			return info.Signal, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported signal %q", name)
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package monitor
+
+import "syscall"
+
+// Signals lists the signals offered for process actions, most common first
+var Signals = []SignalInfo{
+	{syscall.SIGTERM, "SIGTERM", "Terminate gracefully"},
+	{syscall.SIGKILL, "SIGKILL", "Kill immediately"},
+	{syscall.SIGHUP, "SIGHUP", "Hang up, often reloads configuration"},
+	{syscall.SIGINT, "SIGINT", "Interrupt, like Ctrl+C"},
+	{syscall.SIGSTOP, "SIGSTOP", "Pause"},
+	{syscall.SIGCONT, "SIGCONT", "Resume after SIGSTOP"},
+	{syscall.SIGUSR1, "SIGUSR1", "User-defined signal 1"},
+	{syscall.SIGUSR2, "SIGUSR2", "User-defined signal 2"},
+}
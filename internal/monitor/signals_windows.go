@@ -0,0 +1,12 @@
+package monitor
+
+import "syscall"
+
+// Signals lists the signals offered for process actions. Windows has no
+// job control or user-defined signals.
+var Signals = []SignalInfo{
+	{syscall.SIGTERM, "SIGTERM", "Terminate gracefully"},
+	{syscall.SIGKILL, "SIGKILL", "Kill immediately"},
+	{syscall.SIGHUP, "SIGHUP", "Hang up, often reloads configuration"},
+	{syscall.SIGINT, "SIGINT", "Interrupt, like Ctrl+C"},
+}
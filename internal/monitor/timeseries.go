@@ -0,0 +1,176 @@
+package monitor
+
+import "time"
+
+// Ring is a fixed-capacity circular buffer. Storage grows on demand up to the
+// capacity, after which appends overwrite the oldest element without allocating.
+type Ring[T any] struct {
+	buf      []T
+	start    int
+	capacity int
+}
+
+// NewRing creates a ring buffer holding at most capacity elements
+func NewRing[T any](capacity int) *Ring[T] {
+	return &Ring[T]{capacity: capacity}
+}
+
+// Push appends a value, dropping the oldest one when the ring is full
+func (r *Ring[T]) Push(value T) {
+	if len(r.buf) < r.capacity {
+		r.buf = append(r.buf, value)
+		return
+	}
+	r.buf[r.start] = value
+	r.start = (r.start + 1) % r.capacity
+}
+
+// Len returns the number of stored elements
+func (r *Ring[T]) Len() int {
+	return len(r.buf)
+}
+
+// Snapshot appends the elements from oldest to newest to dst and returns it
+func (r *Ring[T]) Snapshot(dst []T) []T {
+	dst = append(dst, r.buf[r.start:]...)
+	return append(dst, r.buf[:r.start]...)
+}
+
+// Each calls fn with a pointer to every stored element, oldest first
+func (r *Ring[T]) Each(fn func(*T)) {
+	for i := range r.buf {
+		fn(&r.buf[(r.start+i)%len(r.buf)])
+	}
+}
+
+// MetricSample is one data point of a process time series
+type MetricSample struct {
+	Timestamp     time.Time
+	CPUPercent    float64
+	MemoryMB      float64
+	DiskReadRate  float64 // KB/s
+	DiskWriteRate float64 // KB/s
+	DiskReadPerc  float64 // Percentage
+	DiskWritePerc float64 // Percentage
+	NetSentRate   float64 // KB/s
+	NetRecvRate   float64 // KB/s
+}
+
+// add accumulates another sample's values, used to build rollup averages
+func (s *MetricSample) add(other MetricSample) {
+	s.CPUPercent += other.CPUPercent
+	s.MemoryMB += other.MemoryMB
+	s.DiskReadRate += other.DiskReadRate
+	s.DiskWriteRate += other.DiskWriteRate
+	s.DiskReadPerc += other.DiskReadPerc
+	s.DiskWritePerc += other.DiskWritePerc
+	s.NetSentRate += other.NetSentRate
+	s.NetRecvRate += other.NetRecvRate
+}
+
+// scale multiplies all values by factor
+func (s *MetricSample) scale(factor float64) {
+	s.CPUPercent *= factor
+	s.MemoryMB *= factor
+	s.DiskReadRate *= factor
+	s.DiskWriteRate *= factor
+	s.DiskReadPerc *= factor
+	s.DiskWritePerc *= factor
+	s.NetSentRate *= factor
+	s.NetRecvRate *= factor
+}
+
+// TierConfig describes one resolution of a process time series
+type TierConfig struct {
+	Resolution time.Duration // Samples within the same interval are averaged
+	Capacity   int           // Number of intervals kept
+}
+
+// Span returns how much time a full tier covers
+func (t TierConfig) Span() time.Duration {
+	return t.Resolution * time.Duration(t.Capacity)
+}
+
+// DefaultMetricTiers keeps a minute at 1s, an hour at 10s and a day at 1m resolution
+var DefaultMetricTiers = []TierConfig{
+	{Resolution: time.Second, Capacity: 60},
+	{Resolution: 10 * time.Second, Capacity: 360},
+	{Resolution: time.Minute, Capacity: 1440},
+}
+
+// metricTier is one downsampled ring of a process time series
+type metricTier struct {
+	config       TierConfig
+	ring         *Ring[MetricSample]
+	bucket       time.Time    // Start of the interval being accumulated
+	pending      MetricSample // Sum of the samples in the current interval
+	pendingCount int
+}
+
+// average returns the mean of the samples accumulated for the current interval
+func (t *metricTier) average() MetricSample {
+	avg := t.pending
+	avg.scale(1 / float64(t.pendingCount))
+	avg.Timestamp = t.bucket
+	return avg
+}
+
+// ProcessMetrics represents time-series metrics for a single process at
+// several resolutions
+type ProcessMetrics struct {
+	tiers []*metricTier
+}
+
+// NewProcessMetrics creates time series with the given resolution tiers
+func NewProcessMetrics(tiers []TierConfig) *ProcessMetrics {
+	pm := &ProcessMetrics{tiers: make([]*metricTier, len(tiers))}
+	for i, config := range tiers {
+		pm.tiers[i] = &metricTier{
+			config: config,
+			ring:   NewRing[MetricSample](config.Capacity),
+		}
+	}
+	return pm
+}
+
+// AddMetric adds a new data point to every tier
+func (pm *ProcessMetrics) AddMetric(sample MetricSample) {
+	for _, tier := range pm.tiers {
+		bucket := sample.Timestamp.Truncate(tier.config.Resolution)
+		if tier.pendingCount > 0 && !bucket.Equal(tier.bucket) {
+			// Interval complete: store its average and start a new one
+			tier.ring.Push(tier.average())
+			tier.pending = MetricSample{}
+			tier.pendingCount = 0
+		}
+		tier.bucket = bucket
+		tier.pending.add(sample)
+		tier.pendingCount++
+	}
+}
+
+// Samples returns the samples of a tier from oldest to newest, including the
+// interval that is still being accumulated
+func (pm *ProcessMetrics) Samples(tier int) []MetricSample {
+	if tier < 0 || tier >= len(pm.tiers) {
+		return nil
+	}
+	t := pm.tiers[tier]
+
+	samples := make([]MetricSample, 0, t.ring.Len()+1)
+	samples = t.ring.Snapshot(samples)
+	if t.pendingCount > 0 {
+		samples = append(samples, t.average())
+	}
+	return samples
+}
+
+// scaleCPU rescales the CPU usage of all recorded samples
+func (pm *ProcessMetrics) scaleCPU(factor float64) {
+	for _, tier := range pm.tiers {
+		tier.ring.Each(func(sample *MetricSample) {
+			sample.CPUPercent *= factor
+		})
+		tier.pending.CPUPercent *= factor
+	}
+}
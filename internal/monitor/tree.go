@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProcessNode is a process of a ProcessTree with its children
+type ProcessNode struct {
+	Process  ProcessInfo
+	Children []*ProcessNode
+
+	// Sums over the process and all its descendants
+	TotalCPU        float64
+	TotalMemoryMB   float64
+	TotalMemoryPerc float32
+	Descendants     int
+}
+
+// ProcessTree is the parent/child hierarchy of the processes of one update.
+// It is not modified after it was built, so it can be shared.
+type ProcessTree struct {
+	Roots []*ProcessNode // Processes whose parent is not part of the tree
+	nodes map[ProcessKey]*ProcessNode
+}
+
+// TreeRow is a process as listed in tree order
+type TreeRow struct {
+	Node      *ProcessNode
+	Depth     int  // 0 for roots
+	Last      bool // Last of the listed children of its parent
+	Collapsed bool // Has children that are not listed
+}
+
+// BuildProcessTree links processes to their parents. A process whose parent
+// is missing, or whose PPID belongs to a process started after it (the parent
+// exited and its PID was reused), becomes a root.
+func BuildProcessTree(processes []ProcessInfo) *ProcessTree {
+	tree := &ProcessTree{nodes: make(map[ProcessKey]*ProcessNode, len(processes))}
+	byPID := make(map[int32]*ProcessNode, len(processes))
+	for _, proc := range processes {
+		node := &ProcessNode{Process: proc}
+		tree.nodes[proc.Key()] = node
+		byPID[proc.PID] = node
+	}
+
+	for _, proc := range processes {
+		node := byPID[proc.PID]
+		parent, exists := byPID[proc.PPID]
+		if !exists || proc.PPID == proc.PID || parent.Process.CreateTime.After(proc.CreateTime) {
+			tree.Roots = append(tree.Roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	// Parent links can form a cycle when create times are equal or unknown.
+	// Such processes would hang off each other without a root, so each
+	// cycle is cut above the first of its processes.
+	reached := make(map[*ProcessNode]bool, len(processes))
+	var reach func(node *ProcessNode)
+	reach = func(node *ProcessNode) {
+		reached[node] = true
+		for _, child := range node.Children {
+			if !reached[child] {
+				reach(child)
+			}
+		}
+	}
+	for _, root := range tree.Roots {
+		reach(root)
+	}
+	for _, proc := range processes {
+		node := byPID[proc.PID]
+		if reached[node] {
+			continue
+		}
+		parent := byPID[proc.PPID]
+		parent.Children = slices.DeleteFunc(parent.Children, func(child *ProcessNode) bool { return child == node })
+		tree.Roots = append(tree.Roots, node)
+		reach(node)
+	}
+
+	for _, root := range tree.Roots {
+		root.sum()
+	}
+	return tree
+}
+
+// sum computes the subtree totals of a node and its descendants
+func (n *ProcessNode) sum() {
+	n.TotalCPU = n.Process.CPUPercent
+	n.TotalMemoryMB = n.Process.MemoryMB
+	n.TotalMemoryPerc = n.Process.MemoryPerc
+	n.Descendants = len(n.Children)
+	for _, child := range n.Children {
+		child.sum()
+		n.TotalCPU += child.TotalCPU
+		n.TotalMemoryMB += child.TotalMemoryMB
+		n.TotalMemoryPerc += child.TotalMemoryPerc
+		n.Descendants += child.Descendants
+	}
+}
+
+// Node returns the node of a process, or nil if it is not part of the tree
+func (t *ProcessTree) Node(key ProcessKey) *ProcessNode {
+	return t.nodes[key]
+}
+
+// processes lists the processes of the tree ordered by PID
+func (t *ProcessTree) processes() []ProcessInfo {
+	processes := make([]ProcessInfo, 0, len(t.nodes))
+	for _, node := range t.nodes {
+		processes = append(processes, node.Process)
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].PID < processes[j].PID
+	})
+	return processes
+}
+
+// Len returns the number of processes in the tree
+func (t *ProcessTree) Len() int {
+	return len(t.nodes)
+}
+
+// Rows lists the tree depth-first with siblings sorted like the process
+// table, by their subtree totals for CPU and memory so that busy subtrees
+// come first. Children of collapsed processes are left out. With a query, only
+// processes matching it like FilterProcesses and their ancestors are listed.
+func (t *ProcessTree) Rows(sortBy SortBy, desc bool, query string, collapsed map[ProcessKey]bool) []TreeRow {
+	query = strings.ToLower(query)
+	var rows []TreeRow
+	var walk func(nodes []*ProcessNode, depth int)
+	walk = func(nodes []*ProcessNode, depth int) {
+		shown := make([]*ProcessNode, 0, len(nodes))
+		for _, node := range nodes {
+			if query == "" || node.matches(query) {
+				shown = append(shown, node)
+			}
+		}
+		sort.Slice(shown, func(i, j int) bool {
+			a, b := shown[i].totals(), shown[j].totals()
+			if desc {
+				a, b = b, a
+			}
+			if sortBy.Less(a, b) {
+				return true
+			}
+			if sortBy.Less(b, a) {
+				return false
+			}
+			return shown[i].Process.PID < shown[j].Process.PID // Keep ties in place between updates
+		})
+
+		for i, node := range shown {
+			row := TreeRow{Node: node, Depth: depth, Last: i == len(shown)-1}
+			if collapsed[node.Process.Key()] && len(node.Children) > 0 {
+				row.Collapsed = true
+				rows = append(rows, row)
+				continue
+			}
+			rows = append(rows, row)
+			walk(node.Children, depth+1)
+		}
+	}
+	walk(t.Roots, 0)
+	return rows
+}
+
+// totals returns the process with its usage replaced by the subtree totals
+func (n *ProcessNode) totals() ProcessInfo {
+	proc := n.Process
+	proc.CPUPercent = n.TotalCPU
+	proc.MemoryMB = n.TotalMemoryMB
+	proc.MemoryPerc = n.TotalMemoryPerc
+	return proc
+}
+
+// matches reports whether the process or one of its descendants matches a
+// lower-case query
+func (n *ProcessNode) matches(query string) bool {
+	if strings.Contains(strings.ToLower(n.Process.Name), query) ||
+		strings.Contains(strconv.Itoa(int(n.Process.PID)), query) {
+		return true
+	}
+	for _, child := range n.Children {
+		if child.matches(query) {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleCPU returns a copy of the tree with CPU usage multiplied by a factor
+func (t *ProcessTree) scaleCPU(factor float64) *ProcessTree {
+	processes := make([]ProcessInfo, 0, len(t.nodes))
+	for _, node := range t.nodes {
+		proc := node.Process
+		proc.CPUPercent *= factor
+		processes = append(processes, proc)
+	}
+	return BuildProcessTree(processes)
+}
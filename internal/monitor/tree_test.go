@@ -0,0 +1,36 @@
+package monitor
+
+import "testing"
+
+func TestProcessTreeBreaksParentCycles(t *testing.T) {
+	// 20 and 30 name each other as parent and started at the same time
+	tree := BuildProcessTree([]ProcessInfo{
+		{PID: 1, Name: "init", CreateTime: testStart, CPUPercent: 1},
+		{PID: 10, PPID: 1, Name: "child", CreateTime: testStart, CPUPercent: 2},
+		{PID: 20, PPID: 30, Name: "a", CreateTime: testStart, CPUPercent: 4},
+		{PID: 30, PPID: 20, Name: "b", CreateTime: testStart, CPUPercent: 8},
+		{PID: 40, PPID: 30, Name: "c", CreateTime: testStart, CPUPercent: 16},
+	})
+
+	if len(tree.Roots) != 2 || tree.Roots[0].Process.PID != 1 || tree.Roots[1].Process.PID != 20 {
+		t.Fatalf("roots = %v, want PIDs 1 and 20", rootPIDs(tree))
+	}
+	if got := len(tree.Rows(SortByPID, false, "", nil)); got != 5 {
+		t.Errorf("tree lists %d rows, want all 5 processes", got)
+	}
+	cycle := tree.Roots[1]
+	if cycle.Descendants != 2 || cycle.TotalCPU != 28 {
+		t.Errorf("PID 20 has %d descendants and %v%% CPU, want 2 and 28%%", cycle.Descendants, cycle.TotalCPU)
+	}
+	if node := tree.Node(NewProcessKey(30, testStart)); len(node.Children) != 1 || node.Children[0].Process.PID != 40 {
+		t.Errorf("PID 30 lost its child 40 or kept its parent 20 as a child")
+	}
+}
+
+func rootPIDs(tree *ProcessTree) []int32 {
+	var pids []int32
+	for _, root := range tree.Roots {
+		pids = append(pids, root.Process.PID)
+	}
+	return pids
+}
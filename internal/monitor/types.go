@@ -1,15 +1,45 @@
 package monitor
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// ProcessKey identifies a single process instance. PIDs are recycled by the
+// kernel, so the start time is needed to tell two processes with the same PID apart.
+type ProcessKey struct {
+	PID       int32
+	StartTime int64 // Milliseconds since the epoch
+}
+
+// NewProcessKey builds the key of a process from its PID and creation time
+func NewProcessKey(pid int32, createTime time.Time) ProcessKey {
+	return ProcessKey{PID: pid, StartTime: createTime.UnixMilli()}
+}
+
+// String formats the key for display and logging
+func (k ProcessKey) String() string {
+	return fmt.Sprintf("%d@%d", k.PID, k.StartTime)
+}
 
 // ProcessInfo represents information about a single process
 type ProcessInfo struct {
 	PID           int32
+	PPID          int32 // Parent process, 0 when unknown
 	Name          string
+	Username      string // Effective user
 	CPUPercent    float64
 	MemoryMB      float64
 	MemoryPerc    float32
 	CreateTime    time.Time
+	State         string // Run state letter: R, S, D, Z, T, ...
+	Threads       int32
+	Nice          int32
+	Priority      int32
+	TTY           string // Controlling terminal, empty for none
+	Cmdline       string // Arguments joined by spaces, only for detailed processes
+	Cwd           string // Only for detailed processes
+	Exe           string // Only for detailed processes
 	DiskReadKB    float64
 	DiskWriteKB   float64
 	DiskReadRate  float64 // KB/s
@@ -20,68 +50,72 @@ type ProcessInfo struct {
 	NetRecvKB     float64
 	NetSentRate   float64 // KB/s
 	NetRecvRate   float64 // KB/s
+	CtxSwitchRate float64 // Context switches per second
+	MinorFaultPS  float64 // Minor page faults per second
+	MajorFaultPS  float64 // Major page faults per second
+}
+
+// Key returns the identity of the process
+func (p ProcessInfo) Key() ProcessKey {
+	return NewProcessKey(p.PID, p.CreateTime)
 }
 
 // SystemMetrics represents overall system metrics
 type SystemMetrics struct {
 	CPUPercent    float64
+	NumCPU        int
 	MemoryPercent float64
 	TotalMemoryMB float64
 	UsedMemoryMB  float64
+	DiskReadRate  float64 // KB/s, summed over all disks
+	DiskWriteRate float64 // KB/s, summed over all disks
+	Disks         []DiskDeviceMetrics
+	Load          LoadAverage
 	Timestamp     time.Time
 }
 
-// ProcessMetrics represents time-series metrics for a single process
-type ProcessMetrics struct {
-	Timestamps    []time.Time
-	CPUPercent    []float64
-	MemoryMB      []float64
-	DiskReadRate  []float64 // KB/s
-	DiskWriteRate []float64 // KB/s
-	DiskReadPerc  []float64 // Percentage
-	DiskWritePerc []float64 // Percentage
-	NetSentRate   []float64 // KB/s
-	NetRecvRate   []float64 // KB/s
+// LoadAverage is the run queue length averaged over 1, 5 and 15 minutes
+type LoadAverage struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
 }
 
-// NewProcessMetrics creates a new ProcessMetrics with specified capacity
-func NewProcessMetrics(capacity int) *ProcessMetrics {
-	return &ProcessMetrics{
-		Timestamps:    make([]time.Time, 0, capacity),
-		CPUPercent:    make([]float64, 0, capacity),
-		MemoryMB:      make([]float64, 0, capacity),
-		DiskReadRate:  make([]float64, 0, capacity),
-		DiskWriteRate: make([]float64, 0, capacity),
-		DiskReadPerc:  make([]float64, 0, capacity),
-		DiskWritePerc: make([]float64, 0, capacity),
-		NetSentRate:   make([]float64, 0, capacity),
-		NetRecvRate:   make([]float64, 0, capacity),
+// ProcessEventKind tells what happened to a process
+type ProcessEventKind int
+
+const (
+	// ProcessExited means the process is gone
+	ProcessExited ProcessEventKind = iota
+	// PIDReused means another process took over the PID of an exited one.
+	// It replaces the ProcessExited event of the previous holder.
+	PIDReused
+)
+
+// String returns the name used in notifications
+func (k ProcessEventKind) String() string {
+	if k == PIDReused {
+		return "pid_reuse"
 	}
+	return "process_exit"
 }
 
-// AddMetric adds a new data point to the metrics
-func (pm *ProcessMetrics) AddMetric(timestamp time.Time, cpu, memory, diskReadRate, diskWriteRate, diskReadPerc, diskWritePerc, netSentRate, netRecvRate float64) {
-	pm.Timestamps = append(pm.Timestamps, timestamp)
-	pm.CPUPercent = append(pm.CPUPercent, cpu)
-	pm.MemoryMB = append(pm.MemoryMB, memory)
-	pm.DiskReadRate = append(pm.DiskReadRate, diskReadRate)
-	pm.DiskWriteRate = append(pm.DiskWriteRate, diskWriteRate)
-	pm.DiskReadPerc = append(pm.DiskReadPerc, diskReadPerc)
-	pm.DiskWritePerc = append(pm.DiskWritePerc, diskWritePerc)
-	pm.NetSentRate = append(pm.NetSentRate, netSentRate)
-	pm.NetRecvRate = append(pm.NetRecvRate, netRecvRate)
+// ProcessEvent is a change of the process table noticed during an update
+type ProcessEvent struct {
+	Kind    ProcessEventKind
+	Time    time.Time
+	Process ProcessKey
+	Name    string
 
-	// Keep only the last N metrics (rolling window)
-	capacity := cap(pm.Timestamps)
-	if len(pm.Timestamps) > capacity {
-		pm.Timestamps = pm.Timestamps[1:]
-		pm.CPUPercent = pm.CPUPercent[1:]
-		pm.MemoryMB = pm.MemoryMB[1:]
-		pm.DiskReadRate = pm.DiskReadRate[1:]
-		pm.DiskWriteRate = pm.DiskWriteRate[1:]
-		pm.DiskReadPerc = pm.DiskReadPerc[1:]
-		pm.DiskWritePerc = pm.DiskWritePerc[1:]
-		pm.NetSentRate = pm.NetSentRate[1:]
-		pm.NetRecvRate = pm.NetRecvRate[1:]
-	}
+	// The process now holding the PID, only for PIDReused
+	Replacement     ProcessKey
+	ReplacementName string
+}
+
+// Update is the state after one monitor update, as passed to subscribers
+type Update struct {
+	System    SystemMetrics
+	Processes []ProcessInfo // Sorted, CPU usage in CPUMode
+	All       []ProcessInfo // Every running process ordered by PID, see Monitor.GetAllProcesses
+	CPUMode   CPUMode
 }
@@ -0,0 +1,296 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Channel defaults
+const (
+	DefaultRetries = 3
+	DefaultTimeout = 10 * time.Second
+	queueSize      = 100 // Notifications waiting for delivery per channel
+)
+
+// minRetryDelay is the first delay before a retry; a variable so tests can shorten it
+var minRetryDelay = time.Second
+
+// ChannelConfig describes one channel in a JSON channel file. Which fields
+// apply depends on the type.
+type ChannelConfig struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`       // exec, webhook, smtp or syslog
+	Events    []string `json:"events"`     // Notification kinds to deliver, default alert_firing and alert_resolved
+	Process   string   `json:"process"`    // Regular expression matching the whole process name, for process alerts and events
+	RateLimit string   `json:"rate_limit"` // At most this many notifications per period, e.g. "10/1m"
+	Retries   *int     `json:"retries"`    // Further attempts after a failed delivery, default 3
+	Timeout   string   `json:"timeout"`    // Per attempt, default 10s
+	Template  string   `json:"template"`   // Message body, a Go text/template over the Notification
+
+	Command []string `json:"command"` // exec: program and arguments
+
+	URL     string            `json:"url"`    // webhook
+	Method  string            `json:"method"` // webhook, default POST
+	Headers map[string]string `json:"headers"`
+
+	Address  string   `json:"address"` // smtp: host:port; syslog: socket path or host:port
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"` // smtp: template, default "[pulse] {{.Summary}}"
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+
+	Network  string `json:"network"`  // syslog: unixgram (default), unix, udp or tcp
+	Facility string `json:"facility"` // syslog: default daemon
+	Tag      string `json:"tag"`      // syslog: default pulse
+}
+
+// LoadChannels reads a JSON array of channel configurations from a file
+func LoadChannels(path string) ([]ChannelConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []ChannelConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return configs, nil
+}
+
+// sender delivers one notification over a channel's transport
+type sender interface {
+	send(ctx context.Context, n Notification) error
+}
+
+// permanentError marks failures that will happen again when retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Channel filters, rate limits and delivers notifications on its own
+// goroutine, retrying failed deliveries, so a slow endpoint never holds up
+// the monitor
+type Channel struct {
+	name    string
+	kinds   map[string]bool
+	process *regexp.Regexp // Nil for any process
+	limiter *rateLimiter   // Nil without a rate limit
+	retries int
+	timeout time.Duration
+	sender  sender
+	queue   chan Notification
+	logf    func(format string, args ...any)
+
+	mu         sync.Mutex
+	suppressed int // Rate limited since the last delivery
+	dropped    int // Discarded because the queue was full
+}
+
+// newChannel validates a configuration and creates its sender
+func newChannel(config ChannelConfig) (*Channel, error) {
+	c := &Channel{
+		name:    config.Name,
+		kinds:   make(map[string]bool),
+		retries: DefaultRetries,
+		timeout: DefaultTimeout,
+		queue:   make(chan Notification, queueSize),
+		logf:    logToStderr,
+	}
+	if c.name == "" {
+		c.name = config.Type
+	}
+
+	events := config.Events
+	if len(events) == 0 {
+		events = []string{KindAlertFiring, KindAlertResolved}
+	}
+	for _, kind := range events {
+		if !slices.Contains(kinds, kind) {
+			return nil, fmt.Errorf("unknown event %q (available: %s)", kind, strings.Join(kinds, ", "))
+		}
+		c.kinds[kind] = true
+	}
+	if config.Process != "" {
+		process, err := regexp.Compile("^(?:" + config.Process + ")$")
+		if err != nil {
+			return nil, err
+		}
+		c.process = process
+	}
+	if config.RateLimit != "" {
+		limiter, err := parseRateLimit(config.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		c.limiter = limiter
+	}
+	if config.Retries != nil {
+		if *config.Retries < 0 {
+			return nil, errors.New("retries must not be negative")
+		}
+		c.retries = *config.Retries
+	}
+	if config.Timeout != "" {
+		timeout, err := time.ParseDuration(config.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", config.Timeout)
+		}
+		c.timeout = timeout
+	}
+
+	var err error
+	switch config.Type {
+	case "exec":
+		c.sender, err = newExecSender(config)
+	case "webhook":
+		c.sender, err = newWebhookSender(config)
+	case "smtp":
+		c.sender, err = newSMTPSender(config)
+	case "syslog":
+		c.sender, err = newSyslogSender(config)
+	default:
+		return nil, fmt.Errorf("unknown type %q (available: exec, webhook, smtp, syslog)", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// logToStderr is the default log function of the channels
+func logToStderr(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// Offer queues a notification if the channel accepts it. It never blocks.
+func (c *Channel) Offer(n Notification) {
+	if !c.kinds[n.Kind] {
+		return
+	}
+	if c.process != nil && (n.Process == nil || !c.process.MatchString(n.Process.Name)) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limiter != nil && !c.limiter.allow(time.Now()) {
+		if c.suppressed == 0 {
+			c.logf("Notify %s: rate limit reached, dropping notifications", c.name)
+		}
+		c.suppressed++
+		return
+	}
+	if c.suppressed > 0 {
+		c.logf("Notify %s: %d notifications were rate limited", c.name, c.suppressed)
+		c.suppressed = 0
+	}
+
+	select {
+	case c.queue <- n:
+	default:
+		if c.dropped == 0 {
+			c.logf("Notify %s: queue full, dropping notifications", c.name)
+		}
+		c.dropped++
+	}
+}
+
+// Run delivers queued notifications until the context is cancelled
+func (c *Channel) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-c.queue:
+			c.deliver(ctx, n)
+		}
+	}
+}
+
+// deliver sends a notification, retrying temporary failures with backoff
+func (c *Channel) deliver(ctx context.Context, n Notification) {
+	delay := minRetryDelay
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := c.sender.send(attemptCtx, n)
+		cancel()
+		if err == nil {
+			c.mu.Lock()
+			if c.dropped > 0 {
+				c.logf("Notify %s: %d notifications were dropped while the queue was full", c.name, c.dropped)
+				c.dropped = 0
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		if ctx.Err() != nil {
+			return // Shutting down
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= c.retries {
+			c.logf("Notify %s: giving up on %s after %d attempts: %v", c.name, n.Kind, attempt+1, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// rateLimiter is a token bucket allowing bursts of up to its capacity
+type rateLimiter struct {
+	capacity float64
+	perSec   float64 // Tokens added per second
+	tokens   float64
+	last     time.Time
+}
+
+// parseRateLimit parses "count/period", e.g. "10/1m"
+func parseRateLimit(value string) (*rateLimiter, error) {
+	countText, periodText, ok := strings.Cut(value, "/")
+	count, err := strconv.Atoi(strings.TrimSpace(countText))
+	if !ok || err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid rate limit %q, expected count/period like 10/1m", value)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodText))
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %q, expected count/period like 10/1m", value)
+	}
+	return &rateLimiter{
+		capacity: float64(count),
+		perSec:   float64(count) / period.Seconds(),
+		tokens:   float64(count),
+	}, nil
+}
+
+// allow takes a token if one is available
+func (l *rateLimiter) allow(now time.Time) bool {
+	if !l.last.IsZero() {
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.perSec)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,349 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/alert"
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+var testNotification = Notification{
+	Kind:     KindAlertFiring,
+	Time:     testStart,
+	Host:     "db1",
+	Severity: alert.SeverityCritical,
+	Summary:  "cpu of postgres is 97.0",
+}
+
+// testChannel creates a channel and collects what it logs
+func testChannel(t *testing.T, config ChannelConfig) (*Channel, *[]string) {
+	t.Helper()
+	channel, err := newChannel(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged []string
+	channel.logf = func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	return channel, &logged
+}
+
+// shortenRetries makes retries quick for the duration of a test
+func shortenRetries(t *testing.T) {
+	previous := minRetryDelay
+	minRetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { minRetryDelay = previous })
+}
+
+// webhookReceiver answers with the given statuses in turn, then 200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestWebhookRetriesTemporaryFailures(t *testing.T) {
+	shortenRetries(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel, logged := testChannel(t, ChannelConfig{Type: "webhook", URL: server.URL})
+	channel.deliver(context.Background(), testNotification)
+
+	if len(receiver.bodies) != 3 {
+		t.Fatalf("webhook called %d times, want 3", len(receiver.bodies))
+	}
+	var got Notification
+	if err := json.Unmarshal(receiver.bodies[2], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != KindAlertFiring || got.Summary != testNotification.Summary {
+		t.Errorf("webhook received %+v", got)
+	}
+	if len(*logged) != 0 {
+		t.Errorf("successful delivery logged %q", *logged)
+	}
+}
+
+func TestWebhookGivesUpOnClientErrors(t *testing.T) {
+	shortenRetries(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	channel, logged := testChannel(t, ChannelConfig{Type: "webhook", URL: server.URL})
+	channel.deliver(context.Background(), testNotification)
+
+	if len(receiver.bodies) != 1 {
+		t.Errorf("webhook called %d times, want 1 (no retry after 400)", len(receiver.bodies))
+	}
+	if len(*logged) != 1 || !strings.Contains((*logged)[0], "400") {
+		t.Errorf("logged %q, want the 400 reported", *logged)
+	}
+}
+
+// selfSignedCert creates a certificate for 127.0.0.1 and a pool trusting it
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mail"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// smtpSession is what the test SMTP server received
+type smtpSession struct {
+	tls        bool // Whether MAIL FROM came after STARTTLS
+	from       string
+	recipients []string
+	data       string
+}
+
+// serveSMTP answers one SMTP session offering STARTTLS
+func serveSMTP(conn net.Conn, config *tls.Config) (smtpSession, error) {
+	defer conn.Close()
+	var session smtpSession
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 127.0.0.1 ESMTP test")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return session, err
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			if session.tls {
+				text.PrintfLine("250 127.0.0.1")
+			} else {
+				text.PrintfLine("250-127.0.0.1\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			text.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, config)
+			if err := tlsConn.Handshake(); err != nil {
+				return session, err
+			}
+			conn = tlsConn
+			text = textproto.NewConn(tlsConn)
+			session.tls = true
+		case "MAIL":
+			session.from = arg
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			session.recipients = append(session.recipients, arg)
+			text.PrintfLine("250 ok")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return session, err
+			}
+			session.data = string(data)
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return session, nil
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPUpgradesToTLS(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		session, err := serveSMTP(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err != nil {
+			t.Errorf("SMTP session: %v", err)
+		}
+		sessions <- session
+	}()
+
+	channel, logged := testChannel(t, ChannelConfig{
+		Type:    "smtp",
+		Address: listener.Addr().String(),
+		From:    "pulse@example.com",
+		To:      []string{"ops@example.com"},
+	})
+	channel.sender.(*smtpSender).rootCAs = pool
+	channel.deliver(context.Background(), testNotification)
+	if len(*logged) != 0 {
+		t.Fatalf("delivery failed: %q", *logged)
+	}
+
+	session := <-sessions
+	if !session.tls {
+		t.Error("message was sent without STARTTLS")
+	}
+	if session.from != "FROM:<pulse@example.com>" || len(session.recipients) != 1 || session.recipients[0] != "TO:<ops@example.com>" {
+		t.Errorf("envelope from %q to %q", session.from, session.recipients)
+	}
+	if !strings.Contains(session.data, "Subject: [pulse] cpu of postgres is 97.0") || !strings.Contains(session.data, "Severity: critical") {
+		t.Errorf("message:\n%s", session.data)
+	}
+}
+
+func TestSyslogOverUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	channel, logged := testChannel(t, ChannelConfig{
+		Type:     "syslog",
+		Network:  "udp",
+		Address:  listener.LocalAddr().String(),
+		Facility: "local0",
+		Tag:      "pulse-test",
+	})
+	channel.deliver(context.Background(), testNotification)
+	if len(*logged) != 0 {
+		t.Fatalf("delivery failed: %q", *logged)
+	}
+
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(buf[:n])
+	// local0 (16) * 8 + critical (2)
+	if !strings.HasPrefix(line, "<130>") || !strings.Contains(line, " pulse-test[") || !strings.HasSuffix(line, ": cpu of postgres is 97.0\n") {
+		t.Errorf("syslog line %q", line)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter, err := parseRateLimit("2/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{0, true}, // Burst up to the count
+		{time.Second, false},
+		{29 * time.Second, true}, // One token every 30 seconds
+		{time.Second, false},
+		{10 * time.Minute, true}, // Refills to the count, not beyond
+		{0, true},
+		{0, false},
+	}
+	now := testStart
+	for i, step := range steps {
+		now = now.Add(step.after)
+		if got := limiter.allow(now); got != step.want {
+			t.Errorf("step %d: allow = %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestProcessEventsOnlyForWatchedProcesses(t *testing.T) {
+	notifier, err := New([]ChannelConfig{
+		{Name: "any", Type: "exec", Command: []string{"true"}, Events: []string{KindProcessExit}},
+		{Name: "db", Type: "exec", Command: []string{"true"}, Events: []string{KindProcessExit}, Process: "postgres"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.SetAlertCoverage(func(name string) bool { return name == "worker" })
+
+	tests := []struct {
+		name string
+		want []int // Queued notifications per channel
+	}{
+		{"sh", []int{0, 0}},
+		{"postgres", []int{0, 1}},
+		{"worker", []int{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, channel := range notifier.channels {
+				for len(channel.queue) > 0 {
+					<-channel.queue
+				}
+			}
+			notifier.ProcessEvent(monitor.ProcessEvent{
+				Kind:    monitor.ProcessExited,
+				Time:    testStart,
+				Process: monitor.NewProcessKey(42, testStart),
+				Name:    tt.name,
+			})
+			for i, channel := range notifier.channels {
+				if len(channel.queue) != tt.want[i] {
+					t.Errorf("channel %s queued %d, want %d", channel.name, len(channel.queue), tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// execSender runs a local command with the message on standard input
+type execSender struct {
+	command  []string
+	template *template.Template
+}
+
+func newExecSender(config ChannelConfig) (*execSender, error) {
+	if len(config.Command) == 0 {
+		return nil, errors.New("exec channel needs a command")
+	}
+	tmpl, err := parseTemplate("template", config.Template)
+	if err != nil {
+		return nil, err
+	}
+	return &execSender{command: config.Command, template: tmpl}, nil
+}
+
+func (s *execSender) send(ctx context.Context, n Notification) error {
+	body, err := render(s.template, n)
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "PULSE_KIND="+n.Kind, "PULSE_SUMMARY="+n.Summary)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return &permanentError{err} // Missing or not executable
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
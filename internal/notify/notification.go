@@ -0,0 +1,144 @@
+// Package notify delivers alerts and process events to external channels:
+// local commands, webhooks, email and syslog.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/alert"
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Notification kinds
+const (
+	KindAlertFiring   = "alert_firing"
+	KindAlertResolved = "alert_resolved"
+	KindProcessExit   = "process_exit"
+	KindPIDReuse      = "pid_reuse"
+)
+
+// kinds lists every notification kind, for validation
+var kinds = []string{KindAlertFiring, KindAlertResolved, KindProcessExit, KindPIDReuse}
+
+// Notification is what channels deliver. It is the JSON document sent by
+// webhooks and exec hooks, and the data of message templates.
+type Notification struct {
+	Kind        string     `json:"kind"`
+	Time        time.Time  `json:"time"`
+	Host        string     `json:"host"`
+	Severity    string     `json:"severity"`
+	Summary     string     `json:"summary"` // One line describing what happened
+	Alert       *AlertInfo `json:"alert,omitempty"`
+	Process     *Process   `json:"process,omitempty"`     // Process the alert or event is about
+	Replacement *Process   `json:"replacement,omitempty"` // New holder of a reused PID
+}
+
+// AlertInfo describes the alert behind an alert notification
+type AlertInfo struct {
+	Rule       string     `json:"rule"`
+	State      string     `json:"state"`
+	Instance   string     `json:"instance"`
+	Metric     string     `json:"metric"`
+	Op         string     `json:"op"`
+	Threshold  float64    `json:"threshold"`
+	Value      float64    `json:"value"`
+	Since      time.Time  `json:"since"`
+	FiredAt    time.Time  `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Process identifies a process in a notification
+type Process struct {
+	PID       int32  `json:"pid"`
+	Name      string `json:"name"`
+	StartTime int64  `json:"start_time"` // Milliseconds since the epoch
+}
+
+// FromAlert builds the notification for a fired or resolved alert. The host
+// is used unless the alert names its own.
+func FromAlert(a alert.Alert, host string) Notification {
+	if a.Host != "" {
+		host = a.Host
+	}
+	info := &AlertInfo{
+		Rule:      a.Rule,
+		State:     a.State.String(),
+		Instance:  a.Instance,
+		Metric:    a.Metric,
+		Op:        a.Op,
+		Threshold: a.Threshold,
+		Value:     a.Value,
+		Since:     a.Since,
+		FiredAt:   a.FiredAt,
+	}
+	n := Notification{
+		Kind:     KindAlertFiring,
+		Time:     a.FiredAt,
+		Host:     host,
+		Severity: a.Severity,
+		Alert:    info,
+		Summary:  fmt.Sprintf("[%s] %s firing on %s: %s", a.Severity, a.Rule, host, a.Description()),
+	}
+	if a.State == alert.StateResolved {
+		resolved := a.ResolvedAt
+		info.ResolvedAt = &resolved
+		n.Kind = KindAlertResolved
+		n.Time = a.ResolvedAt
+		n.Summary = fmt.Sprintf("[%s] %s resolved on %s: %s", a.Severity, a.Rule, host, a.Description())
+	}
+	if a.Process.PID != 0 {
+		n.Process = &Process{PID: a.Process.PID, Name: a.ProcessName, StartTime: a.Process.StartTime}
+	}
+	return n
+}
+
+// FromProcessEvent builds the notification for a process exit or PID reuse
+func FromProcessEvent(event monitor.ProcessEvent, host string) Notification {
+	n := Notification{
+		Kind:     event.Kind.String(),
+		Time:     event.Time,
+		Host:     host,
+		Severity: alert.SeverityInfo,
+		Process:  &Process{PID: event.Process.PID, Name: event.Name, StartTime: event.Process.StartTime},
+	}
+	switch event.Kind {
+	case monitor.PIDReused:
+		n.Replacement = &Process{PID: event.Replacement.PID, Name: event.ReplacementName, StartTime: event.Replacement.StartTime}
+		n.Summary = fmt.Sprintf("PID %d reused on %s: %s exited, now held by %s", event.Process.PID, host, event.Name, event.ReplacementName)
+	default:
+		n.Summary = fmt.Sprintf("Process %s (PID %d) exited on %s", event.Name, event.Process.PID, host)
+	}
+	return n
+}
+
+// templateFuncs are available in message templates
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// parseTemplate compiles a message template, or returns nil for an empty one
+func parseTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// render executes a template, or encodes the notification as JSON without one
+func render(tmpl *template.Template, n Notification) ([]byte, error) {
+	if tmpl == nil {
+		return json.Marshal(n)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
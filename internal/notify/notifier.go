@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"hyperbyte-proc-monitor/internal/alert"
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Notifier turns alert transitions and process events into notifications
+// and hands them to every channel
+type Notifier struct {
+	channels []*Channel
+	hostname string
+	covered  func(name string) bool // Whether alert rules watch a process, nil without rules
+}
+
+// New creates the channels of a notifier
+func New(configs []ChannelConfig) (*Notifier, error) {
+	hostname, _ := os.Hostname()
+	n := &Notifier{hostname: hostname}
+	for i, config := range configs {
+		channel, err := newChannel(config)
+		if err != nil {
+			name := config.Name
+			if name == "" {
+				name = fmt.Sprintf("%d", i+1)
+			}
+			return nil, fmt.Errorf("channel %s: %w", name, err)
+		}
+		n.channels = append(n.channels, channel)
+	}
+	return n, nil
+}
+
+// SetLogf sets where delivery problems of the channels are reported, stderr
+// by default. Call it before Run.
+func (n *Notifier) SetLogf(logf func(format string, args ...any)) {
+	for _, channel := range n.channels {
+		channel.logf = logf
+	}
+}
+
+// SetAlertCoverage lets process events through to channels without a process
+// filter when an alert rule applies to the process, e.g. alert.Engine.Covers.
+// Call it before the first event.
+func (n *Notifier) SetAlertCoverage(covered func(name string) bool) {
+	n.covered = covered
+}
+
+// Alert notifies about a fired or resolved alert, for use with alert.Engine.Subscribe
+func (n *Notifier) Alert(a alert.Alert) {
+	n.Notify(FromAlert(a, n.hostname))
+}
+
+// ProcessEvent notifies about a process exit or PID reuse, for use with
+// monitor.Monitor.SubscribeEvents. Only processes that a channel's process
+// filter or an alert rule watches are of interest, the others come and go
+// all the time.
+func (n *Notifier) ProcessEvent(event monitor.ProcessEvent) {
+	notification := FromProcessEvent(event, n.hostname)
+	covered := n.covered != nil && n.covered(event.Name)
+	for _, channel := range n.channels {
+		if channel.process != nil || covered {
+			channel.Offer(notification)
+		}
+	}
+}
+
+// Notify hands a notification to every channel
+func (n *Notifier) Notify(notification Notification) {
+	for _, channel := range n.channels {
+		channel.Offer(notification)
+	}
+}
+
+// Run delivers notifications until the context is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, channel := range n.channels {
+		wg.Add(1)
+		go func(channel *Channel) {
+			defer wg.Done()
+			channel.Run(ctx)
+		}(channel)
+	}
+	wg.Wait()
+}
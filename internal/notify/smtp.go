@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultSubject and defaultBody are used when an SMTP channel sets no templates
+const (
+	defaultSubject = "[pulse] {{.Summary}}"
+	defaultBody    = `{{.Summary}}
+
+Kind:     {{.Kind}}
+Host:     {{.Host}}
+Time:     {{.Time.Format "2006-01-02 15:04:05 MST"}}
+Severity: {{.Severity}}
+{{- with .Alert}}
+Rule:     {{.Rule}}
+Instance: {{.Instance}}
+Value:    {{.Metric}} = {{printf "%.1f" .Value}} ({{.Op}} {{.Threshold}})
+{{- end}}
+{{- with .Process}}
+Process:  {{.Name}} (PID {{.PID}})
+{{- end}}
+{{- with .Replacement}}
+Now:      {{.Name}} (PID {{.PID}})
+{{- end}}
+`
+)
+
+// smtpSender sends the message as an email. It upgrades to TLS when the
+// server offers STARTTLS, and authenticates when a username is set.
+type smtpSender struct {
+	address  string
+	host     string
+	from     string
+	to       []string
+	username string
+	password string
+	subject  *template.Template
+	body     *template.Template
+	rootCAs  *x509.CertPool // Trusted for STARTTLS, nil for the system roots
+}
+
+func newSMTPSender(config ChannelConfig) (*smtpSender, error) {
+	host, _, err := net.SplitHostPort(config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("smtp channel needs an address like host:25: %w", err)
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, errors.New("smtp channel needs from and to")
+	}
+	subjectText := config.Subject
+	if subjectText == "" {
+		subjectText = defaultSubject
+	}
+	subject, err := parseTemplate("subject", subjectText)
+	if err != nil {
+		return nil, err
+	}
+	bodyText := config.Template
+	if bodyText == "" {
+		bodyText = defaultBody
+	}
+	body, err := parseTemplate("template", bodyText)
+	if err != nil {
+		return nil, err
+	}
+	return &smtpSender{
+		address:  config.Address,
+		host:     host,
+		from:     config.From,
+		to:       config.To,
+		username: config.Username,
+		password: config.Password,
+		subject:  subject,
+		body:     body,
+	}, nil
+}
+
+func (s *smtpSender) send(ctx context.Context, n Notification) error {
+	subject, err := render(s.subject, n)
+	if err != nil {
+		return &permanentError{err}
+	}
+	body, err := render(s.body, n)
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(string(subject)), " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body)
+
+	return smtpError(s.deliver(ctx, msg.String()))
+}
+
+// deliver runs one SMTP session
+func (s *smtpSender) deliver(ctx context.Context, msg string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host, RootCAs: s.rootCAs}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// smtpError marks rejections with a 5xx reply as permanent
+func smtpError(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &permanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/alert"
+)
+
+// syslogFacilities maps facility names to their codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSockets are where the local syslog daemon usually listens
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogSender writes the message to the local syslog socket, or to a
+// remote syslog server over UDP or TCP, in the BSD syslog format
+type syslogSender struct {
+	network  string // Empty for the local socket
+	address  string
+	facility int
+	tag      string
+	template *template.Template
+	hostname string
+}
+
+func newSyslogSender(config ChannelConfig) (*syslogSender, error) {
+	s := &syslogSender{network: config.Network, address: config.Address, facility: 3, tag: config.Tag}
+	switch s.network {
+	case "":
+		if s.address != "" {
+			s.network = "unixgram"
+		}
+	case "unixgram", "unix", "udp", "tcp":
+		if s.address == "" {
+			return nil, fmt.Errorf("syslog network %s needs an address", s.network)
+		}
+	default:
+		return nil, fmt.Errorf("unknown syslog network %q (available: unixgram, unix, udp, tcp)", s.network)
+	}
+	if config.Facility != "" {
+		facility, ok := syslogFacilities[config.Facility]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", config.Facility)
+		}
+		s.facility = facility
+	}
+	if s.tag == "" {
+		s.tag = "pulse"
+	}
+	tmpl, err := parseTemplate("template", config.Template)
+	if err != nil {
+		return nil, err
+	}
+	s.template = tmpl
+	s.hostname, _ = os.Hostname()
+	return s, nil
+}
+
+func (s *syslogSender) send(ctx context.Context, n Notification) error {
+	text := []byte(n.Summary)
+	if s.template != nil {
+		var err error
+		if text, err = render(s.template, n); err != nil {
+			return &permanentError{err}
+		}
+	}
+	msg := strings.Join(strings.Fields(string(text)), " ")
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	priority := s.facility*8 + syslogSeverity(n)
+	now := time.Now()
+	var line string
+	switch conn.LocalAddr().Network() {
+	case "udp", "tcp":
+		// Remote servers need the hostname to tell senders apart
+		line = fmt.Sprintf("<%d>%s %s %s[%d]: %s\n", priority, now.Format(time.RFC3339), s.hostname, s.tag, os.Getpid(), msg)
+	default:
+		line = fmt.Sprintf("<%d>%s %s[%d]: %s\n", priority, now.Format(time.Stamp), s.tag, os.Getpid(), msg)
+	}
+	_, err = conn.Write([]byte(line))
+	return err
+}
+
+// dial connects to the configured address, or tries the usual local sockets
+func (s *syslogSender) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	if s.network != "" {
+		return dialer.DialContext(ctx, s.network, s.address)
+	}
+	for _, path := range syslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := dialer.DialContext(ctx, network, path); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("no local syslog socket found")
+}
+
+// syslogSeverity maps a notification to a syslog severity
+func syslogSeverity(n Notification) int {
+	if n.Kind != KindAlertFiring {
+		return 5 // Notice, for resolutions and process events
+	}
+	switch n.Severity {
+	case alert.SeverityCritical:
+		return 2
+	case alert.SeverityWarning:
+		return 4
+	default:
+		return 6 // Informational
+	}
+}
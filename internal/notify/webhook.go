@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// webhookSender sends the message in the body of an HTTP request
+type webhookSender struct {
+	url      string
+	method   string
+	headers  map[string]string
+	template *template.Template
+	client   *http.Client
+}
+
+func newWebhookSender(config ChannelConfig) (*webhookSender, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook channel needs an http or https url, got %q", config.URL)
+	}
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	tmpl, err := parseTemplate("template", config.Template)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookSender{
+		url:      config.URL,
+		method:   method,
+		headers:  config.Headers,
+		template: tmpl,
+		client:   &http.Client{},
+	}, nil
+}
+
+func (s *webhookSender) send(ctx context.Context, n Notification) error {
+	body, err := render(s.template, n)
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, s.method, s.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pulse")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = errors.New(resp.Status)
+	// Other client errors mean the request itself is wrong
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,329 @@
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/audit"
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// AgentOptions configures an agent
+type AgentOptions struct {
+	Listen       string     // TCP address to listen on
+	TLS          TLSFiles   // CA is the authority viewer certificates must be signed by
+	Token        string     // Shared secret viewers must present
+	AllowSignals bool       // Whether viewers may send any of monitor.Signals and change scheduling
+	Audit        *audit.Log // Records the actions of viewers, may be nil
+}
+
+// Agent streams the snapshots of a monitor to connected viewers
+type Agent struct {
+	monitor  *monitor.Monitor
+	opts     AgentOptions
+	listener net.Listener
+	hostname string
+
+	mu      sync.Mutex
+	latest  *Snapshot
+	clients map[chan *Snapshot]struct{}
+
+	logf func(format string, args ...any)
+}
+
+// NewAgent starts listening and subscribes to the monitor's updates
+func NewAgent(mon *monitor.Monitor, opts AgentOptions) (*Agent, error) {
+	if opts.Token == "" {
+		return nil, errors.New("agent requires a token")
+	}
+	config, err := serverTLSConfig(opts.TLS)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := tls.Listen("tcp", opts.Listen, config)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+
+	a := &Agent{
+		monitor:  mon,
+		opts:     opts,
+		listener: listener,
+		hostname: hostname,
+		clients:  make(map[chan *Snapshot]struct{}),
+		logf:     logToStderr,
+	}
+	mon.Subscribe(a.publish)
+	return a, nil
+}
+
+// SetLogf sets where viewer errors and actions are reported, stderr by
+// default. Call it before Serve.
+func (a *Agent) SetLogf(logf func(format string, args ...any)) {
+	a.logf = logf
+}
+
+// logToStderr is the default log function of the agent
+func logToStderr(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// Addr returns the address the agent listens on
+func (a *Agent) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+// Close stops listening. It is only needed when Serve is never called.
+func (a *Agent) Close() error {
+	return a.listener.Close()
+}
+
+// Serve accepts viewers until the context is cancelled
+func (a *Agent) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		a.listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			if err := a.serveConn(ctx, conn.(*tls.Conn)); err != nil && ctx.Err() == nil {
+				a.logf("Agent: viewer %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// publish hands a new snapshot to every viewer. Slow viewers skip snapshots
+// rather than holding up the monitor.
+func (a *Agent) publish(update monitor.Update) {
+	snapshot := newSnapshot(update)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.latest = snapshot
+	for ch := range a.clients {
+		select {
+		case <-ch: // Replace an unsent snapshot
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+// subscribe registers a viewer, primed with the latest snapshot
+func (a *Agent) subscribe() chan *Snapshot {
+	ch := make(chan *Snapshot, 1)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.latest != nil {
+		ch <- a.latest
+	}
+	a.clients[ch] = struct{}{}
+	return ch
+}
+
+func (a *Agent) unsubscribe(ch chan *Snapshot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.clients, ch)
+}
+
+// serveConn authenticates a viewer and streams snapshots until either side stops
+func (a *Agent) serveConn(ctx context.Context, conn *tls.Conn) error {
+	handshakeCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	err := conn.HandshakeContext(handshakeCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	c := newCodec(conn)
+	var h hello
+	if err := c.receive(&h, handshakeTimeout); err != nil {
+		return fmt.Errorf("failed to read hello: %w", err)
+	}
+	reply := welcome{Version: protocolVersion, Hostname: a.hostname, Signals: a.opts.AllowSignals, Tuning: true}
+	switch {
+	case h.Version != protocolVersion:
+		reply.Error = fmt.Sprintf("unsupported protocol version %d, agent speaks %d", h.Version, protocolVersion)
+	case subtle.ConstantTimeCompare([]byte(h.Token), []byte(a.opts.Token)) != 1:
+		reply.Error = "invalid token"
+	}
+	if err := c.send(reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+
+	snapshots := a.subscribe()
+	defer a.unsubscribe(snapshots)
+
+	requests := make(chan error, 1)
+	go func() {
+		requests <- a.handleRequests(ctx, c, conn)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-requests:
+			// A viewer closing the connection ends the stream without a final block
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case snapshot := <-snapshots:
+			if err := c.send(serverMessage{Snapshot: snapshot}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleRequests answers the viewer's requests until the connection fails
+func (a *Agent) handleRequests(ctx context.Context, c *codec, conn net.Conn) error {
+	defer conn.Close() // Unblocks a pending snapshot write
+	for {
+		var msg clientMessage
+		// Viewers only send requests, so there is no read deadline
+		if err := c.receive(&msg, 0); err != nil {
+			return err
+		}
+		var result *actionResult
+		var err error
+		switch {
+		case msg.Signal != nil:
+			result = &actionResult{ID: msg.Signal.ID}
+			err = a.signal(ctx, conn.RemoteAddr(), msg.Signal)
+		case msg.Tuning != nil:
+			result = &actionResult{ID: msg.Tuning.ID}
+			result.Scheduling, err = a.tune(ctx, conn.RemoteAddr(), msg.Tuning)
+		default:
+			continue
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.Permission = errors.Is(err, os.ErrPermission)
+		}
+		if err := c.send(serverMessage{Result: result}); err != nil {
+			return err
+		}
+	}
+}
+
+// signal authorizes and performs a signal request
+func (a *Agent) signal(ctx context.Context, viewer net.Addr, req *signalRequest) error {
+	sig, err := monitor.ParseSignal(req.Name)
+	if err == nil && !a.opts.AllowSignals {
+		err = errors.New("process actions are disabled on this agent")
+	}
+	if err != nil {
+		a.record(viewer, req.Key, "signal", req.Name, audit.ResultDenied, err)
+		return err
+	}
+
+	err = a.monitor.Signal(ctx, req.Key, sig)
+	a.record(viewer, req.Key, "signal", monitor.SignalName(sig), auditResult(err), err)
+	a.logf("Agent: viewer %s sent %s to PID %d: %s", viewer, monitor.SignalName(sig), req.Key.PID, outcome(err))
+	return err
+}
+
+// tune reads the scheduling of a process, or authorizes and makes a change
+func (a *Agent) tune(ctx context.Context, viewer net.Addr, req *tuningRequest) (*monitor.Scheduling, error) {
+	var action, argument string
+	var change func() error
+	switch {
+	case req.Nice != nil:
+		action, argument = "renice", strconv.Itoa(*req.Nice)
+		change = func() error { return a.monitor.SetNice(ctx, req.Key, *req.Nice) }
+	case req.IOPriority != nil:
+		action, argument = "ionice", req.IOPriority.String()
+		change = func() error { return a.monitor.SetIOPriority(ctx, req.Key, *req.IOPriority) }
+	case req.Affinity != nil:
+		action, argument = "affinity", monitor.FormatCPUList(req.Affinity)
+		change = func() error { return a.monitor.SetAffinity(ctx, req.Key, req.Affinity) }
+	default:
+		sched, err := a.monitor.Scheduling(ctx, req.Key)
+		if err != nil {
+			return nil, err
+		}
+		return &sched, nil
+	}
+
+	if !a.opts.AllowSignals {
+		err := errors.New("process actions are disabled on this agent")
+		a.record(viewer, req.Key, action, argument, audit.ResultDenied, err)
+		return nil, err
+	}
+	err := change()
+	a.record(viewer, req.Key, action, argument, auditResult(err), err)
+	a.logf("Agent: viewer %s ran %s %s on PID %d: %s", viewer, action, argument, req.Key.PID, outcome(err))
+	return nil, err
+}
+
+// auditResult is the audit log result of an action that was allowed
+func auditResult(err error) string {
+	if err != nil {
+		return audit.ResultFailed
+	}
+	return audit.ResultOK
+}
+
+// outcome describes the result of an action for the log
+func outcome(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// record writes a viewer's action to the audit log
+func (a *Agent) record(viewer net.Addr, key monitor.ProcessKey, action, argument, result string, err error) {
+	if a.opts.Audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		Host:      a.hostname,
+		Viewer:    viewer.String(),
+		PID:       key.PID,
+		StartTime: time.UnixMilli(key.StartTime),
+		Action:    action,
+		Argument:  argument,
+		Result:    result,
+	}
+	if node := a.monitor.GetProcessTree().Node(key); node != nil {
+		entry.Name = node.Process.Name
+		entry.Cmdline = node.Process.Cmdline
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := a.opts.Audit.Record(entry); err != nil {
+		a.logf("Agent: %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Reconnect backoff and the time a viewer waits for an action to complete
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+	actionTimeout     = 10 * time.Second
+)
+
+// ClientOptions configures a viewer connection
+type ClientOptions struct {
+	Address    string   // Agent address as host:port
+	TLS        TLSFiles // CA is the authority the agent certificate must be signed by
+	ServerName string   // Name expected in the agent certificate, defaults to the host of Address
+	Token      string
+}
+
+// ConnectionState describes the connection to the agent
+type ConnectionState struct {
+	Address   string
+	Hostname  string // Reported by the agent
+	Connected bool
+	Err       error // Why the last connection attempt failed
+}
+
+// Client receives snapshots from an agent and applies them to a local
+// monitor, so the UI works as if the processes were local
+type Client struct {
+	opts    ClientOptions
+	config  *tls.Config
+	monitor *monitor.Monitor
+	clock   *monitor.FakeClock
+
+	mu       sync.Mutex
+	state    ConnectionState
+	signals  bool   // Whether the agent accepts process actions
+	tuning   bool   // Whether the agent answers tuning requests
+	codec    *codec // Current connection, nil while disconnected
+	nextID   uint64
+	pending  map[uint64]chan actionResult
+	onUpdate func()
+}
+
+// NewClient creates a client that connects once Run is called
+func NewClient(opts ClientOptions) (*Client, error) {
+	if opts.ServerName == "" {
+		host, _, err := net.SplitHostPort(opts.Address)
+		if err != nil {
+			return nil, err
+		}
+		opts.ServerName = host
+	}
+	config, err := clientTLSConfig(opts.TLS, opts.ServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	clock := monitor.NewFakeClock(time.Now())
+	return &Client{
+		opts:    opts,
+		config:  config,
+		monitor: monitor.NewSnapshotMonitor(clock),
+		clock:   clock,
+		state:   ConnectionState{Address: opts.Address},
+		pending: make(map[uint64]chan actionResult),
+	}, nil
+}
+
+// Dial creates a client and connects to the agent, so configuration and
+// authentication errors are reported right away. Later connection failures
+// are retried by Run.
+func Dial(ctx context.Context, opts ClientOptions) (*Client, error) {
+	c, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Monitor returns the monitor holding the agent's data
+func (c *Client) Monitor() *monitor.Monitor {
+	return c.monitor
+}
+
+// SetOnUpdate registers a function called after a snapshot was applied or
+// the connection state changed
+func (c *Client) SetOnUpdate(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onUpdate = fn
+}
+
+// State returns the current connection state
+func (c *Client) State() ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Run connects if needed, receives snapshots and reconnects after failures
+// until the context is cancelled
+func (c *Client) Run(ctx context.Context) {
+	delay := minReconnectDelay
+	for {
+		c.mu.Lock()
+		conn := c.codec
+		c.mu.Unlock()
+
+		var wait time.Duration
+		if conn != nil {
+			c.disconnect(c.receive(ctx, conn))
+			delay = minReconnectDelay
+			wait = delay
+		} else if err := c.connect(ctx); err != nil {
+			c.setError(err)
+			wait = delay
+			delay = min(delay*2, maxReconnectDelay)
+		} else {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Signal asks the agent to send a signal to a process and waits for the result
+func (c *Client) Signal(ctx context.Context, key monitor.ProcessKey, sig syscall.Signal) error {
+	_, err := c.request(ctx, true, func(id uint64) clientMessage {
+		return clientMessage{Signal: &signalRequest{ID: id, Key: key, Name: monitor.SignalName(sig)}}
+	})
+	return err
+}
+
+// Scheduling asks the agent for the nice value, I/O priority and CPU affinity of a process
+func (c *Client) Scheduling(ctx context.Context, key monitor.ProcessKey) (monitor.Scheduling, error) {
+	res, err := c.tune(ctx, tuningRequest{Key: key})
+	if err != nil {
+		return monitor.Scheduling{}, err
+	}
+	if res.Scheduling == nil {
+		return monitor.Scheduling{}, errors.New("agent sent no scheduling")
+	}
+	return *res.Scheduling, nil
+}
+
+// SetNice asks the agent to change the nice value of a process
+func (c *Client) SetNice(ctx context.Context, key monitor.ProcessKey, nice int) error {
+	_, err := c.tune(ctx, tuningRequest{Key: key, Nice: &nice})
+	return err
+}
+
+// SetIOPriority asks the agent to change the I/O class and level of a process
+func (c *Client) SetIOPriority(ctx context.Context, key monitor.ProcessKey, prio monitor.IOPriority) error {
+	_, err := c.tune(ctx, tuningRequest{Key: key, IOPriority: &prio})
+	return err
+}
+
+// SetAffinity asks the agent to restrict a process to the given CPUs
+func (c *Client) SetAffinity(ctx context.Context, key monitor.ProcessKey, cpus []int) error {
+	_, err := c.tune(ctx, tuningRequest{Key: key, Affinity: cpus})
+	return err
+}
+
+// tune sends a tuning request
+func (c *Client) tune(ctx context.Context, req tuningRequest) (actionResult, error) {
+	c.mu.Lock()
+	tuning := c.tuning
+	c.mu.Unlock()
+	if !tuning {
+		return actionResult{}, errors.New("the agent does not support scheduling controls")
+	}
+	return c.request(ctx, req.changes(), func(id uint64) clientMessage {
+		req.ID = id
+		return clientMessage{Tuning: &req}
+	})
+}
+
+// request sends a request built for a new ID and waits for its result.
+// Actions are refused right away when the agent disabled them. Errors the
+// agent reports as permission problems match os.ErrPermission.
+func (c *Client) request(ctx context.Context, action bool, build func(id uint64) clientMessage) (actionResult, error) {
+	c.mu.Lock()
+	conn := c.codec
+	if conn == nil {
+		c.mu.Unlock()
+		return actionResult{}, errors.New("not connected to agent")
+	}
+	if action && !c.signals {
+		c.mu.Unlock()
+		return actionResult{}, errors.New("process actions are disabled on this agent")
+	}
+	c.nextID++
+	id := c.nextID
+	result := make(chan actionResult, 1)
+	c.pending[id] = result
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := conn.send(build(id)); err != nil {
+		return actionResult{}, err
+	}
+
+	timer := time.NewTimer(actionTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return actionResult{}, ctx.Err()
+	case <-timer.C:
+		return actionResult{}, errors.New("agent did not respond")
+	case res := <-result:
+		if res.Permission {
+			return res, &permissionError{res.Error}
+		}
+		if res.Error != "" {
+			return res, errors.New(res.Error)
+		}
+		return res, nil
+	}
+}
+
+// Close drops the connection of a client that is not running
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.codec != nil {
+		c.codec.conn.Close()
+		c.codec = nil
+	}
+	c.state.Connected = false
+}
+
+// permissionError is an agent's failure to act on a process it may not touch
+type permissionError struct {
+	msg string
+}
+
+func (e *permissionError) Error() string { return e.msg }
+
+// Is lets callers detect the failure with errors.Is(err, os.ErrPermission)
+func (e *permissionError) Is(target error) bool { return target == os.ErrPermission }
+
+// connect dials the agent and exchanges the hello
+func (c *Client) connect(ctx context.Context) error {
+	dialer := &tls.Dialer{Config: c.config}
+	dialCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	conn, err := dialer.DialContext(dialCtx, "tcp", c.opts.Address)
+	if err != nil {
+		return err
+	}
+
+	codec := newCodec(conn)
+	var reply welcome
+	err = codec.send(hello{Version: protocolVersion, Token: c.opts.Token})
+	if err == nil {
+		err = codec.receive(&reply, handshakeTimeout)
+	}
+	if err == nil && reply.Error != "" {
+		err = fmt.Errorf("agent refused connection: %s", reply.Error)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.codec = codec
+	c.signals = reply.Signals
+	c.tuning = reply.Tuning
+	c.state.Hostname = reply.Hostname
+	c.state.Connected = true
+	c.state.Err = nil
+	c.mu.Unlock()
+	c.notify()
+	return nil
+}
+
+// receive applies snapshots and delivers action results until the connection fails
+func (c *Client) receive(ctx context.Context, conn *codec) error {
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+
+	for {
+		var msg serverMessage
+		if err := conn.receive(&msg, readTimeout); err != nil {
+			return err
+		}
+		if msg.Snapshot != nil {
+			c.clock.Set(msg.Snapshot.System.Timestamp)
+			c.monitor.ApplySnapshot(msg.Snapshot.System, msg.Snapshot.Processes, msg.Snapshot.Others)
+			c.notify()
+		}
+		if msg.Result != nil {
+			c.mu.Lock()
+			if ch, ok := c.pending[msg.Result.ID]; ok {
+				ch <- *msg.Result
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// disconnect closes the current connection after a failure
+func (c *Client) disconnect(err error) {
+	c.mu.Lock()
+	if c.codec != nil {
+		c.codec.conn.Close()
+		c.codec = nil
+	}
+	c.state.Connected = false
+	c.state.Err = err
+	c.mu.Unlock()
+	c.notify()
+}
+
+func (c *Client) setError(err error) {
+	c.mu.Lock()
+	c.state.Err = err
+	c.mu.Unlock()
+	c.notify()
+}
+
+func (c *Client) notify() {
+	c.mu.Lock()
+	onUpdate := c.onUpdate
+	c.mu.Unlock()
+	if onUpdate != nil {
+		onUpdate()
+	}
+}
@@ -0,0 +1,153 @@
+// Package remote streams monitor snapshots from a headless agent to viewers
+// over mutually authenticated TLS, and forwards process actions back.
+package remote
+
+import (
+	"compress/flate"
+	"encoding/gob"
+	"net"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// protocolVersion is increased on incompatible changes to the messages.
+// Gob tolerates added and removed fields, so new metrics don't need a bump.
+const protocolVersion = 1
+
+// Connection timeouts
+const (
+	handshakeTimeout = 10 * time.Second
+	writeTimeout     = 30 * time.Second
+	readTimeout      = time.Minute // Agents send a snapshot every few seconds
+)
+
+// hello is the first message of a viewer
+type hello struct {
+	Version int
+	Token   string
+}
+
+// welcome answers a hello. A non-empty Error rejects the viewer.
+type welcome struct {
+	Version  int
+	Hostname string
+	Signals  bool // Whether the agent accepts process actions
+	Tuning   bool // Whether the agent answers tuning requests
+	Error    string
+}
+
+// Snapshot is the state after one agent update. Process CPU usage is per-core.
+type Snapshot struct {
+	System    monitor.SystemMetrics
+	Processes []monitor.ProcessInfo
+	Others    []monitor.ProcessInfo // Running processes outside the top list
+}
+
+// serverMessage is sent by the agent after the welcome; exactly one field is set
+type serverMessage struct {
+	Snapshot *Snapshot
+	Result   *actionResult
+}
+
+// clientMessage is sent by the viewer after the hello
+type clientMessage struct {
+	Signal *signalRequest
+	Tuning *tuningRequest
+}
+
+// signalRequest asks the agent to send a signal to a process. Signal numbers
+// differ between platforms, so the signal is given by name.
+type signalRequest struct {
+	ID   uint64
+	Key  monitor.ProcessKey
+	Name string // e.g. "SIGTERM"
+}
+
+// tuningRequest reads the scheduling of a process, or changes the one of
+// Nice, IOPriority and Affinity that is set
+type tuningRequest struct {
+	ID         uint64
+	Key        monitor.ProcessKey
+	Nice       *int
+	IOPriority *monitor.IOPriority
+	Affinity   []int
+}
+
+// changes reports whether a request changes the scheduling rather than reading it
+func (r *tuningRequest) changes() bool {
+	return r.Nice != nil || r.IOPriority != nil || r.Affinity != nil
+}
+
+// actionResult answers a request; an empty Error means success
+type actionResult struct {
+	ID         uint64
+	Error      string
+	Permission bool                // The agent may not act on the process
+	Scheduling *monitor.Scheduling // Answers a tuning request that reads
+}
+
+// codec exchanges gob messages over a deflate-compressed stream in each
+// direction. Every message is flushed on its own, so the stream stays live.
+type codec struct {
+	conn    net.Conn
+	mu      sync.Mutex // Serializes writers
+	flate   *flate.Writer
+	encoder *gob.Encoder
+	decoder *gob.Decoder
+}
+
+func newCodec(conn net.Conn) *codec {
+	fw, _ := flate.NewWriter(conn, flate.BestSpeed) // Only fails for invalid levels
+	return &codec{
+		conn:    conn,
+		flate:   fw,
+		encoder: gob.NewEncoder(fw),
+		decoder: gob.NewDecoder(flate.NewReader(conn)),
+	}
+}
+
+// send writes and flushes one message
+func (c *codec) send(message any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	if err := c.encoder.Encode(message); err != nil {
+		return err
+	}
+	return c.flate.Flush()
+}
+
+// receive reads the next message into message. A zero timeout waits forever.
+func (c *codec) receive(message any, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	return c.decoder.Decode(message)
+}
+
+// newSnapshot converts a monitor update to the wire format
+func newSnapshot(update monitor.Update) *Snapshot {
+	snapshot := &Snapshot{System: update.System, Processes: make([]monitor.ProcessInfo, len(update.Processes))}
+	top := make(map[monitor.ProcessKey]bool, len(update.Processes))
+	for i, proc := range update.Processes {
+		proc.CPUPercent = update.CPUMode.ToPerCore(proc.CPUPercent, update.System.NumCPU)
+		snapshot.Processes[i] = proc
+		top[proc.Key()] = true
+	}
+	for _, proc := range update.All {
+		if !top[proc.Key()] {
+			proc.CPUPercent = update.CPUMode.ToPerCore(proc.CPUPercent, update.System.NumCPU)
+			snapshot.Others = append(snapshot.Others, proc)
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,342 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+const testToken = "secret"
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+// testCA signs certificates for one side of the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	path string // PEM file of the CA certificate
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{cert: cert, key: key, path: filepath.Join(t.TempDir(), name+".pem")}
+	writePEM(t, ca.path, "CERTIFICATE", der)
+	return ca
+}
+
+// issue creates a certificate and key signed by the CA and returns the files
+// to use it with the given CA as the peer's authority
+func (ca *testCA) issue(t *testing.T, name string, server bool, peer *testCA) TLSFiles {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.DNSNames = []string{"localhost"}
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := TLSFiles{Cert: filepath.Join(dir, name+".pem"), Key: filepath.Join(dir, name+".key"), CA: peer.path}
+	writePEM(t, files.Cert, "CERTIFICATE", der)
+	writePEM(t, files.Key, "EC PRIVATE KEY", keyDER)
+	return files
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testAgent is an agent on the loopback interface serving a fake monitor
+type testAgent struct {
+	agent     *Agent
+	collector *monitor.FakeCollector
+	serverCA  *testCA
+	viewerCA  *testCA
+	process   monitor.ProcessKey // The only process of the monitor
+}
+
+func startAgent(t *testing.T, allowSignals bool) *testAgent {
+	t.Helper()
+	serverCA, viewerCA := newTestCA(t, "agent-ca"), newTestCA(t, "viewer-ca")
+
+	collector := monitor.NewFakeCollector()
+	collector.SetSystem(monitor.SystemSample{NumCPU: 1, TotalMemoryBytes: 1 << 30})
+	collector.SetProcess(monitor.ProcessSample{PID: 42, Name: "worker", CreateTime: testStart})
+	clock := monitor.NewFakeClock(testStart)
+	mon := monitor.NewMonitorWithCollector(collector, clock)
+
+	agent, err := NewAgent(mon, AgentOptions{
+		Listen:       "127.0.0.1:0",
+		TLS:          serverCA.issue(t, "agent", true, viewerCA),
+		Token:        testToken,
+		AllowSignals: allowSignals,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.SetLogf(t.Logf)
+
+	clock.Advance(time.Second)
+	if err := mon.UpdateMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		if err := agent.Serve(ctx); err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return &testAgent{
+		agent:     agent,
+		collector: collector,
+		serverCA:  serverCA,
+		viewerCA:  viewerCA,
+		process:   monitor.NewProcessKey(42, testStart),
+	}
+}
+
+// options returns client options for a viewer with a certificate of the given CA
+func (a *testAgent) options(t *testing.T, ca *testCA, token string) ClientOptions {
+	return ClientOptions{
+		Address:    a.agent.Addr().String(),
+		TLS:        ca.issue(t, "viewer", false, a.serverCA),
+		ServerName: "localhost",
+		Token:      token,
+	}
+}
+
+// connect dials the agent and receives snapshots until the test ends
+func (a *testAgent) connect(t *testing.T) *Client {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := Dial(ctx, a.options(t, a.viewerCA, testToken))
+	if err != nil {
+		cancel()
+		t.Fatalf("Dial: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return client
+}
+
+func TestViewerReceivesSnapshots(t *testing.T) {
+	agent := startAgent(t, false)
+	client := agent.connect(t)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(client.Monitor().GetProcesses()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot received")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if proc := client.Monitor().GetProcesses()[0]; proc.Key() != agent.process || proc.Name != "worker" {
+		t.Errorf("received %s %q, want %s worker", proc.Key(), proc.Name, agent.process)
+	}
+	if hostname, _ := os.Hostname(); client.State().Hostname != hostname {
+		t.Errorf("agent hostname %q, want %q", client.State().Hostname, hostname)
+	}
+}
+
+func TestAgentRejectsViewers(t *testing.T) {
+	agent := startAgent(t, false)
+	tests := []struct {
+		name    string
+		ca      *testCA
+		token   string
+		wantErr string
+	}{
+		// The agent requires and verifies a client certificate of its viewer CA
+		{"certificate of another CA", newTestCA(t, "other-ca"), testToken, ""},
+		{"wrong token", agent.viewerCA, "guess", "invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := Dial(context.Background(), agent.options(t, tt.ca, tt.token))
+			if err == nil {
+				client.Close()
+				t.Fatal("viewer was accepted")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAgentRefusesSignalsWhenDisabled(t *testing.T) {
+	agent := startAgent(t, false)
+	client := agent.connect(t)
+
+	if err := client.Signal(context.Background(), agent.process, syscall.SIGTERM); err == nil {
+		t.Error("viewer sent a signal although the agent disabled actions")
+	}
+
+	// The agent has to refuse even when a viewer ignores the welcome
+	client.mu.Lock()
+	client.signals = true
+	client.mu.Unlock()
+	err := client.Signal(context.Background(), agent.process, syscall.SIGTERM)
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("agent answered %v, want actions disabled", err)
+	}
+	if signals := agent.collector.Signals(); len(signals) != 0 {
+		t.Errorf("signals were sent: %v", signals)
+	}
+}
+
+func TestAgentChecksProcessStartTime(t *testing.T) {
+	agent := startAgent(t, true)
+	client := agent.connect(t)
+
+	// The PID now belongs to a process started later than the one the viewer saw
+	stale := agent.process
+	stale.StartTime -= 1000
+	err := client.Signal(context.Background(), stale, syscall.SIGTERM)
+	if err == nil || !strings.Contains(err.Error(), "was replaced") {
+		t.Errorf("signal to a replaced process answered %v, want a PID reuse error", err)
+	}
+	if signals := agent.collector.Signals(); len(signals) != 0 {
+		t.Fatalf("signal reached the new process: %v", signals)
+	}
+
+	if err := client.Signal(context.Background(), agent.process, syscall.SIGTERM); err != nil {
+		t.Fatalf("signal to the current process: %v", err)
+	}
+	if signals := agent.collector.Signals(); len(signals) != 1 || signals[0].PID != 42 || signals[0].Signal != syscall.SIGTERM {
+		t.Errorf("signals = %v, want SIGTERM to PID 42", signals)
+	}
+}
+
+func TestAgentRequiresSignalName(t *testing.T) {
+	agent := startAgent(t, true)
+	client := agent.connect(t)
+
+	for _, name := range []string{"", "SIGSEGV"} {
+		_, err := client.request(context.Background(), true, func(id uint64) clientMessage {
+			return clientMessage{Signal: &signalRequest{ID: id, Key: agent.process, Name: name}}
+		})
+		if err == nil || !strings.Contains(err.Error(), "unsupported signal") {
+			t.Errorf("signal %q answered %v, want it refused", name, err)
+		}
+	}
+	if signals := agent.collector.Signals(); len(signals) != 0 {
+		t.Errorf("signals were sent: %v", signals)
+	}
+}
+
+func TestViewerTunesProcesses(t *testing.T) {
+	agent := startAgent(t, true)
+	client := agent.connect(t)
+	ctx := context.Background()
+
+	if err := client.SetNice(ctx, agent.process, 10); err != nil {
+		t.Fatalf("SetNice: %v", err)
+	}
+	sched, err := client.Scheduling(ctx, agent.process)
+	if err != nil || sched.Nice != 10 {
+		t.Errorf("Scheduling = %+v, %v, want nice 10", sched, err)
+	}
+
+	// The agent marks permission problems so the viewer can explain them
+	agent.collector.SetTuningError(syscall.EPERM)
+	err = client.SetAffinity(ctx, agent.process, []int{0})
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("SetAffinity answered %v, want a permission error", err)
+	}
+}
+
+func TestAgentRefusesTuningWhenDisabled(t *testing.T) {
+	agent := startAgent(t, false)
+	client := agent.connect(t)
+	ctx := context.Background()
+
+	if _, err := client.Scheduling(ctx, agent.process); err != nil {
+		t.Errorf("reading the scheduling failed: %v", err)
+	}
+
+	client.mu.Lock()
+	client.signals = true
+	client.mu.Unlock()
+	err := client.SetNice(ctx, agent.process, 10)
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("agent answered %v, want actions disabled", err)
+	}
+	if sched, _ := client.Scheduling(ctx, agent.process); sched.Nice != 0 {
+		t.Errorf("nice changed to %d", sched.Nice)
+	}
+}
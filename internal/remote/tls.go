@@ -0,0 +1,78 @@
+package remote
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSFiles names the PEM files of one side of a connection
+type TLSFiles struct {
+	Cert string // Own certificate
+	Key  string // Private key of Cert
+	CA   string // Authority the other side's certificate must be signed by
+}
+
+// load reads the certificate and the CA pool
+func (f TLSFiles) load() (tls.Certificate, *x509.CertPool, error) {
+	if f.Cert == "" || f.Key == "" || f.CA == "" {
+		return tls.Certificate{}, nil, errors.New("a certificate, its key and a CA certificate are required")
+	}
+	cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	pem, err := os.ReadFile(f.CA)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return tls.Certificate{}, nil, fmt.Errorf("%s: no certificates found", f.CA)
+	}
+	return cert, pool, nil
+}
+
+// serverTLSConfig requires viewers to present a certificate signed by the CA
+func serverTLSConfig(files TLSFiles) (*tls.Config, error) {
+	cert, pool, err := files.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// clientTLSConfig verifies the agent against the CA and presents the viewer certificate
+func clientTLSConfig(files TLSFiles, serverName string) (*tls.Config, error) {
+	cert, pool, err := files.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// LoadToken reads a shared token from a file, ignoring surrounding whitespace
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s: token is empty", path)
+	}
+	return token, nil
+}
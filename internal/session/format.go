@@ -0,0 +1,146 @@
+// Package session records monitor snapshots to a file and plays them back.
+package session
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// fileMagic starts every session file, followed by a gzip-compressed gob stream
+// of one Header and any number of Frames
+const fileMagic = "PULSESESSION\n"
+
+// formatVersion is increased on incompatible changes to the file layout.
+// Gob tolerates added and removed fields, so new metrics don't need a bump.
+const formatVersion = 1
+
+// Header describes a recording
+type Header struct {
+	Version  int
+	Hostname string
+	Started  time.Time
+	Interval time.Duration
+}
+
+// Frame is one snapshot produced by Monitor.UpdateMetrics. Process CPU usage
+// is always per-core.
+type Frame struct {
+	System    monitor.SystemMetrics
+	Processes []monitor.ProcessInfo
+	Others    []monitor.ProcessInfo // Running processes outside the top list
+}
+
+// Time returns when the frame was captured
+func (f Frame) Time() time.Time {
+	return f.System.Timestamp
+}
+
+// Writer appends frames to a session file
+type Writer struct {
+	file    *os.File
+	buf     *bufio.Writer
+	gz      *gzip.Writer
+	encoder *gob.Encoder
+}
+
+// Create starts a new session file, replacing any existing one
+func Create(path string, header Header) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(file)
+	if _, err := buf.WriteString(fileMagic); err != nil {
+		file.Close()
+		return nil, err
+	}
+	gz := gzip.NewWriter(buf)
+	w := &Writer{file: file, buf: buf, gz: gz, encoder: gob.NewEncoder(gz)}
+
+	header.Version = formatVersion
+	if err := w.encoder.Encode(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write session header: %w", err)
+	}
+	return w, nil
+}
+
+// WriteFrame appends a frame and flushes it, so an interrupted recording
+// keeps everything up to the last frame
+func (w *Writer) WriteFrame(frame Frame) error {
+	if err := w.encoder.Encode(frame); err != nil {
+		return err
+	}
+	if err := w.gz.Flush(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// Close finishes the compressed stream and closes the file
+func (w *Writer) Close() error {
+	err := w.gz.Close()
+	if flushErr := w.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadFile loads a whole session file. A recording that was cut off, e.g. by
+// a crash, yields the frames that were completely written.
+func ReadFile(path string) (Header, []Frame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	magic := make([]byte, len(fileMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != fileMagic {
+		return Header{}, nil, fmt.Errorf("%s: not a pulse session file", path)
+	}
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	decoder := gob.NewDecoder(gz)
+
+	var header Header
+	if err := decoder.Decode(&header); err != nil {
+		return Header{}, nil, fmt.Errorf("%s: failed to read session header: %w", path, err)
+	}
+	if header.Version != formatVersion {
+		return Header{}, nil, fmt.Errorf("%s: unsupported session version %d", path, header.Version)
+	}
+
+	var frames []Frame
+	for {
+		var frame Frame
+		err := decoder.Decode(&frame)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			if len(frames) > 0 {
+				break // Truncated tail
+			}
+			return Header{}, nil, fmt.Errorf("%s: failed to read frame: %w", path, err)
+		}
+		frames = append(frames, frame)
+	}
+
+	return header, frames, nil
+}
@@ -0,0 +1,131 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+// testFrame returns a frame captured n seconds after testStart
+func testFrame(n int) Frame {
+	at := testStart.Add(time.Duration(n) * time.Second)
+	return Frame{
+		System:    monitor.SystemMetrics{Timestamp: at, CPUPercent: float64(n)},
+		Processes: []monitor.ProcessInfo{{PID: 100, Name: "top", CreateTime: testStart, CPUPercent: float64(n)}},
+		Others:    []monitor.ProcessInfo{{PID: 200, PPID: 100, Name: "child", CreateTime: testStart}},
+	}
+}
+
+// writeSession writes frames without closing the writer, like a recording
+// that was interrupted, and returns the file path
+func writeSession(t *testing.T, frames ...Frame) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.pulse")
+	writer, err := Create(path, Header{Hostname: "db1", Started: testStart, Interval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range frames {
+		if err := writer.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { writer.file.Close() })
+	return path
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.pulse")
+	writer, err := Create(path, Header{Hostname: "db1", Started: testStart, Interval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 3; n++ {
+		if err := writer.WriteFrame(testFrame(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header, frames, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != formatVersion || header.Hostname != "db1" || !header.Started.Equal(testStart) || header.Interval != time.Second {
+		t.Errorf("header = %+v", header)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("read %d frames, want 3", len(frames))
+	}
+	for n, frame := range frames {
+		if !frame.Time().Equal(testStart.Add(time.Duration(n) * time.Second)) {
+			t.Errorf("frame %d captured at %v", n, frame.Time())
+		}
+		if len(frame.Processes) != 1 || frame.Processes[0].CPUPercent != float64(n) {
+			t.Errorf("frame %d processes = %+v", n, frame.Processes)
+		}
+		if len(frame.Others) != 1 || frame.Others[0].Name != "child" || frame.Others[0].PPID != 100 {
+			t.Errorf("frame %d others = %+v", n, frame.Others)
+		}
+	}
+}
+
+func TestSessionDamagedTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte { return data[:len(data)-10] }},
+		{"corrupt", func(data []byte) []byte {
+			for i := len(data) - 10; i < len(data); i++ {
+				data[i] ^= 0xff
+			}
+			return data
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intact, err := os.ReadFile(writeSession(t, testFrame(0), testFrame(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The same recording with a third frame whose end gets damaged
+			path := writeSession(t, testFrame(0), testFrame(1), testFrame(2))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) <= len(intact)+10 {
+				t.Fatalf("third frame takes only %d bytes", len(data)-len(intact))
+			}
+			if err := os.WriteFile(path, tt.damage(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, frames, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v, want the intact frames", err)
+			}
+			if len(frames) != 2 {
+				t.Errorf("read %d frames, want the 2 intact ones", len(frames))
+			}
+		})
+	}
+}
+
+func TestSessionRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadFile(path); err == nil {
+		t.Error("ReadFile accepted a file without the session magic")
+	}
+}
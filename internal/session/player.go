@@ -0,0 +1,207 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// playbackSpeeds are the speed multipliers available during replay
+var playbackSpeeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32, 64}
+
+// playerTick is how often the player advances its virtual time
+const playerTick = 100 * time.Millisecond
+
+// PlaybackState describes where a replay currently is
+type PlaybackState struct {
+	Position time.Time
+	Start    time.Time
+	End      time.Time
+	Speed    float64
+	Paused   bool
+}
+
+// Player feeds recorded frames into a Monitor at the pace they were captured,
+// scaled by a speed multiplier. The monitor works as usual, so the UI can't
+// tell a replay from live data.
+type Player struct {
+	mu       sync.Mutex
+	frames   []Frame
+	monitor  *monitor.Monitor
+	clock    *monitor.FakeClock
+	next     int       // Index of the next frame to apply
+	position time.Time // Virtual time of the replay
+	speed    int       // Index into playbackSpeeds
+	paused   bool
+	onUpdate func()
+}
+
+// NewPlayer creates a player positioned at the first of the given frames
+func NewPlayer(frames []Frame) (*Player, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("session contains no frames")
+	}
+	sorted := make([]Frame, len(frames))
+	copy(sorted, frames)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time().Before(sorted[j].Time())
+	})
+
+	clock := monitor.NewFakeClock(sorted[0].Time())
+	p := &Player{
+		frames:  sorted,
+		monitor: monitor.NewSnapshotMonitor(clock),
+		clock:   clock,
+		speed:   2, // 1x
+	}
+	p.seekTo(sorted[0].Time())
+	return p, nil
+}
+
+// Monitor returns the monitor driven by the player
+func (p *Player) Monitor() *monitor.Monitor {
+	return p.monitor
+}
+
+// SetOnUpdate registers a function called after new frames were applied
+func (p *Player) SetOnUpdate(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onUpdate = fn
+}
+
+// Run advances the replay until the context is cancelled
+func (p *Player) Run(ctx context.Context) {
+	ticker := time.NewTicker(playerTick)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(last)
+			last = now
+			p.advance(elapsed)
+		}
+	}
+}
+
+// TogglePause pauses or resumes playback
+func (p *Player) TogglePause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Restart from the beginning when resuming a finished replay
+	if p.paused && !p.position.Before(p.end()) {
+		p.seekTo(p.start())
+	}
+	p.paused = !p.paused
+}
+
+// ChangeSpeed selects the next faster or slower speed multiplier
+func (p *Player) ChangeSpeed(faster bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if faster && p.speed < len(playbackSpeeds)-1 {
+		p.speed++
+	} else if !faster && p.speed > 0 {
+		p.speed--
+	}
+}
+
+// Seek moves the replay forwards or backwards by offset
+func (p *Player) Seek(offset time.Duration) {
+	p.mu.Lock()
+	target := p.position.Add(offset)
+	if target.Before(p.start()) {
+		target = p.start()
+	}
+	if target.After(p.end()) {
+		target = p.end()
+	}
+	p.seekTo(target)
+	onUpdate := p.onUpdate
+	p.mu.Unlock()
+
+	if onUpdate != nil {
+		onUpdate()
+	}
+}
+
+// State returns the current playback position and settings
+func (p *Player) State() PlaybackState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PlaybackState{
+		Position: p.position,
+		Start:    p.start(),
+		End:      p.end(),
+		Speed:    playbackSpeeds[p.speed],
+		Paused:   p.paused,
+	}
+}
+
+// advance moves virtual time forward by the scaled wall time that passed
+func (p *Player) advance(elapsed time.Duration) {
+	p.mu.Lock()
+	if p.paused {
+		p.mu.Unlock()
+		return
+	}
+
+	target := p.position.Add(time.Duration(float64(elapsed) * playbackSpeeds[p.speed]))
+	if !target.Before(p.end()) {
+		target = p.end()
+		p.paused = true
+	}
+	applied := p.applyUntil(target)
+	onUpdate := p.onUpdate
+	p.mu.Unlock()
+
+	if applied && onUpdate != nil {
+		onUpdate()
+	}
+}
+
+// seekTo rebuilds the monitor state at the given time. Seeking backwards
+// replays from the start, since time series can't be rewound.
+// Callers must hold the lock.
+func (p *Player) seekTo(target time.Time) {
+	if target.Before(p.position) || p.next == 0 {
+		p.monitor.Reset()
+		p.next = 0
+	}
+	p.applyUntil(target)
+}
+
+// applyUntil applies all frames captured up to target and reports whether
+// any were applied. Callers must hold the lock.
+func (p *Player) applyUntil(target time.Time) bool {
+	applied := false
+	for p.next < len(p.frames) && !p.frames[p.next].Time().After(target) {
+		frame := p.frames[p.next]
+		p.clock.Set(frame.Time())
+		p.monitor.ApplySnapshot(frame.System, frame.Processes, frame.Others)
+		p.next++
+		applied = true
+	}
+	p.position = target
+	p.clock.Set(target)
+	return applied
+}
+
+func (p *Player) start() time.Time {
+	return p.frames[0].Time()
+}
+
+func (p *Player) end() time.Time {
+	return p.frames[len(p.frames)-1].Time()
+}
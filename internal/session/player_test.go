@@ -0,0 +1,85 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestPlayer(t *testing.T, count int) *Player {
+	t.Helper()
+	frames := make([]Frame, count)
+	for n := range frames {
+		frames[n] = testFrame(n)
+	}
+	player, err := NewPlayer(frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return player
+}
+
+func TestPlayerReplaysOthersInTheTree(t *testing.T) {
+	player := newTestPlayer(t, 1)
+	tree := player.Monitor().GetProcessTree()
+	if tree.Len() != 2 {
+		t.Fatalf("tree has %d processes, want the top one and the other", tree.Len())
+	}
+	parent := tree.Node(testFrame(0).Processes[0].Key())
+	if parent == nil || len(parent.Children) != 1 || parent.Children[0].Process.PID != 200 {
+		t.Errorf("PID 200 is not listed below its parent PID 100")
+	}
+}
+
+func TestPlayerSeekBackwards(t *testing.T) {
+	player := newTestPlayer(t, 5)
+	player.Seek(3 * time.Second)
+	if got := player.Monitor().GetSystemMetrics().CPUPercent; got != 3 {
+		t.Fatalf("CPU after seeking to frame 3 = %v, want 3", got)
+	}
+
+	player.Seek(-2 * time.Second)
+	state := player.State()
+	if !state.Position.Equal(testStart.Add(time.Second)) {
+		t.Errorf("position = %v, want one second after the start", state.Position)
+	}
+	if got := player.Monitor().GetSystemMetrics().CPUPercent; got != 1 {
+		t.Errorf("CPU after seeking back = %v, want 1 from frame 1", got)
+	}
+	// Replayed from the start, so the time series holds frames 0 and 1 only
+	key := testFrame(0).Processes[0].Key()
+	if got := len(player.Monitor().GetProcessMetrics(key)); got != 2 {
+		t.Errorf("time series has %d samples, want 2", got)
+	}
+
+	player.Seek(-time.Hour)
+	if !player.State().Position.Equal(testStart) {
+		t.Errorf("seeking before the start left the position at %v", player.State().Position)
+	}
+}
+
+func TestPlayerPausesAtTheEnd(t *testing.T) {
+	player := newTestPlayer(t, 3)
+	player.advance(time.Hour)
+
+	state := player.State()
+	if !state.Paused || !state.Position.Equal(state.End) {
+		t.Fatalf("state after the end = %+v, want paused at the end", state)
+	}
+	if got := player.Monitor().GetSystemMetrics().CPUPercent; got != 2 {
+		t.Errorf("CPU at the end = %v, want 2 from the last frame", got)
+	}
+
+	// Resuming a finished replay starts over
+	player.TogglePause()
+	state = player.State()
+	if state.Paused || !state.Position.Equal(state.Start) {
+		t.Errorf("state after resuming = %+v, want playing from the start", state)
+	}
+	if got := player.Monitor().GetSystemMetrics().CPUPercent; got != 0 {
+		t.Errorf("CPU after resuming = %v, want 0 from the first frame", got)
+	}
+	player.advance(time.Second)
+	if got := player.Monitor().GetSystemMetrics().CPUPercent; got != 1 {
+		t.Errorf("CPU one second after resuming = %v, want 1", got)
+	}
+}
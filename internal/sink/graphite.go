@@ -0,0 +1,111 @@
+package sink
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// graphiteTimeout bounds connecting to and writing to the Carbon server
+const graphiteTimeout = 5 * time.Second
+
+// graphiteWriter sends the Graphite plaintext protocol over TCP. Metric
+// paths are prefix.host.measurement[.tag values...].field, e.g.
+// servers.web1.process.nginx.1234.cpu_percent.
+type graphiteWriter struct {
+	address string
+	prefix  string
+	conn    net.Conn // Nil until connected or after an error
+}
+
+func newGraphiteWriter(address, prefix string) (*graphiteWriter, error) {
+	if address == "" {
+		return nil, errors.New("missing host:port")
+	}
+	return &graphiteWriter{address: address, prefix: strings.Trim(prefix, ".")}, nil
+}
+
+// Write sends the points, reconnecting if the previous connection broke
+func (w *graphiteWriter) Write(points []Point) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout("tcp", w.address, graphiteTimeout)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(graphiteTimeout)); err != nil {
+		return w.reset(err)
+	}
+	if _, err := w.conn.Write(appendGraphite(nil, w.prefix, points)); err != nil {
+		return w.reset(err)
+	}
+	return nil
+}
+
+// reset drops a broken connection
+func (w *graphiteWriter) reset(err error) error {
+	w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *graphiteWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
+
+// appendGraphite formats points as "path value timestamp" lines
+func appendGraphite(buf []byte, prefix string, points []Point) []byte {
+	for _, point := range points {
+		var path strings.Builder
+		if prefix != "" {
+			path.WriteString(prefix)
+			path.WriteByte('.')
+		}
+		// The host tag comes first, before the measurement
+		for _, tag := range point.Tags {
+			if tag.Key == "host" {
+				path.WriteString(graphiteNode(tag.Value))
+				path.WriteByte('.')
+			}
+		}
+		path.WriteString(graphiteNode(point.Measurement))
+		for _, tag := range point.Tags {
+			if tag.Key != "host" && tag.Key != "user" {
+				path.WriteByte('.')
+				path.WriteString(graphiteNode(tag.Value))
+			}
+		}
+
+		for _, field := range point.Fields {
+			buf = append(buf, path.String()...)
+			buf = append(buf, '.')
+			buf = append(buf, graphiteNode(field.Key)...)
+			buf = append(buf, ' ')
+			buf = strconv.AppendFloat(buf, field.Value, 'f', -1, 64)
+			buf = append(buf, ' ')
+			buf = strconv.AppendInt(buf, point.Time.Unix(), 10)
+			buf = append(buf, '\n')
+		}
+	}
+	return buf
+}
+
+// graphiteNode makes a value usable as one node of a metric path
+func graphiteNode(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, value)
+}
@@ -0,0 +1,48 @@
+package sink
+
+import "testing"
+
+func TestGraphitePaths(t *testing.T) {
+	points := []Point{
+		{
+			Measurement: "process",
+			Tags:        []Tag{{"host", "web1.example.com"}, {"name", "php-fpm: pool www"}, {"pid", "1234"}, {"user", "www-data"}},
+			Fields:      []Field{{"cpu_percent", 12.5}, {"memory_mb", 64}},
+			Time:        testStart,
+		},
+		{
+			Measurement: "disk",
+			Tags:        []Tag{{"host", ""}, {"device", "nvme0n1"}},
+			Fields:      []Field{{"read_kbps", 0.25}},
+			Time:        testStart,
+		},
+	}
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"", `web1_example_com.process.php-fpm__pool_www.1234.cpu_percent 12.5 1792141200
+web1_example_com.process.php-fpm__pool_www.1234.memory_mb 64 1792141200
+unknown.disk.nvme0n1.read_kbps 0.25 1792141200
+`},
+		{"servers.prod", `servers.prod.web1_example_com.process.php-fpm__pool_www.1234.cpu_percent 12.5 1792141200
+servers.prod.web1_example_com.process.php-fpm__pool_www.1234.memory_mb 64 1792141200
+servers.prod.unknown.disk.nvme0n1.read_kbps 0.25 1792141200
+`},
+	}
+	for _, tt := range tests {
+		if got := string(appendGraphite(nil, tt.prefix, points)); got != tt.want {
+			t.Errorf("prefix %q:\n%s\nwant:\n%s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestGraphitePrefixIsTrimmed(t *testing.T) {
+	writer, err := newGraphiteWriter("localhost:2003", ".servers.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.prefix != "servers" {
+		t.Errorf("prefix = %q, want servers", writer.prefix)
+	}
+}
@@ -0,0 +1,162 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDatagram keeps UDP packets below a typical MTU
+const maxDatagram = 1400
+
+// appendLineProtocol formats points in the InfluxDB line protocol with
+// nanosecond timestamps
+func appendLineProtocol(buf []byte, points []Point) []byte {
+	for _, point := range points {
+		buf = append(buf, measurementEscaper.Replace(point.Measurement)...)
+		for _, tag := range point.Tags {
+			if tag.Value == "" {
+				continue // Empty tag values are rejected by InfluxDB
+			}
+			buf = append(buf, ',')
+			buf = append(buf, keyEscaper.Replace(tag.Key)...)
+			buf = append(buf, '=')
+			buf = append(buf, keyEscaper.Replace(tag.Value)...)
+		}
+		for i, field := range point.Fields {
+			if i == 0 {
+				buf = append(buf, ' ')
+			} else {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, keyEscaper.Replace(field.Key)...)
+			buf = append(buf, '=')
+			buf = strconv.AppendFloat(buf, field.Value, 'g', -1, 64)
+		}
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, point.Time.UnixNano(), 10)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// Escaping rules of the line protocol
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+)
+
+// influxFileWriter appends line protocol to a file
+type influxFileWriter struct {
+	file *os.File
+}
+
+func newInfluxFileWriter(path string) (*influxFileWriter, error) {
+	if path == "" {
+		return nil, errors.New("missing file path")
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &influxFileWriter{file: file}, nil
+}
+
+func (w *influxFileWriter) Write(points []Point) error {
+	_, err := w.file.Write(appendLineProtocol(nil, points))
+	return err
+}
+
+func (w *influxFileWriter) Close() error {
+	return w.file.Close()
+}
+
+// influxUDPWriter sends line protocol to InfluxDB's UDP listener or Telegraf
+type influxUDPWriter struct {
+	conn net.Conn
+}
+
+func newInfluxUDPWriter(address string) (*influxUDPWriter, error) {
+	if address == "" {
+		return nil, errors.New("missing host:port")
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &influxUDPWriter{conn: conn}, nil
+}
+
+// Write sends as many whole lines per datagram as fit
+func (w *influxUDPWriter) Write(points []Point) error {
+	var packet []byte
+	for i := range points {
+		line := appendLineProtocol(nil, points[i:i+1])
+		if len(packet) > 0 && len(packet)+len(line) > maxDatagram {
+			if _, err := w.conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		_, err := w.conn.Write(packet)
+		return err
+	}
+	return nil
+}
+
+func (w *influxUDPWriter) Close() error {
+	return w.conn.Close()
+}
+
+// influxHTTPWriter posts line protocol to the write endpoint of InfluxDB 1.x
+// (/write?db=...) or 2.x (/api/v2/write?org=...&bucket=...)
+type influxHTTPWriter struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newInfluxHTTPWriter(url, token string) (*influxHTTPWriter, error) {
+	return &influxHTTPWriter{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (w *influxHTTPWriter) Write(points []Point) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(appendLineProtocol(nil, points)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+func (w *influxHTTPWriter) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
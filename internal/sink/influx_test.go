@@ -0,0 +1,22 @@
+package sink
+
+import (
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+func TestLineProtocolEscaping(t *testing.T) {
+	points := []Point{{
+		Measurement: "process list",
+		Tags:        []Tag{{"host", "db1"}, {"name", "my worker,v=2"}, {"user", ""}},
+		Fields:      []Field{{"cpu_percent", 12.5}, {"memory mb", 1e9}},
+		Time:        testStart,
+	}}
+	got := string(appendLineProtocol(nil, points))
+	want := `process\ list,host=db1,name=my\ worker\,v\=2 cpu_percent=12.5,memory\ mb=1e+09 1792141200000000000` + "\n"
+	if got != want {
+		t.Errorf("line protocol:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+// Package sink pushes monitor updates to legacy metrics backends such as
+// InfluxDB and Graphite.
+package sink
+
+import (
+	"strconv"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Point is one measurement with its tags and numeric fields
+type Point struct {
+	Measurement string
+	Tags        []Tag
+	Fields      []Field
+	Time        time.Time
+}
+
+// Tag identifies the series of a point
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Field is one value of a point
+type Field struct {
+	Key   string
+	Value float64
+}
+
+// Points converts a monitor update into system, disk and process points.
+// Process CPU usage is per-core, like in the history and the exporters.
+func Points(update monitor.Update, hostname string) []Point {
+	system := update.System
+	at := system.Timestamp
+	host := Tag{"host", hostname}
+
+	points := make([]Point, 0, len(update.Processes)+len(system.Disks)+1)
+	points = append(points, Point{
+		Measurement: "system",
+		Tags:        []Tag{host},
+		Fields: []Field{
+			{"cpu_percent", system.CPUPercent},
+			{"memory_percent", system.MemoryPercent},
+			{"memory_used_mb", system.UsedMemoryMB},
+			{"memory_total_mb", system.TotalMemoryMB},
+			{"disk_read_kbps", system.DiskReadRate},
+			{"disk_write_kbps", system.DiskWriteRate},
+		},
+		Time: at,
+	})
+
+	for _, disk := range system.Disks {
+		points = append(points, Point{
+			Measurement: "disk",
+			Tags:        []Tag{host, {"device", disk.Name}},
+			Fields: []Field{
+				{"read_kbps", disk.ReadRate},
+				{"write_kbps", disk.WriteRate},
+				{"read_iops", disk.ReadIOPS},
+				{"write_iops", disk.WriteIOPS},
+				{"busy_percent", disk.BusyPercent},
+			},
+			Time: at,
+		})
+	}
+
+	for _, proc := range update.Processes {
+		tags := []Tag{host, {"name", proc.Name}, {"pid", strconv.Itoa(int(proc.PID))}}
+		if proc.Username != "" {
+			tags = append(tags, Tag{"user", proc.Username})
+		}
+		points = append(points, Point{
+			Measurement: "process",
+			Tags:        tags,
+			Fields: []Field{
+				{"cpu_percent", update.CPUMode.ToPerCore(proc.CPUPercent, system.NumCPU)},
+				{"memory_mb", proc.MemoryMB},
+				{"memory_percent", float64(proc.MemoryPerc)},
+				{"disk_read_kbps", proc.DiskReadRate},
+				{"disk_write_kbps", proc.DiskWriteRate},
+				{"net_sent_kbps", proc.NetSentRate},
+				{"net_recv_kbps", proc.NetRecvRate},
+				{"ctx_switches_ps", proc.CtxSwitchRate},
+				{"minor_faults_ps", proc.MinorFaultPS},
+				{"major_faults_ps", proc.MajorFaultPS},
+			},
+			Time: at,
+		})
+	}
+
+	return points
+}
+
+// filterFields keeps only allowed fields, given as "field" for every
+// measurement or "measurement.field". Points without fields are dropped.
+func filterFields(points []Point, allowed map[string]bool) []Point {
+	if len(allowed) == 0 {
+		return points
+	}
+
+	filtered := make([]Point, 0, len(points))
+	for _, point := range points {
+		fields := make([]Field, 0, len(point.Fields))
+		for _, field := range point.Fields {
+			if allowed[field.Key] || allowed[point.Measurement+"."+field.Key] {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			point.Fields = fields
+			filtered = append(filtered, point)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,207 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Defaults for sink options not given in the spec
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultBuffer   = 60 // Batches kept while the backend is unavailable
+)
+
+// Writer delivers batches of points to a backend
+type Writer interface {
+	Write(points []Point) error
+	Close() error
+}
+
+// Sink periodically writes the latest monitor update to a Writer. Offer only
+// stores the update, all formatting and I/O happens on the sink's own
+// goroutine, so a slow backend never holds up the monitor or the UI.
+type Sink struct {
+	name     string
+	writer   Writer
+	interval time.Duration
+	fields   map[string]bool // Allowed fields, empty for all
+	buffer   int
+	hostname string
+
+	mu      sync.Mutex
+	latest  *monitor.Update
+	pending [][]Point // Batches not yet written, oldest first
+	dropped int       // Batches discarded because the buffer was full
+	failing bool      // Whether the last write failed, to log only state changes
+	logf    func(format string, args ...any)
+}
+
+// Parse creates a sink from a spec like
+//
+//	influx+file:///var/log/pulse.lp?interval=30s
+//	influx+udp://localhost:8089
+//	influx+http://localhost:8086/api/v2/write?org=ops&bucket=pulse&token=...
+//	graphite://localhost:2003?prefix=servers&fields=cpu_percent,memory_mb
+//
+// The options interval, fields and buffer apply to every kind of sink.
+func Parse(spec string) (*Sink, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", spec, err)
+	}
+
+	query := u.Query()
+	option := func(name string) string {
+		value := query.Get(name)
+		query.Del(name)
+		return value
+	}
+
+	hostname, _ := os.Hostname()
+	s := &Sink{
+		name:     u.Scheme,
+		interval: DefaultInterval,
+		buffer:   DefaultBuffer,
+		hostname: hostname,
+		logf:     logToStderr,
+	}
+	if value := option("interval"); value != "" {
+		if s.interval, err = time.ParseDuration(value); err != nil || s.interval <= 0 {
+			return nil, fmt.Errorf("sink %s: invalid interval %q", u.Scheme, value)
+		}
+	}
+	if value := option("buffer"); value != "" {
+		if s.buffer, err = strconv.Atoi(value); err != nil || s.buffer < 1 {
+			return nil, fmt.Errorf("sink %s: invalid buffer %q", u.Scheme, value)
+		}
+	}
+	if value := option("fields"); value != "" {
+		s.fields = make(map[string]bool)
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				s.fields[field] = true
+			}
+		}
+	}
+
+	switch u.Scheme {
+	case "influx+file":
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque // Relative path, e.g. influx+file:pulse.lp
+		}
+		s.writer, err = newInfluxFileWriter(path)
+	case "influx+udp":
+		s.writer, err = newInfluxUDPWriter(u.Host)
+	case "influx+http", "influx+https":
+		token := option("token")
+		u.Scheme = strings.TrimPrefix(u.Scheme, "influx+")
+		u.RawQuery = query.Encode()
+		s.writer, err = newInfluxHTTPWriter(u.String(), token)
+	case "graphite":
+		s.writer, err = newGraphiteWriter(u.Host, option("prefix"))
+	default:
+		return nil, fmt.Errorf("unknown sink %q (available: influx+file, influx+udp, influx+http, influx+https, graphite)", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sink %s: %w", s.name, err)
+	}
+	return s, nil
+}
+
+// SetLogf sets where write problems are reported, stderr by default.
+// Call it before Run.
+func (s *Sink) SetLogf(logf func(format string, args ...any)) {
+	s.logf = logf
+}
+
+// logToStderr is the default log function of the sinks
+func logToStderr(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// Offer hands the sink a new update; only the latest one is written at the next interval
+func (s *Sink) Offer(update monitor.Update) {
+	s.mu.Lock()
+	s.latest = &update
+	s.mu.Unlock()
+}
+
+// Run writes updates every interval until the context is cancelled, then
+// makes a last attempt to write what is buffered and closes the writer
+func (s *Sink) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.collect()
+			s.flush()
+			if err := s.Close(); err != nil {
+				s.logf("Sink %s: %v", s.name, err)
+			}
+			return
+		case <-ticker.C:
+			s.collect()
+			s.flush()
+		}
+	}
+}
+
+// Close releases the connection or file of the sink. Run closes it when done.
+func (s *Sink) Close() error {
+	return s.writer.Close()
+}
+
+// collect turns the latest update into a pending batch, dropping the oldest
+// batch when the buffer is full
+func (s *Sink) collect() {
+	s.mu.Lock()
+	update := s.latest
+	s.latest = nil
+	s.mu.Unlock()
+
+	if update == nil || update.System.Timestamp.IsZero() {
+		return
+	}
+	points := filterFields(Points(*update, s.hostname), s.fields)
+	if len(points) == 0 {
+		return
+	}
+
+	if len(s.pending) >= s.buffer {
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+	s.pending = append(s.pending, points)
+}
+
+// flush writes pending batches oldest first, stopping at the first failure
+func (s *Sink) flush() {
+	for len(s.pending) > 0 {
+		if err := s.writer.Write(s.pending[0]); err != nil {
+			if !s.failing {
+				s.logf("Sink %s: %v (buffering up to %d batches)", s.name, err, s.buffer)
+				s.failing = true
+			}
+			return
+		}
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+	}
+
+	if s.failing {
+		s.logf("Sink %s: recovered, %d batches were dropped", s.name, s.dropped)
+		s.failing = false
+		s.dropped = 0
+	}
+}
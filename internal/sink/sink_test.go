@@ -0,0 +1,99 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// recordingWriter keeps the batches written to it and fails while failing is set
+type recordingWriter struct {
+	batches [][]Point
+	failing bool
+}
+
+func (w *recordingWriter) Write(points []Point) error {
+	if w.failing {
+		return errors.New("connection refused")
+	}
+	w.batches = append(w.batches, points)
+	return nil
+}
+
+func (w *recordingWriter) Close() error { return nil }
+
+func testUpdate(at time.Time) monitor.Update {
+	return monitor.Update{
+		System:    monitor.SystemMetrics{Timestamp: at, NumCPU: 2, CPUPercent: 10},
+		Processes: []monitor.ProcessInfo{{PID: 100, Name: "worker", CPUPercent: 50, MemoryMB: 64}},
+	}
+}
+
+func TestFieldsOption(t *testing.T) {
+	s, err := Parse("influx+file://" + filepath.Join(t.TempDir(), "pulse.lp") + "?fields=cpu_percent,process.memory_mb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	points := filterFields(Points(testUpdate(testStart), "db1"), s.fields)
+	var got []string
+	for _, point := range points {
+		for _, field := range point.Fields {
+			got = append(got, point.Measurement+"."+field.Key)
+		}
+	}
+	// No disk point is left, since none of its fields was selected
+	want := "system.cpu_percent process.cpu_percent process.memory_mb"
+	if strings.Join(got, " ") != want {
+		t.Errorf("fields = %v, want %s", got, want)
+	}
+}
+
+func TestBufferWhileBackendFails(t *testing.T) {
+	writer := &recordingWriter{failing: true}
+	var logged []string
+	s := &Sink{name: "graphite", writer: writer, buffer: 2, hostname: "db1", logf: func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}}
+
+	for i := 0; i < 4; i++ {
+		s.Offer(testUpdate(testStart.Add(time.Duration(i) * time.Second)))
+		s.collect()
+		s.flush()
+	}
+	if len(s.pending) != 2 || s.dropped != 2 {
+		t.Fatalf("%d batches pending, %d dropped; want 2 and 2", len(s.pending), s.dropped)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "connection refused") {
+		t.Errorf("logged %q, want the failure reported once", logged)
+	}
+
+	// The two newest batches are delivered oldest first
+	writer.failing = false
+	s.collect() // Nothing new was offered
+	s.flush()
+	if len(writer.batches) != 2 {
+		t.Fatalf("wrote %d batches, want 2", len(writer.batches))
+	}
+	for i, batch := range writer.batches {
+		if want := testStart.Add(time.Duration(i+2) * time.Second); !batch[0].Time.Equal(want) {
+			t.Errorf("batch %d is from %v, want %v", i, batch[0].Time, want)
+		}
+	}
+	if len(logged) != 2 || logged[1] != "Sink graphite: recovered, 2 batches were dropped" {
+		t.Errorf("logged %q, want one recovery line", logged)
+	}
+
+	s.Offer(testUpdate(testStart.Add(time.Minute)))
+	s.collect()
+	s.flush()
+	if len(logged) != 2 {
+		t.Errorf("logged %q after another successful write", logged[2:])
+	}
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// Record kinds stored in a block
+const (
+	kindSystem  byte = 1
+	kindProcess byte = 2
+)
+
+// errCorruptRecord is returned when a block payload can't be decoded
+var errCorruptRecord = errors.New("corrupt history record")
+
+// SystemRecord is one stored system-wide sample
+type SystemRecord struct {
+	Timestamp     time.Time
+	CPUPercent    float64
+	MemoryPercent float64
+	UsedMemoryMB  float64
+	TotalMemoryMB float64
+	DiskReadRate  float64 // KB/s
+	DiskWriteRate float64 // KB/s
+}
+
+// ProcessRecord is one stored process sample. CPU usage is always per-core.
+type ProcessRecord struct {
+	Key    monitor.ProcessKey
+	Name   string
+	Sample monitor.MetricSample
+}
+
+// Snapshot is everything recorded at one point in time
+type Snapshot struct {
+	System    SystemRecord
+	Processes []ProcessRecord
+}
+
+// record is a decoded entry of either kind
+type record struct {
+	kind    byte
+	system  SystemRecord
+	process ProcessRecord
+}
+
+// encoder appends records to an uncompressed block payload. Timestamps are
+// stored as millisecond deltas from the previous record; the first record of
+// a block is relative to the epoch.
+type encoder struct {
+	buf  []byte
+	last int64
+}
+
+func (e *encoder) appendFloat(value float64) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(float32(value)))
+}
+
+func (e *encoder) appendTime(at time.Time) {
+	ms := at.UnixMilli()
+	e.buf = binary.AppendVarint(e.buf, ms-e.last)
+	e.last = ms
+}
+
+func (e *encoder) appendSystem(rec SystemRecord) {
+	e.buf = append(e.buf, kindSystem)
+	e.appendTime(rec.Timestamp)
+	e.appendFloat(rec.CPUPercent)
+	e.appendFloat(rec.MemoryPercent)
+	e.appendFloat(rec.UsedMemoryMB)
+	e.appendFloat(rec.TotalMemoryMB)
+	e.appendFloat(rec.DiskReadRate)
+	e.appendFloat(rec.DiskWriteRate)
+}
+
+func (e *encoder) appendProcess(rec ProcessRecord) {
+	e.buf = append(e.buf, kindProcess)
+	e.appendTime(rec.Sample.Timestamp)
+	e.buf = binary.AppendUvarint(e.buf, uint64(rec.Key.PID))
+	e.buf = binary.AppendVarint(e.buf, rec.Key.StartTime)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(rec.Name)))
+	e.buf = append(e.buf, rec.Name...)
+	e.appendFloat(rec.Sample.CPUPercent)
+	e.appendFloat(rec.Sample.MemoryMB)
+	e.appendFloat(rec.Sample.DiskReadRate)
+	e.appendFloat(rec.Sample.DiskWriteRate)
+	e.appendFloat(rec.Sample.DiskReadPerc)
+	e.appendFloat(rec.Sample.DiskWritePerc)
+	e.appendFloat(rec.Sample.NetSentRate)
+	e.appendFloat(rec.Sample.NetRecvRate)
+}
+
+// decoder reads records written by encoder
+type decoder struct {
+	buf  []byte
+	last int64
+	err  error
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil || len(d.buf) < 4 {
+		d.err = errCorruptRecord
+		return 0
+	}
+	value := math.Float32frombits(binary.LittleEndian.Uint32(d.buf))
+	d.buf = d.buf[4:]
+	return float64(value)
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errCorruptRecord
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return value
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errCorruptRecord
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return value
+}
+
+func (d *decoder) time() time.Time {
+	d.last += d.varint()
+	return time.UnixMilli(d.last)
+}
+
+// next decodes the following record; ok is false at the end of the payload
+func (d *decoder) next() (rec record, ok bool, err error) {
+	if len(d.buf) == 0 {
+		return record{}, false, nil
+	}
+	rec.kind = d.buf[0]
+	d.buf = d.buf[1:]
+
+	switch rec.kind {
+	case kindSystem:
+		rec.system = SystemRecord{
+			Timestamp:     d.time(),
+			CPUPercent:    d.float(),
+			MemoryPercent: d.float(),
+			UsedMemoryMB:  d.float(),
+			TotalMemoryMB: d.float(),
+			DiskReadRate:  d.float(),
+			DiskWriteRate: d.float(),
+		}
+	case kindProcess:
+		at := d.time()
+		rec.process.Key.PID = int32(d.uvarint())
+		rec.process.Key.StartTime = d.varint()
+		nameLen := d.uvarint()
+		if d.err == nil && nameLen > uint64(len(d.buf)) {
+			d.err = errCorruptRecord
+		}
+		if d.err == nil {
+			rec.process.Name = string(d.buf[:nameLen])
+			d.buf = d.buf[nameLen:]
+		}
+		rec.process.Sample = monitor.MetricSample{
+			Timestamp:     at,
+			CPUPercent:    d.float(),
+			MemoryMB:      d.float(),
+			DiskReadRate:  d.float(),
+			DiskWriteRate: d.float(),
+			DiskReadPerc:  d.float(),
+			DiskWritePerc: d.float(),
+			NetSentRate:   d.float(),
+			NetRecvRate:   d.float(),
+		}
+	default:
+		return record{}, false, errCorruptRecord
+	}
+
+	if d.err != nil {
+		return record{}, false, d.err
+	}
+	return rec, true, nil
+}
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// On-disk layout of a segment file:
+//
+//	file header:  8 bytes  segmentMagic
+//	block header: 32 bytes magic, payload length, CRC-32C, record count,
+//	              first and last timestamp (ms)
+//	block payload: DEFLATE-compressed records
+//
+// The CRC covers the header fields after it and the payload, so a block
+// that was only partly written before a crash is detected and dropped.
+const (
+	segmentMagic    = "PULSEHS1"
+	segmentExt      = ".seg"
+	blockMagic      = 0x4b4c4250 // "PBLK"
+	blockHeaderSize = 32
+	maxBlockPayload = 16 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errBadBlock marks the end of the readable part of a segment
+var errBadBlock = errors.New("invalid history block")
+
+// blockRef locates a block inside a segment
+type blockRef struct {
+	offset int64
+	length uint32 // Payload length
+	count  uint32
+	minTs  int64
+	maxTs  int64
+}
+
+// segment is one append-only history file
+type segment struct {
+	path   string
+	start  time.Time // Creation time, encoded in the file name
+	size   int64     // Bytes of valid data
+	blocks []blockRef
+	minTs  int64
+	maxTs  int64
+}
+
+// segmentName returns the file name of a segment created at start
+func segmentName(start time.Time) string {
+	return fmt.Sprintf("%020d%s", start.UnixMilli(), segmentExt)
+}
+
+// parseSegmentName extracts the creation time from a segment file name
+func parseSegmentName(name string) (time.Time, bool) {
+	if !strings.HasSuffix(name, segmentExt) {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// addBlock records a block that was written or found while scanning
+func (seg *segment) addBlock(ref blockRef) {
+	if len(seg.blocks) == 0 || ref.minTs < seg.minTs {
+		seg.minTs = ref.minTs
+	}
+	if len(seg.blocks) == 0 || ref.maxTs > seg.maxTs {
+		seg.maxTs = ref.maxTs
+	}
+	seg.blocks = append(seg.blocks, ref)
+	seg.size = ref.offset + blockHeaderSize + int64(ref.length)
+}
+
+// scanSegment reads the block headers of a segment and verifies each block.
+// When repair is set, a damaged or partial tail is truncated so appends can
+// continue from a clean state.
+func scanSegment(path string, start time.Time, repair bool) (*segment, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	seg := &segment{path: path, start: start}
+
+	magic := make([]byte, len(segmentMagic))
+	if _, err := io.ReadFull(file, magic); err != nil || string(magic) != segmentMagic {
+		// Not even the header made it to disk; start the file over
+		if repair {
+			if err := rewriteSegmentHeader(file); err != nil {
+				return nil, err
+			}
+			seg.size = int64(len(segmentMagic))
+			return seg, nil
+		}
+		return nil, fmt.Errorf("%s: not a history segment", path)
+	}
+
+	offset := int64(len(segmentMagic))
+	for {
+		ref, _, err := readBlock(file, offset)
+		if err != nil {
+			break
+		}
+		seg.addBlock(ref)
+		offset = seg.size
+	}
+	seg.size = offset
+
+	if repair {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > seg.size {
+			if err := file.Truncate(seg.size); err != nil {
+				return nil, err
+			}
+			if err := file.Sync(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return seg, nil
+}
+
+// rewriteSegmentHeader resets a file to an empty segment
+func rewriteSegmentHeader(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt([]byte(segmentMagic), 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// readBlock reads and verifies the block at offset, returning its reference
+// and compressed payload
+func readBlock(r io.ReaderAt, offset int64) (blockRef, []byte, error) {
+	header := make([]byte, blockHeaderSize)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return blockRef{}, nil, errBadBlock
+	}
+	if binary.LittleEndian.Uint32(header[0:]) != blockMagic {
+		return blockRef{}, nil, errBadBlock
+	}
+
+	ref := blockRef{
+		offset: offset,
+		length: binary.LittleEndian.Uint32(header[4:]),
+		count:  binary.LittleEndian.Uint32(header[12:]),
+		minTs:  int64(binary.LittleEndian.Uint64(header[16:])),
+		maxTs:  int64(binary.LittleEndian.Uint64(header[24:])),
+	}
+	if ref.length > maxBlockPayload {
+		return blockRef{}, nil, errBadBlock
+	}
+
+	payload := make([]byte, ref.length)
+	if _, err := r.ReadAt(payload, offset+blockHeaderSize); err != nil {
+		return blockRef{}, nil, errBadBlock
+	}
+
+	crc := crc32.Update(crc32.Checksum(header[12:], crcTable), crcTable, payload)
+	if crc != binary.LittleEndian.Uint32(header[8:]) {
+		return blockRef{}, nil, errBadBlock
+	}
+
+	return ref, payload, nil
+}
+
+// encodeBlock compresses a record payload and prepends the block header
+func encodeBlock(records []byte, count uint32, minTs, maxTs int64) ([]byte, error) {
+	var compressed bytes.Buffer
+	compressed.Write(make([]byte, blockHeaderSize))
+
+	writer, err := flate.NewWriter(&compressed, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(records); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	block := compressed.Bytes()
+	payloadLen := len(block) - blockHeaderSize
+	if payloadLen > maxBlockPayload {
+		return nil, fmt.Errorf("history block too large: %d bytes", payloadLen)
+	}
+
+	binary.LittleEndian.PutUint32(block[0:], blockMagic)
+	binary.LittleEndian.PutUint32(block[4:], uint32(payloadLen))
+	binary.LittleEndian.PutUint32(block[12:], count)
+	binary.LittleEndian.PutUint64(block[16:], uint64(minTs))
+	binary.LittleEndian.PutUint64(block[24:], uint64(maxTs))
+	binary.LittleEndian.PutUint32(block[8:], crc32.Checksum(block[12:], crcTable))
+
+	return block, nil
+}
+
+// decodePayload decompresses a block payload into raw records
+func decodePayload(payload []byte) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(payload))
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// eachRecord decodes raw records, stopping early when fn returns false
+func eachRecord(records []byte, fn func(record) bool) error {
+	dec := decoder{buf: records}
+	for {
+		rec, ok, err := dec.next()
+		if err != nil || !ok {
+			return err
+		}
+		if !fn(rec) {
+			return nil
+		}
+	}
+}
+
+// listSegments returns the segment files in dir, oldest first
+func listSegments(dir string) ([]*segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]*segment, 0, len(entries))
+	for _, entry := range entries {
+		start, ok := parseSegmentName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		segments = append(segments, &segment{path: filepath.Join(dir, entry.Name()), start: start})
+	}
+	// File names are zero-padded start times, and ReadDir sorts by name
+	return segments, nil
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+var testStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+func testProcess(pid int32, at time.Time, cpu float64) ProcessRecord {
+	return ProcessRecord{
+		Key:    monitor.NewProcessKey(pid, testStart),
+		Name:   "worker",
+		Sample: monitor.MetricSample{Timestamp: at, CPUPercent: cpu, MemoryMB: 64, DiskReadRate: 2.5, NetRecvRate: 0.5},
+	}
+}
+
+// testBlock encodes records into a block
+func testBlock(t *testing.T, snapshot Snapshot) []byte {
+	t.Helper()
+	var enc encoder
+	minTs, maxTs := snapshot.System.Timestamp.UnixMilli(), snapshot.System.Timestamp.UnixMilli()
+	enc.appendSystem(snapshot.System)
+	for _, proc := range snapshot.Processes {
+		enc.appendProcess(proc)
+		minTs = min(minTs, proc.Sample.Timestamp.UnixMilli())
+		maxTs = max(maxTs, proc.Sample.Timestamp.UnixMilli())
+	}
+	block, err := encodeBlock(enc.buf, uint32(1+len(snapshot.Processes)), minTs, maxTs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+// writeSegment writes a segment file holding one block per snapshot
+func writeSegment(t *testing.T, dir string, start time.Time, snapshots ...Snapshot) string {
+	t.Helper()
+	data := []byte(segmentMagic)
+	for _, snapshot := range snapshots {
+		data = append(data, testBlock(t, snapshot)...)
+	}
+	path := filepath.Join(dir, segmentName(start))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testSnapshot(at time.Time) Snapshot {
+	return Snapshot{
+		System:    SystemRecord{Timestamp: at, CPUPercent: 50, MemoryPercent: 25, UsedMemoryMB: 2048, TotalMemoryMB: 8192, DiskReadRate: 1.5},
+		Processes: []ProcessRecord{testProcess(100, at, 12.5), testProcess(200, at.Add(time.Millisecond), 0.25)},
+	}
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	snapshot := testSnapshot(testStart)
+	path := writeSegment(t, t.TempDir(), testStart, snapshot)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	ref, payload, err := readBlock(file, int64(len(segmentMagic)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.count != 3 || ref.minTs != testStart.UnixMilli() || ref.maxTs != testStart.UnixMilli()+1 {
+		t.Errorf("block header = %+v", ref)
+	}
+	records, err := decodePayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []record
+	if err := eachRecord(records, func(rec record) bool {
+		got = append(got, rec)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("decoded %d records, want 3", len(got))
+	}
+	system := got[0].system
+	if system.Timestamp.Equal(testStart) {
+		system.Timestamp = testStart // Decoded in the local time zone
+	}
+	if got[0].kind != kindSystem || system != snapshot.System {
+		t.Errorf("system record = %+v, want %+v", got[0].system, snapshot.System)
+	}
+	for i, want := range snapshot.Processes {
+		rec := got[i+1]
+		if rec.kind != kindProcess || rec.process.Key != want.Key || rec.process.Name != want.Name {
+			t.Errorf("process record %d = %+v, want %+v", i, rec.process, want)
+		}
+		if !rec.process.Sample.Timestamp.Equal(want.Sample.Timestamp) || rec.process.Sample.CPUPercent != want.Sample.CPUPercent ||
+			rec.process.Sample.DiskReadRate != want.Sample.DiskReadRate || rec.process.Sample.NetRecvRate != want.Sample.NetRecvRate {
+			t.Errorf("process sample %d = %+v, want %+v", i, rec.process.Sample, want.Sample)
+		}
+	}
+}
+
+func TestScanSegmentDropsDamagedTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(data []byte, tail int) []byte
+	}{
+		{"crc mismatch", func(data []byte, tail int) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		}},
+		{"truncated", func(data []byte, tail int) []byte {
+			return data[:len(data)-tail/2]
+		}},
+		{"torn header", func(data []byte, tail int) []byte {
+			return data[:len(data)-tail+blockHeaderSize/2]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			first := testSnapshot(testStart)
+			second := testSnapshot(testStart.Add(time.Second))
+			path := writeSegment(t, dir, testStart, first, second)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tail := len(testBlock(t, second))
+			intact := int64(len(data) - tail)
+			if err := os.WriteFile(path, tt.damage(data, tail), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			seg, err := scanSegment(path, testStart, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seg.blocks) != 1 || seg.size != intact || seg.maxTs != testStart.UnixMilli()+1 {
+				t.Errorf("scanned %d blocks, %d bytes, newest %d; want the first block only", len(seg.blocks), seg.size, seg.maxTs)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != intact {
+				t.Errorf("segment is %d bytes after repair, want %d", info.Size(), intact)
+			}
+		})
+	}
+}
@@ -0,0 +1,562 @@
+// Package storage keeps system and process samples on disk so history
+// survives restarts and outlives the processes it describes.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// ErrClosed is returned when appending to a closed store
+var ErrClosed = errors.New("history store is closed")
+
+// Options configures a history store
+type Options struct {
+	Dir             string        // Directory holding the segment files
+	Retention       time.Duration // Segments whose newest sample is older are deleted
+	MaxBytes        int64         // Oldest segments are deleted while the total size exceeds this
+	SegmentDuration time.Duration // A new segment is started after this long
+	SegmentBytes    int64         // A new segment is started once the current one is this large
+	FlushInterval   time.Duration // Longest time samples stay in memory before being written
+	BlockBytes      int           // Uncompressed size at which a block is written early
+}
+
+// DefaultOptions returns the default store configuration for a directory
+func DefaultOptions(dir string) Options {
+	return Options{
+		Dir:             dir,
+		Retention:       24 * time.Hour,
+		MaxBytes:        64 << 20,
+		SegmentDuration: time.Hour,
+		SegmentBytes:    64 << 20,
+		FlushInterval:   10 * time.Second,
+		BlockBytes:      256 << 10,
+	}
+}
+
+// DefaultDir returns the per-user directory history is kept in
+func DefaultDir() string {
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "hyperbyte-pulse", "history")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "hyperbyte-pulse", "history")
+	}
+	return filepath.Join(os.TempDir(), "hyperbyte-pulse", "history")
+}
+
+// ProcessSummary describes a process that has samples in the store
+type ProcessSummary struct {
+	Key       monitor.ProcessKey
+	Name      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Store is an append-only on-disk history of system and process samples.
+//
+// Samples are buffered in memory and written as checksummed, compressed
+// blocks to segment files, each block followed by an fsync. On open, a
+// partially written tail left by a crash is truncated and existing segments
+// are indexed in the background; queries wait for that to finish.
+type Store struct {
+	opts Options
+
+	mu           sync.Mutex
+	segments     []*segment // Oldest first, the last one is being appended to
+	active       *os.File
+	pending      encoder
+	pendingCount uint32
+	pendingMin   int64
+	pendingMax   int64
+	lastFlush    time.Time
+	index        map[monitor.ProcessKey]*ProcessSummary
+	loaded       bool
+	closed       bool
+
+	ready    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Open opens or creates the store in opts.Dir. Zero options take their defaults.
+func Open(opts Options) (*Store, error) {
+	defaults := DefaultOptions(opts.Dir)
+	if opts.Retention <= 0 {
+		opts.Retention = defaults.Retention
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaults.MaxBytes
+	}
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = defaults.SegmentDuration
+	}
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = defaults.SegmentBytes
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaults.FlushInterval
+	}
+	if opts.BlockBytes <= 0 {
+		opts.BlockBytes = defaults.BlockBytes
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	existing, err := listSegments(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history segments: %w", err)
+	}
+
+	s := &Store{
+		opts:      opts,
+		lastFlush: time.Now(),
+		index:     make(map[monitor.ProcessKey]*ProcessSummary),
+		ready:     make(chan struct{}),
+		stop:      make(chan struct{}),
+	}
+
+	// Never append to a segment from a previous run: it may end in a torn block
+	if err := s.startSegment(time.Now()); err != nil {
+		return nil, err
+	}
+
+	go s.load(existing)
+
+	return s, nil
+}
+
+// Append buffers a snapshot, writing a block when enough data accumulated
+func (s *Store) Append(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if !snapshot.System.Timestamp.IsZero() {
+		s.notePending(snapshot.System.Timestamp)
+		s.pending.appendSystem(snapshot.System)
+	}
+	for _, proc := range snapshot.Processes {
+		s.notePending(proc.Sample.Timestamp)
+		s.pending.appendProcess(proc)
+		s.indexProcess(proc.Key, proc.Name, proc.Sample.Timestamp)
+	}
+
+	if len(s.pending.buf) >= s.opts.BlockBytes || time.Since(s.lastFlush) >= s.opts.FlushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+// Flush writes buffered samples to disk
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	return s.flush()
+}
+
+// Close writes buffered samples and releases the store
+func (s *Store) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.ready
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.flush()
+	if closeErr := s.active.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Processes returns every process with recorded samples, most recently seen first
+func (s *Store) Processes() ([]ProcessSummary, error) {
+	<-s.ready
+
+	s.mu.Lock()
+	summaries := make([]ProcessSummary, 0, len(s.index))
+	for _, summary := range s.index {
+		summaries = append(summaries, *summary)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].LastSeen.After(summaries[j].LastSeen)
+	})
+	return summaries, nil
+}
+
+// Process returns the summary of one recorded process
+func (s *Store) Process(key monitor.ProcessKey) (ProcessSummary, bool) {
+	<-s.ready
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if summary, exists := s.index[key]; exists {
+		return *summary, true
+	}
+	return ProcessSummary{}, false
+}
+
+// ProcessHistory returns the recorded samples of a process averaged over
+// intervals of the given resolution, oldest first. CPU usage is per-core.
+func (s *Store) ProcessHistory(key monitor.ProcessKey, resolution time.Duration) ([]monitor.MetricSample, error) {
+	<-s.ready
+
+	s.mu.Lock()
+	summary, exists := s.index[key]
+	var first, last time.Time
+	if exists {
+		first, last = summary.FirstSeen, summary.LastSeen
+	}
+	s.mu.Unlock()
+	if !exists {
+		return nil, nil
+	}
+	if resolution <= 0 {
+		resolution = time.Second
+	}
+
+	capacity := int(last.Sub(first)/resolution) + 2
+	series := monitor.NewProcessMetrics([]monitor.TierConfig{{Resolution: resolution, Capacity: capacity}})
+	err := s.scan(first.UnixMilli(), last.UnixMilli(), func(rec record) {
+		if rec.kind == kindProcess && rec.process.Key == key {
+			series.AddMetric(rec.process.Sample)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return series.Samples(0), nil
+}
+
+// SystemHistory returns the system samples recorded since the given time, oldest first
+func (s *Store) SystemHistory(since time.Time) ([]SystemRecord, error) {
+	<-s.ready
+
+	var records []SystemRecord
+	err := s.scan(since.UnixMilli(), time.Now().UnixMilli(), func(rec record) {
+		if rec.kind == kindSystem && !rec.system.Timestamp.Before(since) {
+			records = append(records, rec.system)
+		}
+	})
+	return records, err
+}
+
+// notePending extends the time range of the block being buffered.
+// Callers must hold the lock.
+func (s *Store) notePending(at time.Time) {
+	ms := at.UnixMilli()
+	if s.pendingCount == 0 {
+		s.pendingMin, s.pendingMax = ms, ms
+	}
+	if ms < s.pendingMin {
+		s.pendingMin = ms
+	}
+	if ms > s.pendingMax {
+		s.pendingMax = ms
+	}
+	s.pendingCount++
+}
+
+// indexProcess records that a process has samples at the given time.
+// Callers must hold the lock.
+func (s *Store) indexProcess(key monitor.ProcessKey, name string, at time.Time) {
+	summary, exists := s.index[key]
+	if !exists {
+		s.index[key] = &ProcessSummary{Key: key, Name: name, FirstSeen: at, LastSeen: at}
+		return
+	}
+	if at.Before(summary.FirstSeen) {
+		summary.FirstSeen = at
+	}
+	if at.After(summary.LastSeen) {
+		summary.LastSeen = at
+		summary.Name = name
+	}
+}
+
+// flush writes the buffered records as one block. Callers must hold the lock.
+func (s *Store) flush() error {
+	s.lastFlush = time.Now()
+	if s.pendingCount == 0 {
+		return nil
+	}
+
+	seg := s.segments[len(s.segments)-1]
+	block, err := encodeBlock(s.pending.buf, s.pendingCount, s.pendingMin, s.pendingMax)
+	if err == nil {
+		_, err = s.active.Write(block)
+		if err == nil {
+			err = s.active.Sync()
+		}
+		if err != nil {
+			// Leave the segment ending at the last complete block
+			_ = s.active.Truncate(seg.size)
+		}
+	}
+	ref := blockRef{
+		offset: seg.size,
+		length: uint32(len(block) - blockHeaderSize),
+		count:  s.pendingCount,
+		minTs:  s.pendingMin,
+		maxTs:  s.pendingMax,
+	}
+
+	// The buffer is dropped even on failure so a broken disk can't grow memory without bound
+	s.pending = encoder{buf: s.pending.buf[:0]}
+	s.pendingCount = 0
+	if err != nil {
+		return fmt.Errorf("failed to write history block: %w", err)
+	}
+	seg.addBlock(ref)
+
+	if seg.size >= s.opts.SegmentBytes || time.Since(seg.start) >= s.opts.SegmentDuration {
+		if err := s.active.Close(); err != nil {
+			return err
+		}
+		if err := s.startSegment(time.Now()); err != nil {
+			return err
+		}
+		s.applyRetention()
+	}
+	return nil
+}
+
+// startSegment creates a new segment file and makes it the active one.
+// Callers must hold the lock, or own the store exclusively.
+func (s *Store) startSegment(now time.Time) error {
+	for {
+		path := filepath.Join(s.opts.Dir, segmentName(now))
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			now = now.Add(time.Millisecond)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create history segment: %w", err)
+		}
+
+		if _, err := file.Write([]byte(segmentMagic)); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write history segment: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write history segment: %w", err)
+		}
+		syncDir(s.opts.Dir)
+
+		s.active = file
+		s.segments = append(s.segments, &segment{
+			path:  path,
+			start: now.Truncate(time.Millisecond),
+			size:  int64(len(segmentMagic)),
+		})
+		return nil
+	}
+}
+
+// load repairs and indexes the segments left by previous runs
+func (s *Store) load(existing []*segment) {
+	defer close(s.ready)
+
+	loaded := make([]*segment, 0, len(existing))
+	index := make(map[monitor.ProcessKey]*ProcessSummary)
+	for _, old := range existing {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		seg, err := scanSegment(old.path, old.start, true)
+		if err != nil {
+			continue
+		}
+		indexSegment(seg, index)
+		loaded = append(loaded, seg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.segments = append(loaded, s.segments...)
+	for key, old := range index {
+		if summary, exists := s.index[key]; exists {
+			summary.FirstSeen = old.FirstSeen
+			continue
+		}
+		s.index[key] = old
+	}
+	s.loaded = true
+	s.applyRetention()
+}
+
+// indexSegment adds the processes found in a segment to index
+func indexSegment(seg *segment, index map[monitor.ProcessKey]*ProcessSummary) {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	for _, ref := range seg.blocks {
+		_, payload, err := readBlock(file, ref.offset)
+		if err != nil {
+			continue
+		}
+		records, err := decodePayload(payload)
+		if err != nil {
+			continue
+		}
+		_ = eachRecord(records, func(rec record) bool {
+			if rec.kind != kindProcess {
+				return true
+			}
+			at := rec.process.Sample.Timestamp
+			summary, exists := index[rec.process.Key]
+			if !exists {
+				index[rec.process.Key] = &ProcessSummary{Key: rec.process.Key, Name: rec.process.Name, FirstSeen: at, LastSeen: at}
+				return true
+			}
+			if at.Before(summary.FirstSeen) {
+				summary.FirstSeen = at
+			}
+			if at.After(summary.LastSeen) {
+				summary.LastSeen = at
+				summary.Name = rec.process.Name
+			}
+			return true
+		})
+	}
+}
+
+// applyRetention deletes segments that are too old or exceed the size limit.
+// The active segment is never deleted. Callers must hold the lock.
+func (s *Store) applyRetention() {
+	if !s.loaded {
+		return
+	}
+
+	cutoff := time.Now().Add(-s.opts.Retention).UnixMilli()
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+
+	kept := s.segments[:0]
+	for i, seg := range s.segments {
+		active := i == len(s.segments)-1
+		expired := len(seg.blocks) == 0 || seg.maxTs < cutoff
+		if !active && (expired || total > s.opts.MaxBytes) {
+			if err := os.Remove(seg.path); err == nil || errors.Is(err, os.ErrNotExist) {
+				total -= seg.size
+				continue
+			}
+		}
+		kept = append(kept, seg)
+	}
+	s.segments = kept
+
+	// Forget processes whose samples were all deleted, and the deleted
+	// samples of the others
+	oldest := cutoff
+	if first := s.segments[0]; len(first.blocks) > 0 && first.minTs > oldest {
+		oldest = first.minTs
+	}
+	for key, summary := range s.index {
+		if summary.LastSeen.UnixMilli() < oldest {
+			delete(s.index, key)
+		} else if summary.FirstSeen.UnixMilli() < oldest {
+			summary.FirstSeen = time.UnixMilli(oldest)
+		}
+	}
+}
+
+// scan calls fn for every record in blocks overlapping [from, to], including
+// records that haven't been written yet
+func (s *Store) scan(from, to int64, fn func(record)) error {
+	type segmentView struct {
+		path   string
+		blocks []blockRef
+	}
+
+	s.mu.Lock()
+	views := make([]segmentView, 0, len(s.segments))
+	for _, seg := range s.segments {
+		if len(seg.blocks) == 0 || seg.maxTs < from || seg.minTs > to {
+			continue
+		}
+		views = append(views, segmentView{path: seg.path, blocks: append([]blockRef(nil), seg.blocks...)})
+	}
+	pending := append([]byte(nil), s.pending.buf...)
+	s.mu.Unlock()
+
+	visit := func(rec record) bool {
+		fn(rec)
+		return true
+	}
+
+	for _, view := range views {
+		file, err := os.Open(view.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Deleted by retention in the meantime
+		}
+		if err != nil {
+			return err
+		}
+		for _, ref := range view.blocks {
+			if ref.maxTs < from || ref.minTs > to {
+				continue
+			}
+			_, payload, err := readBlock(file, ref.offset)
+			if err != nil {
+				continue
+			}
+			records, err := decodePayload(payload)
+			if err != nil {
+				continue
+			}
+			if err := eachRecord(records, visit); err != nil {
+				continue
+			}
+		}
+		file.Close()
+	}
+
+	if len(pending) > 0 {
+		return eachRecord(pending, visit)
+	}
+	return nil
+}
+
+// syncDir makes a newly created file's directory entry durable
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
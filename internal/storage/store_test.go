@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// openStore opens a store in dir and waits until existing segments are loaded
+func openStore(t *testing.T, opts Options) *Store {
+	t.Helper()
+	store, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	<-store.ready
+	return store
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	segments, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(segments))
+	for i, seg := range segments {
+		paths[i] = seg.path
+	}
+	return paths
+}
+
+func TestRetentionDeletesOldSegments(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	old := writeSegment(t, dir, now.Add(-48*time.Hour), testSnapshot(now.Add(-48*time.Hour)))
+	recent := writeSegment(t, dir, now.Add(-time.Hour), testSnapshot(now.Add(-time.Hour)))
+
+	opts := DefaultOptions(dir)
+	opts.Retention = 24 * time.Hour
+	store := openStore(t, opts)
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("segment older than the retention was kept")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("recent segment was deleted: %v", err)
+	}
+	summary, exists := store.Process(testProcess(100, now, 0).Key)
+	if !exists || !summary.FirstSeen.Equal(now.Add(-time.Hour)) {
+		t.Errorf("process 100 = %+v, %v; want it first seen in the recent segment", summary, exists)
+	}
+}
+
+func TestMaxBytesEvictsOldestSegments(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	var paths []string
+	for i := 3; i > 0; i-- {
+		at := now.Add(-time.Duration(i) * time.Minute)
+		paths = append(paths, writeSegment(t, dir, at, testSnapshot(at)))
+	}
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for the two newest segments and the empty active one
+	opts := DefaultOptions(dir)
+	opts.MaxBytes = 2*info.Size() + int64(len(segmentMagic))
+	openStore(t, opts)
+
+	files := segmentFiles(t, dir)
+	if len(files) != 3 || files[0] != paths[1] || files[1] != paths[2] {
+		t.Errorf("segments left = %v, want the two newest and the active one", files)
+	}
+}
+
+func TestQueriesSpanSegments(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	writeSegment(t, dir, now.Add(-time.Minute), testSnapshot(now.Add(-time.Minute)))
+
+	// Every flushed block starts a new segment
+	opts := DefaultOptions(dir)
+	opts.SegmentBytes = 1
+	store := openStore(t, opts)
+	for i := 2; i > 0; i-- {
+		if err := store.Append(testSnapshot(now.Add(-time.Duration(i) * time.Second))); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// And one sample still buffered
+	if err := store.Append(testSnapshot(now)); err != nil {
+		t.Fatal(err)
+	}
+	if files := segmentFiles(t, dir); len(files) != 4 {
+		t.Fatalf("got %d segments, want 4", len(files))
+	}
+
+	key := testProcess(100, now, 0).Key
+	history, err := store.ProcessHistory(key, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{now.Add(-time.Minute), now.Add(-2 * time.Second), now.Add(-time.Second), now}
+	if len(history) != len(want) {
+		t.Fatalf("got %d samples of process 100, want %d", len(history), len(want))
+	}
+	for i, sample := range history {
+		if !sample.Timestamp.Equal(want[i]) || sample.CPUPercent != 12.5 {
+			t.Errorf("sample %d at %v with CPU %v, want %v with 12.5", i, sample.Timestamp, sample.CPUPercent, want[i])
+		}
+	}
+
+	system, err := store.SystemHistory(now.Add(-2 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(system) != 3 {
+		t.Errorf("got %d system samples since two seconds ago, want 3", len(system))
+	}
+}
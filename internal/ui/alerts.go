@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"hyperbyte-proc-monitor/internal/alert"
+)
+
+// Alerts provides the alerts raised by the rules engine
+type Alerts interface {
+	Alerts() []alert.Alert
+	Counts() (firing, pending int)
+}
+
+// SetAlerts shows alerts in the alerts view and the status bar
+func (ui *UI) SetAlerts(alerts Alerts) {
+	ui.alerts = alerts
+}
+
+func (ui *UI) setupAlertsView() {
+	ui.alertsTable = tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[green]Keybindings:[-] [white]↑↓[-] Navigate [white]Enter[-] Process details [white]ESC/q[-] Back")
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(ui.alertsTable, 0, 1, true).
+		AddItem(help, 1, 0, false)
+	flex.SetBorder(true).SetTitle(" Alerts ")
+
+	ui.pages.AddPage("alerts", flex, true, false)
+}
+
+func (ui *UI) handleAlertsViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEsc:
+		ui.showMainView()
+		return nil
+	case tcell.KeyEnter:
+		row, _ := ui.alertsTable.GetSelection()
+		if row > 0 && row <= len(ui.alertRows) {
+			ui.openAlertProcess(ui.alertRows[row-1])
+		}
+		return nil
+	}
+
+	switch event.Rune() {
+	case 'q', 'Q':
+		ui.showMainView()
+		return nil
+	}
+
+	return event
+}
+
+func (ui *UI) showAlertsView() {
+	ui.currentView = "alerts"
+	ui.pages.SwitchToPage("alerts")
+	ui.app.SetFocus(ui.alertsTable)
+	ui.triggerUpdate()
+}
+
+func (ui *UI) updateAlertsView() {
+	ui.alertsTable.Clear()
+	ui.alertRows = nil
+
+	if ui.alerts == nil {
+		ui.alertsTable.SetCell(0, 0, tview.NewTableCell("No alert rules loaded (start with --alert-rules)").
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		return
+	}
+
+	headers := []string{"State", "Severity", "Rule", "Instance", "Condition", "Value", "Since"}
+	if ui.hosts != nil {
+		headers = append([]string{"Host"}, headers...)
+	}
+	for i, header := range headers {
+		ui.alertsTable.SetCell(0, i, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(tview.AlignCenter).
+			SetSelectable(false))
+	}
+
+	ui.alertRows = ui.alerts.Alerts()
+	now := ui.monitor.Now()
+	for i, a := range ui.alertRows {
+		color := tcell.ColorGray
+		switch a.State {
+		case alert.StateFiring:
+			color = tcell.ColorRed
+			if a.Severity == alert.SeverityInfo {
+				color = tcell.ColorAqua
+			}
+		case alert.StatePending:
+			color = tcell.ColorYellow
+		}
+
+		since := a.Since
+		switch a.State {
+		case alert.StateFiring:
+			since = a.FiredAt
+		case alert.StateResolved:
+			since = a.ResolvedAt
+		}
+
+		cells := []string{
+			a.State.String(),
+			a.Severity,
+			a.Rule,
+			a.Instance,
+			fmt.Sprintf("%s %s %g", a.Metric, a.Op, a.Threshold),
+			fmt.Sprintf("%.1f", a.Value),
+			fmt.Sprintf("%s (%s)", since.Format("15:04:05"), now.Sub(since).Round(time.Second)),
+		}
+		if ui.hosts != nil {
+			cells = append([]string{a.Host}, cells...)
+		}
+		for col, text := range cells {
+			ui.alertsTable.SetCell(i+1, col, tview.NewTableCell(text).SetTextColor(color))
+		}
+	}
+}
+
+// openAlertProcess opens the detail view of the process an alert is about
+func (ui *UI) openAlertProcess(a alert.Alert) {
+	if a.Process.PID == 0 {
+		return // System alert
+	}
+	if ui.hosts != nil {
+		found := false
+		for i, host := range ui.hosts {
+			if host.Remote.State().Address == a.Host {
+				ui.selectHost(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+	ui.selectedKey = a.Process
+	ui.archived = nil
+	ui.monitor.EnsureProcessMetrics(ui.selectedKey)
+	ui.showDetailView()
+}
+
+// alertsLabel is the status bar badge counting firing and pending alerts
+func (ui *UI) alertsLabel() string {
+	firing, pending := ui.alerts.Counts()
+	label := ""
+	if firing > 0 {
+		label += fmt.Sprintf("[white:red] %d FIRING [-:-] ", firing)
+	}
+	if pending > 0 {
+		label += fmt.Sprintf("[black:yellow] %d pending [-:-] ", pending)
+	}
+	return label
+}
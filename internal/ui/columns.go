@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// column is an optional column of the process table
+type column struct {
+	name     string // As accepted by SetColumns
+	header   string
+	maxWidth int // 0 for no limit
+	value    func(p monitor.ProcessInfo) string
+}
+
+// optionalColumns lists the columns that can be added after the fixed ones,
+// in display order. User, cwd, exe and command line are only known for the
+// tracked processes.
+var optionalColumns = []column{
+	{"user", "User", 12, func(p monitor.ProcessInfo) string { return p.Username }},
+	{"state", "S", 0, func(p monitor.ProcessInfo) string { return p.State }},
+	{"threads", "Threads", 0, func(p monitor.ProcessInfo) string { return strconv.Itoa(int(p.Threads)) }},
+	{"priority", "PR", 0, func(p monitor.ProcessInfo) string { return strconv.Itoa(int(p.Priority)) }},
+	{"nice", "NI", 0, func(p monitor.ProcessInfo) string { return strconv.Itoa(int(p.Nice)) }},
+	{"tty", "TTY", 0, func(p monitor.ProcessInfo) string { return p.TTY }},
+	{"cwd", "CWD", 30, func(p monitor.ProcessInfo) string { return p.Cwd }},
+	{"exe", "Exe", 30, func(p monitor.ProcessInfo) string { return p.Exe }},
+	{"cmdline", "Command", 60, func(p monitor.ProcessInfo) string { return p.Cmdline }},
+}
+
+// ColumnNames returns the names accepted by SetColumns
+func ColumnNames() []string {
+	names := make([]string, len(optionalColumns))
+	for i, col := range optionalColumns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// SetColumns shows optional columns in the process table
+func (ui *UI) SetColumns(names []string) error {
+	shown := make(map[string]bool, len(names))
+	for _, name := range names {
+		found := false
+		for _, col := range optionalColumns {
+			if col.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(ColumnNames(), ","))
+		}
+		shown[name] = true
+	}
+	ui.columns = shown
+	ui.setProcessHeaders()
+	return nil
+}
+
+// shownColumns returns the optional columns currently shown, in display order
+func (ui *UI) shownColumns() []column {
+	var shown []column
+	for _, col := range optionalColumns {
+		if ui.columns[col.name] {
+			shown = append(shown, col)
+		}
+	}
+	return shown
+}
+
+// showColumnsDialog lets the user pick the optional columns
+func (ui *UI) showColumnsDialog() {
+	list := tview.NewList().ShowSecondaryText(false)
+	label := func(col column) string {
+		mark := "  "
+		if ui.columns[col.name] {
+			mark = "✓ "
+		}
+		return mark + col.header + " (" + col.name + ")"
+	}
+	for i, col := range optionalColumns {
+		list.AddItem(label(col), "", 0, func() {
+			if ui.columns[col.name] {
+				delete(ui.columns, col.name)
+			} else {
+				ui.columns[col.name] = true
+			}
+			list.SetItemText(i, label(col), "")
+			ui.setProcessHeaders()
+			ui.triggerUpdate()
+		})
+	}
+	list.SetDoneFunc(func() {
+		ui.pages.RemovePage("columns")
+	})
+	list.SetBorder(true).SetTitle(" Columns - Enter to toggle, ESC to close ")
+
+	// Center the list like a modal
+	dialog := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, len(optionalColumns)+2, 0, true).
+			AddItem(nil, 0, 1, false), 40, 0, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("columns", dialog, true, true)
+	ui.app.SetFocus(list)
+}
+
+// columnCell formats an optional column of a process row
+func columnCell(col column, proc monitor.ProcessInfo, color tcell.Color) *tview.TableCell {
+	return tview.NewTableCell(orDash(col.value(proc))).
+		SetTextColor(color).
+		SetMaxWidth(col.maxWidth)
+}
+
+// stateNames describes the run state letters of ps
+var stateNames = map[string]string{
+	"R": "running",
+	"S": "sleeping",
+	"D": "disk sleep",
+	"Z": "zombie",
+	"T": "stopped",
+	"t": "tracing stop",
+	"I": "idle",
+	"X": "dead",
+	"L": "locked",
+}
+
+// stateName formats a run state letter with its meaning, e.g. "S (sleeping)"
+func stateName(state string) string {
+	if name, exists := stateNames[state]; exists {
+		return state + " (" + name + ")"
+	}
+	return orDash(state)
+}
+
+// orDash escapes a value for display on one line, or returns "-" when it is unknown
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' ' // Arguments may contain newlines
+		}
+		return r
+	}, value)
+	return tview.Escape(value)
+}
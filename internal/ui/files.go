@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// FileDescriptors lists the open files of local processes
+type FileDescriptors interface {
+	OpenFiles(ctx context.Context, key monitor.ProcessKey) (monitor.OpenFiles, error)
+}
+
+// SetFileDescriptors enables the open files tab of the detail view
+func (ui *UI) SetFileDescriptors(fds FileDescriptors) {
+	ui.fds = fds
+}
+
+func (ui *UI) setupFilesView() {
+	ui.filesSummary = tview.NewTextView().SetDynamicColors(true)
+
+	ui.filesTable = tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[green]Keybindings:[-] [white]↑↓[-] Navigate [white]/[-] Filter [white]ESC[-] Clear filter / back [white]f/q[-] Back to graphs")
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(ui.filesSummary, 1, 0, false).
+		AddItem(ui.filesTable, 0, 1, true).
+		AddItem(help, 1, 0, false)
+	flex.SetBorder(true).SetTitle(" Open Files ")
+
+	ui.pages.AddPage("files", flex, true, false)
+}
+
+func (ui *UI) handleFilesViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEsc:
+		if ui.filesFiltering || ui.filesFilter != "" {
+			ui.filesFiltering = false
+			ui.filesFilter = ""
+			ui.triggerUpdate()
+			return nil
+		}
+		ui.returnToDetailView()
+		return nil
+	case tcell.KeyEnter:
+		if ui.filesFiltering {
+			ui.filesFiltering = false
+			ui.triggerUpdate()
+			return nil
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if ui.filesFiltering && len(ui.filesFilter) > 0 {
+			ui.filesFilter = ui.filesFilter[:len(ui.filesFilter)-1]
+			ui.triggerUpdate()
+			return nil
+		}
+	case tcell.KeyRune:
+		if ui.filesFiltering {
+			ui.filesFilter += string(event.Rune())
+			ui.triggerUpdate()
+			return nil
+		}
+		switch event.Rune() {
+		case '/':
+			ui.filesFiltering = true
+			ui.filesFilter = ""
+			ui.triggerUpdate()
+			return nil
+		case 'f', 'F', 'q', 'Q':
+			ui.returnToDetailView()
+			return nil
+		}
+	}
+
+	return event
+}
+
+// showFilesView opens the open files tab of the selected process
+func (ui *UI) showFilesView() {
+	if ui.archived != nil {
+		return
+	}
+	if ui.fds == nil {
+		ui.showMessage("Open files are only available for processes on this host")
+		return
+	}
+	ui.filesFilter = ""
+	ui.filesFiltering = false
+	ui.currentView = "files"
+	ui.pages.SwitchToPage("files")
+	ui.app.SetFocus(ui.filesTable)
+	ui.filesTable.Select(1, 0)
+	ui.triggerUpdate()
+}
+
+// returnToDetailView goes back to the graphs without resetting them
+func (ui *UI) returnToDetailView() {
+	ui.currentView = "detail"
+	ui.pages.SwitchToPage("detail")
+	ui.app.SetFocus(ui.detailFlex)
+	ui.triggerUpdate()
+}
+
+func (ui *UI) updateFilesView() {
+	ui.filesTable.Clear()
+
+	name := "unknown"
+	if node := ui.monitor.GetProcessTree().Node(ui.selectedKey); node != nil {
+		name = node.Process.Name
+	}
+	title := fmt.Sprintf("[yellow]%s (PID %d)[-]  ", tview.Escape(name), ui.selectedKey.PID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+	files, err := ui.fds.OpenFiles(ctx, ui.selectedKey)
+	if err != nil {
+		text := err.Error()
+		if errors.Is(err, os.ErrPermission) {
+			text = "permission denied, only root or the owner of the process may list its open files"
+		}
+		ui.filesSummary.SetText(title + "[red]" + tview.Escape(text) + "[-]")
+		return
+	}
+
+	headers := []string{"FD", "Type", "Flags", "Pos", "State", "Target"}
+	for i, header := range headers {
+		ui.filesTable.SetCell(0, i, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+
+	query := strings.ToLower(ui.filesFilter)
+	row := 1
+	for _, fd := range files.FDs {
+		if query != "" && !fileMatches(fd, query) {
+			continue
+		}
+		pos := ""
+		if fd.Type == "file" || fd.Type == "block" {
+			pos = strconv.FormatInt(fd.Pos, 10)
+		}
+		color := tcell.ColorWhite
+		switch fd.Type {
+		case "tcp", "tcp6", "udp", "udp6", "unix", "socket":
+			color = tcell.ColorAqua
+		case "pipe", "fifo", "anon":
+			color = tcell.ColorGray
+		}
+		cells := []string{strconv.Itoa(fd.FD), fd.Type, fd.Flags, pos, fd.State, fd.Target}
+		for col, text := range cells {
+			cell := tview.NewTableCell(orDash(text)).SetTextColor(color)
+			if col == 0 || col == 3 {
+				cell.SetAlign(tview.AlignRight)
+			}
+			ui.filesTable.SetCell(row, col, cell)
+		}
+		row++
+	}
+
+	summary := title + "Open files: " + openFilesLabel(len(files.FDs), files.SoftLimit, files.HardLimit)
+	if ui.filesFiltering || ui.filesFilter != "" {
+		summary += fmt.Sprintf("  [green]Filter:[-] %s (%d shown)", tview.Escape(ui.filesFilter), row-1)
+		if ui.filesFiltering {
+			summary += " [dim](Enter to keep, ESC to clear)[-]"
+		}
+	}
+	ui.filesSummary.SetText(summary)
+}
+
+// fileMatches reports whether any column of a descriptor contains a lower-case query
+func fileMatches(fd monitor.FileDescriptor, query string) bool {
+	for _, text := range []string{strconv.Itoa(fd.FD), fd.Type, fd.Flags, fd.State, fd.Target} {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// openFilesLabel formats the descriptor count against the RLIMIT_NOFILE soft
+// limit, colored as it gets close
+func openFilesLabel(count int, soft, hard int64) string {
+	limit := func(value int64) string {
+		if value < 0 {
+			return "unlimited"
+		}
+		return strconv.FormatInt(value, 10)
+	}
+	if soft <= 0 {
+		return fmt.Sprintf("%d (no soft limit, hard %s)", count, limit(hard))
+	}
+
+	perc := float64(count) / float64(soft) * 100
+	color := "green"
+	switch {
+	case perc > 80:
+		color = "red"
+	case perc > 50:
+		color = "yellow"
+	}
+	return fmt.Sprintf("[%s]%d of %d[-] (%.0f%% of the soft limit, hard %s)", color, count, soft, perc, limit(hard))
+}
@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// allHosts is the host filter of the merged process table
+const allHosts = -1
+
+// Host is one machine of a fleet, usually the connection to an agent
+type Host struct {
+	Monitor *monitor.Monitor
+	Actions ProcessActions
+	Tuning  ProcessTuning
+	Remote  Remote
+}
+
+// name returns the hostname reported by the agent, or its address until it connected
+func (h Host) name() string {
+	if h.Remote == nil {
+		return "local"
+	}
+	state := h.Remote.State()
+	if state.Hostname != "" {
+		return state.Hostname
+	}
+	return state.Address
+}
+
+// SetFleet shows several hosts at once: a page listing the hosts, and a
+// process table merged across all of them. The detail view and process actions
+// use the host of the selected process.
+func (ui *UI) SetFleet(hosts []Host) {
+	ui.hosts = hosts
+	ui.hostFilter = allHosts
+	ui.selectHost(0)
+	ui.setProcessHeaders()
+	ui.showHostsView()
+}
+
+func (ui *UI) setupHostsView() {
+	ui.hostsTable = tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+
+	headers := []string{"Host", "Address", "Status", "CPU%", "Memory%", "Load 1m", "Load 5m", "Load 15m", "Processes", "Updated"}
+	for i, header := range headers {
+		ui.hostsTable.SetCell(0, i, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(tview.AlignCenter).
+			SetSelectable(false))
+	}
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("[green]Keybindings:[-] [white]↑↓[-] Navigate [white]Enter[-] Host processes [white]a[-] All processes [white]q[-] Quit [white]h[-] Help")
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(ui.hostsTable, 0, 1, true).
+		AddItem(help, 1, 0, false)
+	flex.SetBorder(true).SetTitle(" Fleet ")
+
+	ui.pages.AddPage("hosts", flex, true, false)
+}
+
+func (ui *UI) handleHostsViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEnter:
+		row, _ := ui.hostsTable.GetSelection()
+		if row > 0 && row <= len(ui.hosts) {
+			ui.hostFilter = row - 1
+			ui.showMainView()
+		}
+		return nil
+	}
+
+	switch event.Rune() {
+	case 'a', 'A':
+		ui.hostFilter = allHosts
+		ui.showMainView()
+		return nil
+	case 'q', 'Q':
+		ui.Stop()
+		return nil
+	case 'h', 'H':
+		ui.showHelpDialog()
+		return nil
+	}
+
+	return event
+}
+
+func (ui *UI) showHostsView() {
+	ui.currentView = "hosts"
+	ui.pages.SwitchToPage("hosts")
+	ui.app.SetFocus(ui.hostsTable)
+	ui.triggerUpdate()
+}
+
+func (ui *UI) updateHostsView() {
+	for i, host := range ui.hosts {
+		row := i + 1
+		state := host.Remote.State()
+		system := host.Monitor.GetSystemMetrics()
+
+		status, color := "connected", tcell.ColorGreen
+		if !state.Connected {
+			status, color = "disconnected", tcell.ColorRed
+			if state.Err != nil {
+				status += ": " + state.Err.Error()
+			}
+		}
+
+		// Metrics of a disconnected host are stale, show when they were taken
+		updated := "-"
+		if !system.Timestamp.IsZero() {
+			updated = system.Timestamp.Format("15:04:05")
+		}
+
+		cells := []string{
+			host.name(),
+			state.Address,
+			status,
+			fmt.Sprintf("%.1f", system.CPUPercent),
+			fmt.Sprintf("%.1f", system.MemoryPercent),
+			fmt.Sprintf("%.2f", system.Load.Load1),
+			fmt.Sprintf("%.2f", system.Load.Load5),
+			fmt.Sprintf("%.2f", system.Load.Load15),
+			strconv.Itoa(len(host.Monitor.GetProcesses())),
+			updated,
+		}
+		for col, text := range cells {
+			cell := tview.NewTableCell(text).SetTextColor(tcell.ColorWhite)
+			if col == 2 {
+				cell.SetTextColor(color)
+			}
+			ui.hostsTable.SetCell(row, col, cell)
+		}
+	}
+}
+
+// selectHost makes the monitor, actions, tuning and connection of a host the
+// ones used by the detail view and the process actions
+func (ui *UI) selectHost(index int) {
+	host := ui.hosts[index]
+	ui.monitor = host.Monitor
+	ui.actions = host.Actions
+	ui.tuning = host.Tuning
+	ui.remote = host.Remote
+}
+
+// fleetProcesses merges the processes of the shown hosts, sorted like a
+// single host's table
+func (ui *UI) fleetProcesses() []processRow {
+	var rows []processRow
+	for i, host := range ui.hosts {
+		if ui.hostFilter != allHosts && ui.hostFilter != i {
+			continue
+		}
+		for _, proc := range monitor.FilterProcesses(host.Monitor.GetProcesses(), ui.searchQuery) {
+			rows = append(rows, processRow{host: i, proc: proc})
+		}
+	}
+
+	sortBy, desc := ui.monitor.GetSorting()
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return sortBy.Less(rows[j].proc, rows[i].proc)
+		}
+		return sortBy.Less(rows[i].proc, rows[j].proc)
+	})
+	return rows
+}
+
+// fleetLabel describes the connected hosts and the host filter for the status bar
+func (ui *UI) fleetLabel() string {
+	connected := 0
+	for _, host := range ui.hosts {
+		if host.Remote.State().Connected {
+			connected++
+		}
+	}
+	shown := "all hosts"
+	if ui.hostFilter != allHosts {
+		shown = ui.hosts[ui.hostFilter].name()
+	}
+	color := "aqua"
+	if connected < len(ui.hosts) {
+		color = "red"
+	}
+	return fmt.Sprintf("[%s]FLEET %d/%d connected, showing %s[-] ", color, connected, len(ui.hosts), shown)
+}
+
+// setSorting changes the sort order of every host, so the merged table and
+// the detail view of any host agree
+func (ui *UI) setSorting(sortBy monitor.SortBy, desc bool) {
+	ui.monitor.SetSorting(sortBy, desc)
+	for _, host := range ui.hosts {
+		host.Monitor.SetSorting(sortBy, desc)
+	}
+	ui.triggerUpdate()
+}
@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// ProcessTuning changes the CPU and I/O scheduling of processes
+type ProcessTuning interface {
+	Scheduling(ctx context.Context, key monitor.ProcessKey) (monitor.Scheduling, error)
+	SetNice(ctx context.Context, key monitor.ProcessKey, nice int) error
+	SetIOPriority(ctx context.Context, key monitor.ProcessKey, prio monitor.IOPriority) error
+	SetAffinity(ctx context.Context, key monitor.ProcessKey, cpus []int) error
+}
+
+// SetProcessTuning enables the nice, I/O priority and CPU affinity controls
+// of the detail view
+func (ui *UI) SetProcessTuning(tuning ProcessTuning) {
+	ui.tuning = tuning
+}
+
+// tuningChange is a scheduling change the user asked for
+type tuningChange struct {
+	action   string // For the audit log, e.g. "renice"
+	argument string // For the audit log, e.g. "10"
+	question string // Confirmation, e.g. "Change the nice value from 0 to 10?"
+	denied   string // Hint shown with a permission error
+	apply    func(ctx context.Context, key monitor.ProcessKey) error
+}
+
+// schedulingText describes the scheduling of the selected process for the
+// detail panel, with the keys that change it
+func (ui *UI) schedulingText() string {
+	if ui.tuning == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+	sched, err := ui.tuning.Scheduling(ctx, ui.selectedKey)
+	if err != nil {
+		return fmt.Sprintf("[green]Scheduling:[-] %s\n\n", tview.Escape(err.Error()))
+	}
+	return fmt.Sprintf("[green]Nice:[-] %d (n to change)\n[green]I/O priority:[-] %s (o to change)\n[green]CPU affinity:[-] %s (a to change)\n\n",
+		sched.Nice, ioPriorityLabel(sched), affinityLabel(sched.Affinity))
+}
+
+// ioPriorityLabel formats the I/O priority, including the one derived from
+// the nice value
+func ioPriorityLabel(sched monitor.Scheduling) string {
+	if sched.IOPriority.Class == monitor.IOClassNone {
+		return fmt.Sprintf("none (%s from nice)", sched.EffectiveIOPriority())
+	}
+	return sched.IOPriority.String()
+}
+
+// affinityLabel formats a CPU affinity mask
+func affinityLabel(cpus []int) string {
+	if len(cpus) == 1 {
+		return monitor.FormatCPUList(cpus) + " (1 CPU)"
+	}
+	return fmt.Sprintf("%s (%d CPUs)", monitor.FormatCPUList(cpus), len(cpus))
+}
+
+// tuningTarget returns the selected process and its scheduling, or reports
+// why it cannot be changed
+func (ui *UI) tuningTarget() (processTarget, monitor.Scheduling, bool) {
+	if ui.archived != nil {
+		return processTarget{}, monitor.Scheduling{}, false
+	}
+	if ui.tuning == nil {
+		ui.showMessage("Scheduling controls are not available for this host")
+		return processTarget{}, monitor.Scheduling{}, false
+	}
+	node := ui.monitor.GetProcessTree().Node(ui.selectedKey)
+	if node == nil {
+		ui.showMessage(fmt.Sprintf("Process %d has exited", ui.selectedKey.PID))
+		return processTarget{}, monitor.Scheduling{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+	sched, err := ui.tuning.Scheduling(ctx, ui.selectedKey)
+	if err != nil {
+		ui.showMessage(fmt.Sprintf("Failed to read the scheduling of %s (PID %d):\n\n%v", node.Process.Name, node.Process.PID, err))
+		return processTarget{}, monitor.Scheduling{}, false
+	}
+	return newProcessTarget(node.Process), sched, true
+}
+
+// showNiceDialog lets the user change the nice value of the selected process
+func (ui *UI) showNiceDialog() {
+	target, sched, ok := ui.tuningTarget()
+	if !ok {
+		return
+	}
+	tuning := ui.tuning // The fleet selection may change meanwhile
+
+	form := tview.NewForm().
+		AddInputField(fmt.Sprintf("Nice (%d to %d)", monitor.MinNice, monitor.MaxNice), strconv.Itoa(int(sched.Nice)), 4, tview.InputFieldInteger, nil)
+	ui.showTuningDialog(target, "Nice", fmt.Sprintf("Current: %d", sched.Nice), form, func() (tuningChange, error) {
+		text := form.GetFormItem(0).(*tview.InputField).GetText()
+		nice, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return tuningChange{}, fmt.Errorf("invalid nice value %q", text)
+		}
+		if err := monitor.ValidateNice(nice); err != nil {
+			return tuningChange{}, err
+		}
+		return tuningChange{
+			action:   "renice",
+			argument: strconv.Itoa(nice),
+			question: fmt.Sprintf("Change the nice value from %d to %d?", sched.Nice, nice),
+			denied:   "Lowering the nice value, or changing processes of other users, requires root or CAP_SYS_NICE.",
+			apply: func(ctx context.Context, key monitor.ProcessKey) error {
+				return tuning.SetNice(ctx, key, nice)
+			},
+		}, nil
+	})
+}
+
+// showIOPriorityDialog lets the user change the I/O class and level of the
+// selected process
+func (ui *UI) showIOPriorityDialog() {
+	target, sched, ok := ui.tuningTarget()
+	if !ok {
+		return
+	}
+	tuning := ui.tuning // The fleet selection may change meanwhile
+
+	level := sched.EffectiveIOPriority().Level
+	form := tview.NewForm().
+		AddDropDown("Class", monitor.IOClassNames(), int(sched.IOPriority.Class), nil).
+		AddInputField(fmt.Sprintf("Level (0 to %d)", monitor.MaxIOLevel), strconv.Itoa(level), 4, tview.InputFieldInteger, nil)
+	ui.showTuningDialog(target, "I/O priority", "Current: "+ioPriorityLabel(sched), form, func() (tuningChange, error) {
+		index, _ := form.GetFormItem(0).(*tview.DropDown).GetCurrentOption()
+		text := form.GetFormItem(1).(*tview.InputField).GetText()
+		prio := monitor.IOPriority{Class: monitor.IOClass(index)}
+		if prio.Class == monitor.IOClassRealtime || prio.Class == monitor.IOClassBestEffort {
+			level, err := strconv.Atoi(strings.TrimSpace(text))
+			if err != nil {
+				return tuningChange{}, fmt.Errorf("invalid level %q", text)
+			}
+			prio.Level = level
+		}
+		if err := prio.Validate(); err != nil {
+			return tuningChange{}, err
+		}
+		return tuningChange{
+			action:   "ionice",
+			argument: prio.String(),
+			question: fmt.Sprintf("Change the I/O priority from %s to %s?", ioPriorityLabel(sched), prio),
+			denied:   "The realtime class, or changing processes of other users, requires root.",
+			apply: func(ctx context.Context, key monitor.ProcessKey) error {
+				return tuning.SetIOPriority(ctx, key, prio)
+			},
+		}, nil
+	})
+}
+
+// showAffinityDialog lets the user change the CPUs the selected process may run on
+func (ui *UI) showAffinityDialog() {
+	target, sched, ok := ui.tuningTarget()
+	if !ok {
+		return
+	}
+	tuning := ui.tuning // The fleet selection may change meanwhile
+
+	current := monitor.FormatCPUList(sched.Affinity)
+	form := tview.NewForm().
+		AddInputField("CPUs (e.g. 0-3,6)", current, 20, nil, nil)
+	ui.showTuningDialog(target, "CPU affinity", "Current: "+affinityLabel(sched.Affinity), form, func() (tuningChange, error) {
+		cpus, err := monitor.ParseCPUList(form.GetFormItem(0).(*tview.InputField).GetText())
+		if err != nil {
+			return tuningChange{}, err
+		}
+		list := monitor.FormatCPUList(cpus)
+		return tuningChange{
+			action:   "affinity",
+			argument: list,
+			question: fmt.Sprintf("Restrict to CPUs %s instead of %s?", list, current),
+			denied:   "Changing processes of other users requires root or CAP_SYS_NICE.",
+			apply: func(ctx context.Context, key monitor.ProcessKey) error {
+				return tuning.SetAffinity(ctx, key, cpus)
+			},
+		}, nil
+	})
+}
+
+// showTuningDialog shows a scheduling form with the current value. Apply asks
+// for confirmation like a signal, then makes the change and records it in the
+// audit log. Errors are shown above the form, which stays open to try again.
+func (ui *UI) showTuningDialog(target processTarget, title, current string, form *tview.Form, parse func() (tuningChange, error)) {
+	status := tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	status.SetText("[white]" + tview.Escape(current) + "[-]")
+	showError := func(text string) {
+		status.SetText("[red]" + tview.Escape(text) + "[-]")
+	}
+	closeDialog := func() {
+		ui.pages.RemovePage("tuning")
+		ui.triggerUpdate()
+	}
+
+	form.AddButton("Apply", func() {
+		change, err := parse()
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		ui.confirmTuning(target, change, form, func(err error) {
+			switch {
+			case err == nil:
+				closeDialog()
+			case errors.Is(err, os.ErrPermission):
+				showError(fmt.Sprintf("%v\n%s", err, change.denied))
+			default:
+				showError(err.Error())
+			}
+			ui.app.SetFocus(form)
+		})
+	})
+	form.AddButton("Cancel", closeDialog)
+	form.SetCancelFunc(closeDialog)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(status, 3, 0, false).
+		AddItem(form, 0, 1, true)
+	layout.SetBorder(true).SetTitle(fmt.Sprintf(" %s of %s (PID %d) ", title, tview.Escape(target.name), target.key.PID))
+
+	// Center the form like a modal
+	height := 3 + 2*form.GetFormItemCount() + 3 + 2
+	dialog := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, height, 0, true).
+			AddItem(nil, 0, 1, false), 64, 0, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("tuning", dialog, true, true)
+	ui.app.SetFocus(form)
+}
+
+// confirmTuning asks before changing the scheduling of a process, then makes
+// the change in the background and passes its outcome to done on the UI
+// goroutine. Cancelling returns to the form without calling done.
+func (ui *UI) confirmTuning(target processTarget, change tuningChange, form tview.Primitive, done func(error)) {
+	text := fmt.Sprintf("%s (PID %d): %s\n\n%s\n\nApplies to all threads of the process.",
+		tview.Escape(target.name), target.key.PID, change.question, shortCmdline(target.cmdline))
+	var host, agent string
+	if ui.remote != nil {
+		state := ui.remote.State()
+		host, agent = state.Hostname, state.Address
+		text += fmt.Sprintf("\n\nThe agent on %s makes the change.", host)
+	}
+
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Apply", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ui.pages.RemovePage("confirm")
+			if buttonLabel != "Apply" {
+				ui.app.SetFocus(form)
+				return
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+				err := change.apply(ctx, target.key)
+				cancel()
+				if auditErr := ui.recordAction(host, agent, target, change.action, change.argument, 0, err); auditErr != nil && err == nil {
+					err = fmt.Errorf("changed, but the action could not be recorded: %w", auditErr)
+				}
+				ui.app.QueueUpdateDraw(func() {
+					done(err)
+				})
+			}()
+		})
+
+	ui.pages.AddPage("confirm", modal, false, true)
+}
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"hyperbyte-proc-monitor/internal/audit"
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// AuditLog records the actions taken on processes
+type AuditLog interface {
+	Record(entry audit.Entry) error
+}
+
+// SetAuditLog records every process action in an audit log
+func (ui *UI) SetAuditLog(log AuditLog) {
+	ui.audit = log
+}
+
+// CommandLines reads the command line of a process outside the top list,
+// whose details are not collected on every update
+type CommandLines interface {
+	Cmdline(ctx context.Context, key monitor.ProcessKey) (string, error)
+}
+
+// SetCommandLines lets confirmation dialogs show the command line of any process
+func (ui *UI) SetCommandLines(cmdlines CommandLines) {
+	ui.cmdlines = cmdlines
+}
+
+// maxReportedFailures limits the failures listed after signalling a process tree
+const maxReportedFailures = 5
+
+// processTarget is a process to act on, as it was when the user confirmed
+type processTarget struct {
+	key     monitor.ProcessKey
+	name    string
+	cmdline string
+}
+
+// newProcessTarget describes a process to act on
+func newProcessTarget(proc monitor.ProcessInfo) processTarget {
+	return processTarget{key: proc.Key(), name: proc.Name, cmdline: proc.Cmdline}
+}
+
+// showSignalMenu lets the user pick a signal for a process
+func (ui *UI) showSignalMenu(key monitor.ProcessKey) {
+	if ui.actions == nil {
+		ui.showMessage("Process actions are not available in a replay")
+		return
+	}
+	node := ui.monitor.GetProcessTree().Node(key)
+	if node == nil {
+		ui.showMessage(fmt.Sprintf("Process %d has exited", key.PID))
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(false)
+	for i, info := range monitor.Signals {
+		list.AddItem(fmt.Sprintf("%-8s %s", info.Name, info.Description), "", rune('1'+i), func() {
+			ui.pages.RemovePage("signals")
+			ui.confirmSignal(node, info)
+		})
+	}
+	list.SetDoneFunc(func() {
+		ui.pages.RemovePage("signals")
+	})
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" Signal %s (PID %d) ", tview.Escape(node.Process.Name), key.PID))
+
+	// Center the list like a modal
+	dialog := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, len(monitor.Signals)+2, 0, true).
+			AddItem(nil, 0, 1, false), 56, 0, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("signals", dialog, true, true)
+	ui.app.SetFocus(list)
+}
+
+// confirmSignal asks before signalling a process, or the process and all its
+// descendants
+func (ui *UI) confirmSignal(node *monitor.ProcessNode, info monitor.SignalInfo) {
+	proc := node.Process
+	if proc.Cmdline == "" && ui.cmdlines != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if cmdline, err := ui.cmdlines.Cmdline(ctx, proc.Key()); err == nil {
+			proc.Cmdline = cmdline
+		}
+		cancel()
+	}
+	text := fmt.Sprintf("Send %s (%s) to %s (PID %d)?\n\n%s",
+		info.Name, strings.ToLower(info.Description), tview.Escape(proc.Name), proc.PID, shortCmdline(proc.Cmdline))
+
+	buttons := []string{"Send"}
+	treeButton := ""
+	if node.Descendants > 0 {
+		treeButton = fmt.Sprintf("Send to tree (%d)", node.Descendants+1)
+		buttons = append(buttons, treeButton)
+		text += fmt.Sprintf("\n\nIts tree has %d more processes.", node.Descendants)
+	}
+	buttons = append(buttons, "Cancel")
+
+	// Keep the host of the process, the fleet selection may change meanwhile
+	actions := ui.actions
+	var host, agent string
+	if ui.remote != nil {
+		state := ui.remote.State()
+		host, agent = state.Hostname, state.Address
+		text += fmt.Sprintf("\n\nThe agent on %s sends the signal.", host)
+	}
+
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ui.pages.RemovePage("confirm")
+			switch {
+			case buttonLabel == "Send":
+				targets := []processTarget{newProcessTarget(proc)}
+				go ui.sendSignal(actions, host, agent, targets, info, false)
+			case treeButton != "" && buttonLabel == treeButton:
+				go ui.sendSignal(actions, host, agent, treeTargets(node), info, true)
+			}
+		})
+
+	ui.pages.AddPage("confirm", modal, false, true)
+}
+
+// shortCmdline formats a command line for a confirmation dialog
+func shortCmdline(cmdline string) string {
+	if cmdline == "" {
+		return "(command line unknown)"
+	}
+	if runes := []rune(cmdline); len(runes) > 200 {
+		cmdline = string(runes[:200]) + "…"
+	}
+	return orDash(cmdline)
+}
+
+// treeTargets lists a process and its descendants, each parent before its
+// children so that a parent cannot respawn children that were already signalled
+func treeTargets(node *monitor.ProcessNode) []processTarget {
+	targets := []processTarget{newProcessTarget(node.Process)}
+	for _, child := range node.Children {
+		targets = append(targets, treeTargets(child)...)
+	}
+	return targets
+}
+
+// sendSignal signals the targets one after the other, records each in the
+// audit log and reports failures in a dialog. The first target is the root
+// of the tree when signalling a whole tree.
+func (ui *UI) sendSignal(actions ProcessActions, host, agent string, targets []processTarget, info monitor.SignalInfo, tree bool) {
+	var failures []string
+	var denied bool
+	var auditErr error
+	var treeRoot int32
+	if tree {
+		treeRoot = targets[0].key.PID
+	}
+	for _, target := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+		err := actions.Signal(ctx, target.key, info.Signal)
+		cancel()
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s (PID %d): %v", target.name, target.key.PID, err))
+			denied = denied || errors.Is(err, os.ErrPermission)
+		}
+
+		if err := ui.recordAction(host, agent, target, "signal", info.Name, treeRoot, err); err != nil && auditErr == nil {
+			auditErr = err
+		}
+	}
+
+	if len(failures) == 0 && auditErr == nil {
+		ui.triggerUpdate()
+		return
+	}
+
+	var text string
+	switch {
+	case len(failures) == 0:
+	case tree:
+		text = fmt.Sprintf("%s reached %d of %d processes in the tree of %s (PID %d):\n\n",
+			info.Name, len(targets)-len(failures), len(targets), targets[0].name, targets[0].key.PID)
+		if len(failures) > maxReportedFailures {
+			failures = append(failures[:maxReportedFailures], fmt.Sprintf("and %d more", len(failures)-maxReportedFailures))
+		}
+		text += strings.Join(failures, "\n")
+	default:
+		text = fmt.Sprintf("Failed to send %s to %s", info.Name, failures[0])
+	}
+	if denied {
+		text += "\n\nPermission denied: only root or the owner of a process may signal it."
+	}
+	if auditErr != nil {
+		text = strings.TrimSpace(text + "\n\nThe action could not be recorded: " + auditErr.Error())
+	}
+	ui.app.QueueUpdateDraw(func() {
+		ui.showMessage(tview.Escape(text))
+	})
+	ui.triggerUpdate()
+}
+
+// recordAction writes an action and its outcome to the audit log, if there is one
+func (ui *UI) recordAction(host, agent string, target processTarget, action, argument string, treeRoot int32, err error) error {
+	if ui.audit == nil {
+		return nil
+	}
+	entry := audit.Entry{
+		Host:      host,
+		Agent:     agent,
+		PID:       target.key.PID,
+		StartTime: time.UnixMilli(target.key.StartTime),
+		Name:      target.name,
+		Cmdline:   target.cmdline,
+		Action:    action,
+		Argument:  argument,
+		TreeRoot:  treeRoot,
+		Result:    audit.ResultOK,
+	}
+	if err != nil {
+		entry.Result = audit.ResultFailed
+		entry.Error = err.Error()
+	}
+	return ui.audit.Record(entry)
+}
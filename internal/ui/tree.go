@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"strings"
+
+	"hyperbyte-proc-monitor/internal/monitor"
+)
+
+// processRow is a row of the process table
+type processRow struct {
+	host   int // Index into UI.hosts, 0 without a fleet
+	proc   monitor.ProcessInfo
+	prefix string               // Tree guides in front of the name, empty in flat mode
+	node   *monitor.ProcessNode // Set in tree mode
+}
+
+// processRows returns the rows of the process table: the sorted and filtered
+// process list, or the process tree of each shown host
+func (ui *UI) processRows() []processRow {
+	if ui.treeMode {
+		return ui.treeRows()
+	}
+	if ui.hosts != nil {
+		return ui.fleetProcesses()
+	}
+
+	processes := monitor.FilterProcesses(ui.monitor.GetProcesses(), ui.searchQuery)
+	rows := make([]processRow, len(processes))
+	for i, proc := range processes {
+		rows[i] = processRow{proc: proc}
+	}
+	return rows
+}
+
+// treeRows lists the process trees of the shown hosts, one after the other in a fleet
+func (ui *UI) treeRows() []processRow {
+	sortBy, desc := ui.monitor.GetSorting()
+
+	var rows []processRow
+	addTree := func(host int, tree *monitor.ProcessTree) {
+		var last []bool // Whether the ancestor at each depth is the last child
+		for _, row := range tree.Rows(sortBy, desc, ui.searchQuery, ui.collapsed) {
+			last = append(last[:row.Depth], row.Last)
+			rows = append(rows, processRow{
+				host:   host,
+				proc:   row.Node.Process,
+				prefix: treePrefix(last, row),
+				node:   row.Node,
+			})
+		}
+	}
+
+	if ui.hosts == nil {
+		addTree(0, ui.monitor.GetProcessTree())
+		return rows
+	}
+	for i, host := range ui.hosts {
+		if ui.hostFilter == allHosts || ui.hostFilter == i {
+			addTree(i, host.Monitor.GetProcessTree())
+		}
+	}
+	return rows
+}
+
+// treePrefix draws the guides in front of a process name. A "+" marks a
+// collapsed process, a "┬" one whose children are listed below it.
+func treePrefix(last []bool, row monitor.TreeRow) string {
+	marker := " "
+	switch {
+	case row.Collapsed:
+		marker = "+"
+	case len(row.Node.Children) > 0:
+		marker = "┬"
+	}
+	if row.Depth == 0 {
+		if row.Collapsed {
+			return "+ "
+		}
+		return ""
+	}
+
+	var prefix strings.Builder
+	for depth := 1; depth < row.Depth; depth++ {
+		if last[depth] {
+			prefix.WriteString("   ")
+		} else {
+			prefix.WriteString("│  ")
+		}
+	}
+	if row.Last {
+		prefix.WriteString("└─")
+	} else {
+		prefix.WriteString("├─")
+	}
+	if marker == " " {
+		marker = "─"
+	}
+	prefix.WriteString(marker + " ")
+	return prefix.String()
+}
+
+// toggleTreeMode switches the process table between the flat list and the tree
+func (ui *UI) toggleTreeMode() {
+	ui.treeMode = !ui.treeMode
+	ui.setProcessHeaders()
+	ui.updateStatusBar()
+	ui.triggerUpdate()
+}
+
+// toggleTreeTotals switches the tree between own and subtree CPU and memory usage
+func (ui *UI) toggleTreeTotals() {
+	ui.treeTotals = !ui.treeTotals
+	ui.setProcessHeaders()
+	ui.triggerUpdate()
+}
+
+// toggleCollapsed collapses or expands the subtree of a table row
+func (ui *UI) toggleCollapsed(row int) {
+	if !ui.treeMode || row < 1 || row > len(ui.rowKeys) {
+		return
+	}
+	ui.selectRow(row)
+	key := ui.rowKeys[row-1]
+	if node := ui.monitor.GetProcessTree().Node(key); node == nil || len(node.Children) == 0 {
+		return
+	}
+	if ui.collapsed[key] {
+		delete(ui.collapsed, key)
+	} else {
+		ui.collapsed[key] = true
+	}
+	ui.triggerUpdate()
+}
+
+// treeLabel marks tree mode in the status bar
+func (ui *UI) treeLabel() string {
+	if !ui.treeMode {
+		return ""
+	}
+	if ui.treeTotals {
+		return "[blue]View: tree, subtree totals[-] "
+	}
+	return "[blue]View: tree[-] "
+}
@@ -2,23 +2,80 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
+	"hyperbyte-proc-monitor/internal/alert"
 	"hyperbyte-proc-monitor/internal/monitor"
+	"hyperbyte-proc-monitor/internal/remote"
+	"hyperbyte-proc-monitor/internal/session"
+	"hyperbyte-proc-monitor/internal/storage"
 )
 
+// Seek steps of the replay controls
+const (
+	seekStep     = 10 * time.Second
+	seekLongStep = time.Minute
+)
+
+// History provides recorded samples of processes, including ones that are
+// no longer running
+type History interface {
+	Processes() ([]storage.ProcessSummary, error)
+	Process(key monitor.ProcessKey) (storage.ProcessSummary, bool)
+	ProcessHistory(key monitor.ProcessKey, resolution time.Duration) ([]monitor.MetricSample, error)
+}
+
+// Playback controls a replayed session
+type Playback interface {
+	TogglePause()
+	ChangeSpeed(faster bool)
+	Seek(offset time.Duration)
+	State() session.PlaybackState
+}
+
+// ProcessActions acts on the monitored processes, locally or through an agent
+type ProcessActions interface {
+	Signal(ctx context.Context, key monitor.ProcessKey, sig syscall.Signal) error
+}
+
+// Remote reports the connection to the agent whose processes are shown
+type Remote interface {
+	State() remote.ConnectionState
+}
+
+// actionTimeout bounds how long the UI waits for a process action
+const actionTimeout = 15 * time.Second
+
+// errorDisplayTime is how long a background error stays in the status bar
+const errorDisplayTime = 30 * time.Second
+
+// Pages shown as dialogs on top of a view. Keys go to the dialog while one is open.
+var dialogPages = map[string]bool{"help": true, "confirm": true, "message": true, "columns": true, "signals": true, "tuning": true}
+
 // UI represents the main UI controller
 type UI struct {
-	app     *tview.Application
-	pages   *tview.Pages
-	monitor *monitor.Monitor
+	app      *tview.Application
+	pages    *tview.Pages
+	monitor  *monitor.Monitor
+	history  History
+	playback Playback
+	actions  ProcessActions
+	remote   Remote
+	alerts   Alerts
+	audit    AuditLog
+	tuning   ProcessTuning
+	fds      FileDescriptors
+	cmdlines CommandLines
 
 	// Main view components
 	processTable *tview.Table
@@ -33,11 +90,45 @@ type UI struct {
 	networkGraph *SparklineGraph
 	processInfo  *tview.TextView
 
+	// Open files view components
+	filesTable     *tview.Table
+	filesSummary   *tview.TextView
+	filesFilter    string
+	filesFiltering bool
+
+	// History view components
+	historyTable *tview.Table
+
+	// Alerts view components
+	alertsTable *tview.Table
+	alertRows   []alert.Alert
+
+	// Fleet, set when showing several hosts
+	hosts      []Host
+	hostFilter int // Host shown in the process table, or allHosts
+	hostsTable *tview.Table
+
 	// State
-	selectedPID int32
-	currentView string
-	searchQuery string
-	isSearching bool
+	selectedKey  monitor.ProcessKey
+	rowKeys      []monitor.ProcessKey // Process shown on each table row, below the header
+	rowHosts     []int                // Host of each table row in a fleet
+	detailNotice string
+	historyTier  int // Resolution tier shown in the detail graphs
+	historyRows  []storage.ProcessSummary
+	archived     *storage.ProcessSummary // Set when the detail view shows a process from the history only
+	stored       []monitor.MetricSample  // Recorded samples of the selected process, CPU per-core
+	currentView  string
+	searchQuery  string
+	isSearching  bool
+	treeMode     bool                        // Process table shows the process tree
+	treeTotals   bool                        // Tree shows subtree CPU and memory usage
+	collapsed    map[monitor.ProcessKey]bool // Processes whose children are hidden in the tree
+	columns      map[string]bool             // Optional process table columns shown
+
+	// Last background error, reported from other goroutines
+	errMu     sync.Mutex
+	lastErr   string
+	lastErrAt time.Time
 
 	// Channels for communication
 	updateChan chan struct{}
@@ -53,12 +144,18 @@ func NewUI(mon *monitor.Monitor) *UI {
 		pages:       tview.NewPages(),
 		monitor:     mon,
 		currentView: "main",
+		collapsed:   make(map[monitor.ProcessKey]bool),
+		columns:     make(map[string]bool),
 		updateChan:  make(chan struct{}, 1),
 		quitChan:    make(chan struct{}),
 	}
 
 	ui.setupMainView()
 	ui.setupDetailView()
+	ui.setupFilesView()
+	ui.setupHistoryView()
+	ui.setupHostsView()
+	ui.setupAlertsView()
 	ui.setupKeyBindings()
 
 	app.SetRoot(ui.pages, true)
@@ -66,6 +163,51 @@ func NewUI(mon *monitor.Monitor) *UI {
 	return ui
 }
 
+// SetHistory enables browsing recorded history, including exited processes
+func (ui *UI) SetHistory(history History) {
+	ui.history = history
+}
+
+// SetPlayback shows replay controls and status for a recorded session
+func (ui *UI) SetPlayback(playback Playback) {
+	ui.playback = playback
+}
+
+// SetProcessActions enables signalling processes
+func (ui *UI) SetProcessActions(actions ProcessActions) {
+	ui.actions = actions
+}
+
+// SetRemote shows the state of the connection to an agent
+func (ui *UI) SetRemote(remote Remote) {
+	ui.remote = remote
+}
+
+// Refresh schedules a redraw with the latest data
+func (ui *UI) Refresh() {
+	ui.triggerUpdate()
+}
+
+// ReportError shows an error of a background task in the status bar for a
+// while, instead of printing over the screen. It may be called from any goroutine.
+func (ui *UI) ReportError(text string) {
+	ui.errMu.Lock()
+	ui.lastErr = text
+	ui.lastErrAt = time.Now()
+	ui.errMu.Unlock()
+	ui.triggerUpdate()
+}
+
+// errorLabel returns the last background error for the status bar, if recent
+func (ui *UI) errorLabel() string {
+	ui.errMu.Lock()
+	defer ui.errMu.Unlock()
+	if ui.lastErr == "" || time.Since(ui.lastErrAt) > errorDisplayTime {
+		return ""
+	}
+	return "[red]" + tview.Escape(ui.lastErr) + "[-] "
+}
+
 // Run starts the UI
 func (ui *UI) Run(ctx context.Context) error {
 	// Start the update goroutine
@@ -95,15 +237,7 @@ func (ui *UI) setupMainView() {
 		SetSelectable(true, false).
 		SetFixed(1, 0)
 
-	// Set table headers
-	headers := []string{"PID", "Name", "CPU%", "Memory%", "Memory(MB)"}
-	for i, header := range headers {
-		cell := tview.NewTableCell(header).
-			SetTextColor(tcell.ColorYellow).
-			SetAlign(tview.AlignCenter).
-			SetSelectable(false)
-		ui.processTable.SetCell(0, i, cell)
-	}
+	ui.setProcessHeaders()
 
 	// Create status bar
 	ui.statusBar = tview.NewTextView().
@@ -113,7 +247,7 @@ func (ui *UI) setupMainView() {
 	// Create help text
 	ui.helpText = tview.NewTextView().
 		SetDynamicColors(true).
-		SetText("[green]Keybindings:[-] [white]↑↓[-] Navigate [white]Enter[-] Details [white]q[-] Quit [white]/[-] Search [white]ESC[-] Clear [white]c[-] CPU Sort [white]m[-] Memory Sort [white]p[-] PID Sort [white]n[-] Name Sort [white]h[-] Help")
+		SetText("[green]Keybindings:[-] [white]↑↓[-] Navigate [white]Enter[-] Details [white]q[-] Quit [white]/[-] Search [white]ESC[-] Clear [white]c[-] CPU Sort [white]m[-] Memory Sort [white]p[-] PID Sort [white]n[-] Name Sort [white]i[-] CPU Mode [white]o[-] History [white]a[-] Alerts [white]t[-] Tree [white]f[-] Columns [white]k[-] Signal [white]h[-] Help")
 
 	// Create main layout
 	mainFlex := tview.NewFlex().
@@ -127,6 +261,29 @@ func (ui *UI) setupMainView() {
 	ui.pages.AddPage("main", mainFlex, true, true)
 }
 
+// setProcessHeaders sets the process table headers, with a host column in a
+// fleet and the optional columns last. The rows are filled by the next update.
+func (ui *UI) setProcessHeaders() {
+	headers := []string{"PID", "Name", "CPU%", "Memory%", "Memory(MB)"}
+	if ui.treeMode && ui.treeTotals {
+		headers = []string{"PID", "Name", "Tree CPU%", "Tree Memory%", "Tree Memory(MB)"}
+	}
+	if ui.hosts != nil {
+		headers = append([]string{"Host"}, headers...)
+	}
+	for _, col := range ui.shownColumns() {
+		headers = append(headers, col.header)
+	}
+	ui.processTable.Clear()
+	for i, header := range headers {
+		cell := tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(tview.AlignCenter).
+			SetSelectable(false)
+		ui.processTable.SetCell(0, i, cell)
+	}
+}
+
 func (ui *UI) setupDetailView() {
 	// Create graphs
 	ui.cpuGraph = NewGraph("CPU Usage", "%", 8)
@@ -158,24 +315,50 @@ func (ui *UI) setupDetailView() {
 		AddItem(ui.processInfo, 0, 1, false).
 		AddItem(graphsCol, 0, 2, false)
 
-	ui.detailFlex.SetBorder(true).SetTitle(" Process Details - ESC or q to return ")
+	ui.detailFlex.SetBorder(true).SetTitle(" Process Details - ESC or q to return, r to change resolution, f for open files ")
 
 	ui.pages.AddPage("detail", ui.detailFlex, true, false)
 }
 
+func (ui *UI) setupHistoryView() {
+	ui.historyTable = tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+
+	ui.historyTable.SetBorder(true).SetTitle(" Recorded History - Enter for details, ESC or q to return ")
+
+	ui.pages.AddPage("history", ui.historyTable, true, false)
+}
+
 func (ui *UI) setupKeyBindings() {
 	ui.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if name, _ := ui.pages.GetFrontPage(); dialogPages[name] {
+			return event
+		}
 		switch ui.currentView {
 		case "main":
 			return ui.handleMainViewKeys(event)
 		case "detail":
 			return ui.handleDetailViewKeys(event)
+		case "files":
+			return ui.handleFilesViewKeys(event)
+		case "history":
+			return ui.handleHistoryViewKeys(event)
+		case "hosts":
+			return ui.handleHostsViewKeys(event)
+		case "alerts":
+			return ui.handleAlertsViewKeys(event)
 		}
 		return event
 	})
 }
 
 func (ui *UI) handleMainViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	if !ui.isSearching && ui.handlePlaybackKeys(event) {
+		return nil
+	}
+
 	switch event.Key() {
 	case tcell.KeyEsc:
 		if ui.isSearching {
@@ -185,21 +368,21 @@ func (ui *UI) handleMainViewKeys(event *tcell.EventKey) *tcell.EventKey {
 			ui.triggerUpdate()
 			return nil
 		}
-		// Ignore ESC on main view when not searching
+		// Go back to the hosts in a fleet, otherwise ignore ESC
+		if ui.hosts != nil {
+			ui.showHostsView()
+		}
 		return nil
 
 	case tcell.KeyEnter:
 		row, _ := ui.processTable.GetSelection()
-		if row > 0 && row < ui.processTable.GetRowCount() {
-			pidCell := ui.processTable.GetCell(row, 0)
-			if pidStr := pidCell.Text; pidStr != "" {
-				if pid, err := strconv.ParseInt(pidStr, 10, 32); err == nil {
-					ui.selectedPID = int32(pid)
-					// Ensure this process has time series metrics tracking
-					ui.monitor.EnsureProcessMetrics(int32(pid))
-					ui.showDetailView()
-				}
-			}
+		if row > 0 && row <= len(ui.rowKeys) {
+			ui.selectRow(row)
+			ui.selectedKey = ui.rowKeys[row-1]
+			ui.archived = nil
+			// Ensure this process has time series metrics tracking
+			ui.monitor.EnsureProcessMetrics(ui.selectedKey)
+			ui.showDetailView()
 		}
 		return nil
 
@@ -217,24 +400,49 @@ func (ui *UI) handleMainViewKeys(event *tcell.EventKey) *tcell.EventKey {
 			ui.cycleSorting()
 			return nil
 		case 'c', 'C':
-			ui.monitor.SetSorting(monitor.SortByCPU, true)
-			ui.triggerUpdate()
+			ui.setSorting(monitor.SortByCPU, true)
 			return nil
 		case 'm', 'M':
-			ui.monitor.SetSorting(monitor.SortByMemory, true)
-			ui.triggerUpdate()
+			ui.setSorting(monitor.SortByMemory, true)
 			return nil
 		case 'p', 'P':
-			ui.monitor.SetSorting(monitor.SortByPID, false)
-			ui.triggerUpdate()
+			ui.setSorting(monitor.SortByPID, false)
 			return nil
 		case 'n', 'N':
-			ui.monitor.SetSorting(monitor.SortByName, false)
-			ui.triggerUpdate()
+			ui.setSorting(monitor.SortByName, false)
+			return nil
+		case 'i', 'I':
+			ui.toggleCPUMode()
+			return nil
+		case 'o', 'O':
+			ui.showHistoryView()
+			return nil
+		case 'a', 'A':
+			ui.showAlertsView()
+			return nil
+		case 't', 'T':
+			ui.toggleTreeMode()
+			return nil
+		case 'x', 'X':
+			row, _ := ui.processTable.GetSelection()
+			ui.toggleCollapsed(row)
+			return nil
+		case 'u', 'U':
+			ui.toggleTreeTotals()
+			return nil
+		case 'f', 'F':
+			ui.showColumnsDialog()
 			return nil
 		case 'h', 'H':
 			ui.showHelpDialog()
 			return nil
+		case 'k', 'K':
+			row, _ := ui.processTable.GetSelection()
+			if row > 0 && row <= len(ui.rowKeys) {
+				ui.selectRow(row)
+				ui.showSignalMenu(ui.rowKeys[row-1])
+			}
+			return nil
 		default:
 			if ui.isSearching {
 				ui.searchQuery += string(event.Rune())
@@ -256,13 +464,129 @@ func (ui *UI) handleMainViewKeys(event *tcell.EventKey) *tcell.EventKey {
 	return event
 }
 
+// selectRow selects the host of a process table row in a fleet
+func (ui *UI) selectRow(row int) {
+	if ui.hosts != nil {
+		ui.selectHost(ui.rowHosts[row-1])
+	}
+}
+
 func (ui *UI) handleDetailViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	if ui.handlePlaybackKeys(event) {
+		return nil
+	}
+
 	switch event.Key() {
 	case tcell.KeyEsc:
 		ui.showMainView()
 		return nil
 	}
 
+	switch event.Rune() {
+	case 'q', 'Q':
+		ui.showMainView()
+		return nil
+	case 'r', 'R':
+		ui.cycleHistoryTier()
+		return nil
+	case 'k', 'K':
+		if ui.archived == nil {
+			ui.showSignalMenu(ui.selectedKey)
+		}
+		return nil
+	case 'n', 'N':
+		ui.showNiceDialog()
+		return nil
+	case 'o', 'O':
+		ui.showIOPriorityDialog()
+		return nil
+	case 'a', 'A':
+		ui.showAffinityDialog()
+		return nil
+	case 'f', 'F':
+		ui.showFilesView()
+		return nil
+	}
+
+	return event
+}
+
+// handlePlaybackKeys handles the replay controls and reports whether the key was used
+func (ui *UI) handlePlaybackKeys(event *tcell.EventKey) bool {
+	if ui.playback == nil {
+		return false
+	}
+
+	switch event.Key() {
+	case tcell.KeyLeft:
+		ui.playback.Seek(-seekStep)
+	case tcell.KeyRight:
+		ui.playback.Seek(seekStep)
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case ' ':
+			ui.playback.TogglePause()
+		case '+', '=':
+			ui.playback.ChangeSpeed(true)
+		case '-':
+			ui.playback.ChangeSpeed(false)
+		case '<', ',':
+			ui.playback.Seek(-seekLongStep)
+		case '>', '.':
+			ui.playback.Seek(seekLongStep)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+
+	ui.triggerUpdate()
+	return true
+}
+
+// playbackLabel describes the replay position for the status bar
+func (ui *UI) playbackLabel() string {
+	state := ui.playback.State()
+	symbol := "▶"
+	if state.Paused {
+		symbol = "⏸"
+	}
+	return fmt.Sprintf("[fuchsia]REPLAY %s %gx %s (%s / %s)[-] ",
+		symbol,
+		state.Speed,
+		state.Position.Format("15:04:05"),
+		state.Position.Sub(state.Start).Round(time.Second),
+		state.End.Sub(state.Start).Round(time.Second),
+	)
+}
+
+// remoteLabel describes the connection to the agent for the status bar
+func (ui *UI) remoteLabel() string {
+	state := ui.remote.State()
+	if !state.Connected {
+		label := "[red]DISCONNECTED " + state.Address
+		if state.Err != nil {
+			label += ": " + tview.Escape(state.Err.Error())
+		}
+		return label + "[-] "
+	}
+	return fmt.Sprintf("[aqua]%s (%s)[-] ", state.Hostname, state.Address)
+}
+
+func (ui *UI) handleHistoryViewKeys(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEsc:
+		ui.showMainView()
+		return nil
+	case tcell.KeyEnter:
+		row, _ := ui.historyTable.GetSelection()
+		if row > 0 && row <= len(ui.historyRows) {
+			ui.openRecordedProcess(ui.historyRows[row-1])
+		}
+		return nil
+	}
+
 	switch event.Rune() {
 	case 'q', 'Q':
 		ui.showMainView()
@@ -273,11 +597,153 @@ func (ui *UI) handleDetailViewKeys(event *tcell.EventKey) *tcell.EventKey {
 }
 
 func (ui *UI) showDetailView() {
+	ui.detailNotice = ""
+	ui.resetDetailGraphs()
+	ui.loadStoredSamples()
 	ui.currentView = "detail"
 	ui.pages.SwitchToPage("detail")
 	ui.app.SetFocus(ui.detailFlex)
 }
 
+// showHistoryView lists the processes found in the on-disk history
+func (ui *UI) showHistoryView() {
+	ui.historyTable.Clear()
+	ui.historyRows = nil
+	ui.currentView = "history"
+	ui.pages.SwitchToPage("history")
+	ui.app.SetFocus(ui.historyTable)
+
+	if ui.history == nil {
+		ui.historyTable.SetCell(0, 0, tview.NewTableCell("History recording is disabled (start with -history)").
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		return
+	}
+	ui.historyTable.SetCell(0, 0, tview.NewTableCell("Loading history...").
+		SetTextColor(tcell.ColorYellow).SetSelectable(false))
+
+	// Indexing a large history can take a moment, so don't block the UI on it
+	go func() {
+		summaries, err := ui.history.Processes()
+		ui.app.QueueUpdateDraw(func() {
+			ui.fillHistoryTable(summaries, err)
+		})
+	}()
+}
+
+// fillHistoryTable shows the recorded processes, marking those still running
+func (ui *UI) fillHistoryTable(summaries []storage.ProcessSummary, err error) {
+	ui.historyTable.Clear()
+	if err != nil {
+		ui.historyTable.SetCell(0, 0, tview.NewTableCell(fmt.Sprintf("Failed to read history: %v", err)).
+			SetTextColor(tcell.ColorRed).SetSelectable(false))
+		return
+	}
+
+	headers := []string{"PID", "Name", "Started", "Last Seen", "Status"}
+	for i, header := range headers {
+		ui.historyTable.SetCell(0, i, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(tview.AlignCenter).
+			SetSelectable(false))
+	}
+
+	running := make(map[monitor.ProcessKey]bool)
+	for _, proc := range ui.monitor.GetProcesses() {
+		running[proc.Key()] = true
+	}
+
+	ui.historyRows = summaries
+	for i, summary := range summaries {
+		row := i + 1
+		status, color := "recorded", tcell.ColorGray
+		if running[summary.Key] {
+			status, color = "running", tcell.ColorGreen
+		}
+
+		ui.historyTable.SetCell(row, 0, tview.NewTableCell(strconv.Itoa(int(summary.Key.PID))).SetTextColor(color))
+		ui.historyTable.SetCell(row, 1, tview.NewTableCell(summary.Name).SetTextColor(color))
+		ui.historyTable.SetCell(row, 2, tview.NewTableCell(time.UnixMilli(summary.Key.StartTime).Format("01-02 15:04:05")).SetTextColor(color))
+		ui.historyTable.SetCell(row, 3, tview.NewTableCell(summary.LastSeen.Format("01-02 15:04:05")).SetTextColor(color))
+		ui.historyTable.SetCell(row, 4, tview.NewTableCell(status).SetTextColor(color))
+	}
+	if len(summaries) > 0 {
+		ui.historyTable.Select(1, 0)
+	}
+}
+
+// openRecordedProcess opens the detail view for a process from the history.
+// Running processes get live graphs; others are shown from the recording.
+func (ui *UI) openRecordedProcess(summary storage.ProcessSummary) {
+	ui.selectedKey = summary.Key
+	ui.archived = &summary
+	for _, proc := range ui.monitor.GetProcesses() {
+		if proc.Key() == summary.Key {
+			ui.archived = nil
+			ui.monitor.EnsureProcessMetrics(summary.Key)
+			break
+		}
+	}
+	ui.showDetailView()
+}
+
+// loadStoredSamples fetches the recorded history of the selected process at
+// the current resolution in the background
+func (ui *UI) loadStoredSamples() {
+	ui.stored = nil
+	if ui.history == nil {
+		return
+	}
+
+	key := ui.selectedKey
+	tier := ui.historyTier
+	resolution := ui.monitor.MetricTiers()[tier].Resolution
+	go func() {
+		samples, err := ui.history.ProcessHistory(key, resolution)
+		if err != nil || len(samples) == 0 {
+			return
+		}
+		ui.app.QueueUpdateDraw(func() {
+			// Drop results for a process or resolution that is no longer shown
+			if ui.selectedKey != key || ui.historyTier != tier {
+				return
+			}
+			ui.stored = samples
+			if ui.currentView == "detail" {
+				ui.updateDetailView()
+			}
+		})
+	}()
+}
+
+// storedSamples returns the recorded samples of the selected process with
+// CPU usage converted to the current mode
+func (ui *UI) storedSamples() []monitor.MetricSample {
+	numCPU := ui.monitor.GetSystemMetrics().NumCPU
+	mode := ui.monitor.GetCPUMode()
+
+	samples := make([]monitor.MetricSample, len(ui.stored))
+	for i, sample := range ui.stored {
+		sample.CPUPercent = mode.FromPerCore(sample.CPUPercent, numCPU)
+		samples[i] = sample
+	}
+	return samples
+}
+
+// withStoredSamples prepends recorded samples older than the in-memory series,
+// so the graphs reach back beyond the start of this session
+func (ui *UI) withStoredSamples(live []monitor.MetricSample) []monitor.MetricSample {
+	if len(ui.stored) == 0 || len(live) == 0 {
+		return live
+	}
+
+	stored := ui.storedSamples()
+	older := 0
+	for older < len(stored) && stored[older].Timestamp.Before(live[0].Timestamp) {
+		older++
+	}
+	return append(stored[:older], live...)
+}
+
 func (ui *UI) showMainView() {
 	ui.currentView = "main"
 	ui.pages.SwitchToPage("main")
@@ -287,10 +753,31 @@ func (ui *UI) showMainView() {
 func (ui *UI) cycleSorting() {
 	// Cycle through sorting options
 	// This is a simplified version - you could make it more sophisticated
-	ui.monitor.SetSorting(monitor.SortByCPU, true)
+	ui.setSorting(monitor.SortByCPU, true)
+}
+
+// toggleCPUMode switches between per-core and normalized CPU usage
+func (ui *UI) toggleCPUMode() {
+	mode := monitor.CPUModePerCore
+	if ui.monitor.GetCPUMode() == monitor.CPUModePerCore {
+		mode = monitor.CPUModeNormalized
+	}
+	ui.monitor.SetCPUMode(mode)
+	for _, host := range ui.hosts {
+		host.Monitor.SetCPUMode(mode)
+	}
+	ui.updateStatusBar()
 	ui.triggerUpdate()
 }
 
+// cpuModeLabel returns a short description of the current CPU mode
+func (ui *UI) cpuModeLabel() string {
+	if ui.monitor.GetCPUMode() == monitor.CPUModeNormalized {
+		return "normalized"
+	}
+	return "per-core"
+}
+
 func (ui *UI) showHelpDialog() {
 	helpText := `[yellow]Process Monitor Help[-]
 
@@ -305,11 +792,47 @@ func (ui *UI) showHelpDialog() {
   [white]m[-]       Sort by Memory usage (desc)  
   [white]p[-]       Sort by PID (asc)
   [white]n[-]       Sort by Name (asc)
+  [white]i[-]       Toggle CPU% per-core / normalized to all cores
+
+[green]Process Tree:[-]
+  [white]t[-]       Toggle flat list / process tree
+  [white]x[-]       Collapse / expand the selected subtree
+  [white]u[-]       Toggle own / subtree CPU and memory usage
+
+[green]Columns:[-]
+  [white]f[-]       Choose optional columns (user, state, threads, ...)
 
 [green]Search:[-]
   [white]/[-]       Start search
   [white]ESC[-]     Clear search
 
+[green]Detail View:[-]
+  [white]r[-]       Cycle graph resolution (1s / 10s / 1m)
+  [white]n[-]       Change the nice value
+  [white]o[-]       Change the I/O class and priority
+  [white]a[-]       Change the CPU affinity
+  [white]f[-]       Browse open files, sockets and pipes (/ to filter)
+
+[green]Processes:[-]
+  [white]k[-]       Send a signal to the selected process or its tree
+
+[green]Replay:[-]
+  [white]Space[-]   Play / pause
+  [white]+/-[-]     Faster / slower
+  [white]←/→[-]     Seek 10 seconds
+  [white]</>[-]     Seek 1 minute
+
+[green]History:[-]
+  [white]o[-]       Browse recorded processes, including exited ones
+
+[green]Alerts:[-]
+  [white]a[-]       Show firing, pending and recently resolved alerts
+
+[green]Fleet:[-]
+  [white]ESC[-]     Back to the hosts (main view)
+  [white]Enter[-]   Show the processes of a host (hosts view)
+  [white]a[-]       Show the processes of all hosts (hosts view)
+
 [green]Other:[-]
   [white]h[-]       Show this help
 
@@ -330,6 +853,18 @@ func (ui *UI) showHelpDialog() {
 	ui.pages.AddPage("help", modal, false, true)
 }
 
+// showMessage shows a dialog with a single button
+func (ui *UI) showMessage(text string) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ui.pages.RemovePage("message")
+		})
+
+	ui.pages.AddPage("message", modal, false, true)
+}
+
 func (ui *UI) updateLoop(ctx context.Context) {
 	// Reduce UI update frequency to improve performance
 	ticker := time.NewTicker(1500 * time.Millisecond) // Update UI every 1.5 seconds instead of 1
@@ -364,45 +899,54 @@ func (ui *UI) updateViews() {
 			ui.updateMainView()
 		case "detail":
 			ui.updateDetailView()
+		case "files":
+			ui.updateFilesView()
+		case "hosts":
+			ui.updateHostsView()
+		case "alerts":
+			ui.updateAlertsView()
 		}
 	})
 }
 
 func (ui *UI) updateMainView() {
-	processes := ui.monitor.GetProcesses()
-
-	// Filter processes based on search query
-	if ui.searchQuery != "" {
-		filtered := make([]monitor.ProcessInfo, 0)
-		query := strings.ToLower(ui.searchQuery)
-		for _, proc := range processes {
-			if strings.Contains(strings.ToLower(proc.Name), query) ||
-				strings.Contains(strconv.Itoa(int(proc.PID)), query) {
-				filtered = append(filtered, proc)
-			}
-		}
-		processes = filtered
-	}
+	rows := ui.processRows()
 
 	// Clear existing rows except header
 	for row := ui.processTable.GetRowCount() - 1; row > 0; row-- {
 		ui.processTable.RemoveRow(row)
 	}
+	ui.rowKeys = ui.rowKeys[:0]
+	ui.rowHosts = ui.rowHosts[:0]
 
 	// Add process rows
-	for i, proc := range processes {
+	optional := ui.shownColumns()
+	for i, r := range rows {
 		row := i + 1
+		proc := r.proc
+
+		// The tree can show the usage of whole subtrees instead
+		cpuPercent, memoryPerc, memoryMB := proc.CPUPercent, proc.MemoryPerc, proc.MemoryMB
+		name := r.prefix + proc.Name
+		if r.node != nil {
+			if ui.treeTotals {
+				cpuPercent, memoryPerc, memoryMB = r.node.TotalCPU, r.node.TotalMemoryPerc, r.node.TotalMemoryMB
+			}
+			if ui.collapsed[proc.Key()] && len(r.node.Children) > 0 {
+				name += fmt.Sprintf(" (+%d)", r.node.Descendants)
+			}
+		}
 
 		// Create cells with appropriate formatting
 		pidCell := tview.NewTableCell(strconv.Itoa(int(proc.PID)))
-		nameCell := tview.NewTableCell(proc.Name)
-		cpuCell := tview.NewTableCell(fmt.Sprintf("%.1f", proc.CPUPercent))
-		memPercCell := tview.NewTableCell(fmt.Sprintf("%.1f", proc.MemoryPerc))
-		memMBCell := tview.NewTableCell(fmt.Sprintf("%.1f", proc.MemoryMB))
+		nameCell := tview.NewTableCell(name)
+		cpuCell := tview.NewTableCell(fmt.Sprintf("%.1f", cpuPercent))
+		memPercCell := tview.NewTableCell(fmt.Sprintf("%.1f", memoryPerc))
+		memMBCell := tview.NewTableCell(fmt.Sprintf("%.1f", memoryMB))
 
 		// Determine color based on resource usage
 		var color tcell.Color = tcell.ColorWhite
-		maxUsage := math.Max(proc.CPUPercent, float64(proc.MemoryPerc))
+		maxUsage := math.Max(cpuPercent, float64(memoryPerc))
 
 		if maxUsage > 80 {
 			color = tcell.ColorRed
@@ -422,74 +966,126 @@ func (ui *UI) updateMainView() {
 		memMBCell.SetTextColor(color)
 
 		// Highlight high usage columns specifically
-		if proc.CPUPercent > 80 {
+		if cpuPercent > 80 {
 			cpuCell.SetTextColor(tcell.ColorRed).SetAttributes(tcell.AttrBold)
 		}
-		if proc.MemoryPerc > 80 {
+		if memoryPerc > 80 {
 			memPercCell.SetTextColor(tcell.ColorRed).SetAttributes(tcell.AttrBold)
 		}
 
-		ui.processTable.SetCell(row, 0, pidCell)
-		ui.processTable.SetCell(row, 1, nameCell)
-		ui.processTable.SetCell(row, 2, cpuCell)
-		ui.processTable.SetCell(row, 3, memPercCell)
-		ui.processTable.SetCell(row, 4, memMBCell)
+		col := 0
+		if ui.hosts != nil {
+			hostCell := tview.NewTableCell(ui.hosts[r.host].name()).SetTextColor(tcell.ColorAqua)
+			ui.processTable.SetCell(row, 0, hostCell)
+			col = 1
+		}
+		ui.processTable.SetCell(row, col, pidCell)
+		ui.processTable.SetCell(row, col+1, nameCell)
+		ui.processTable.SetCell(row, col+2, cpuCell)
+		ui.processTable.SetCell(row, col+3, memPercCell)
+		ui.processTable.SetCell(row, col+4, memMBCell)
+		for i, extra := range optional {
+			ui.processTable.SetCell(row, col+5+i, columnCell(extra, proc, color))
+		}
+		ui.rowKeys = append(ui.rowKeys, proc.Key())
+		ui.rowHosts = append(ui.rowHosts, r.host)
 	}
 
 	ui.updateStatusBar()
 }
 
 func (ui *UI) updateDetailView() {
-	if ui.selectedPID == 0 {
+	if ui.selectedKey.PID == 0 {
+		return
+	}
+	if ui.archived != nil {
+		ui.updateArchivedView()
 		return
 	}
 
+	// Get current process data (this will also update time series if needed)
+	currentProcess, err := ui.monitor.GetCurrentProcessData(ui.selectedKey)
+	var replaced *monitor.ProcessReplacedError
+	if errors.As(err, &replaced) {
+		// The PID now belongs to another process: follow it with fresh graphs
+		ui.detailNotice = fmt.Sprintf("[red]PID %d was reused by %q, graphs were reset[-]", replaced.Old.PID, replaced.Name)
+		ui.selectedKey = replaced.New
+		ui.monitor.EnsureProcessMetrics(ui.selectedKey)
+		ui.resetDetailGraphs()
+		ui.loadStoredSamples()
+		currentProcess, err = ui.monitor.GetCurrentProcessData(ui.selectedKey)
+	}
+
 	// Get process metrics
-	metrics := ui.monitor.GetProcessMetrics(ui.selectedPID)
+	metrics := ui.monitor.GetProcessHistory(ui.selectedKey, ui.historyTier)
 	if metrics == nil {
-		ui.processInfo.SetText("Process not found or no data available")
+		ui.showProcessGone()
 		return
 	}
 
-	// Get current process data (this will also update time series if needed)
-	currentProcess, err := ui.monitor.GetCurrentProcessData(ui.selectedPID)
 	if err != nil {
 		// Fallback to searching in the process list
 		processes := ui.monitor.GetProcesses()
 		for _, proc := range processes {
-			if proc.PID == ui.selectedPID {
+			if proc.Key() == ui.selectedKey {
 				currentProcess = &proc
 				break
 			}
 		}
 		if currentProcess == nil {
-			ui.processInfo.SetText("Process not found or no data available")
+			ui.showProcessGone()
 			return
 		}
 	}
+	metrics = ui.withStoredSamples(metrics)
 
 	if currentProcess != nil {
 		// Guard against empty timestamps to avoid panic when computing duration
 		monitoringDuration := "0s"
-		if len(metrics.Timestamps) > 0 {
-			monitoringDuration = fmt.Sprintf("%.0fs", time.Since(metrics.Timestamps[0]).Seconds())
+		if len(metrics) > 0 {
+			monitoringDuration = ui.monitor.Now().Sub(metrics[0].Timestamp).Round(time.Second).String()
+		}
+
+		notice := ""
+		if ui.detailNotice != "" {
+			notice = ui.detailNotice + "\n\n"
 		}
 
-		info := fmt.Sprintf(`[yellow]Process Information[-]
+		info := fmt.Sprintf(`%s[yellow]Process Information[-]
 
-[white]PID:[-] %d
+[white]PID:[-] %d (parent %d)
 [white]Name:[-] %s
+[white]Command:[-] %s
+[white]User:[-] %s
+[white]State:[-] %s, %d threads
+[white]Priority:[-] %d, nice %d
+[white]TTY:[-] %s
+[white]CWD:[-] %s
+[white]Exe:[-] %s
 [white]Current CPU:[-] %.1f%%
 [white]Current Memory:[-] %.1fMB (%.1f%%)
 [white]Created:[-] %s
 
 [green]Disk I/O:[-] %.1f%% (R: %.1f KB/s, W: %.1f KB/s)
 [green]Network:[-] S: %.1f KB/s, R: %.1f KB/s
+[green]Activity:[-] %.0f ctx sw/s, faults %.0f minor/s %.0f major/s
 
+%s[cyan]Resolution:[-] %s (r to change)
 [cyan]Data points:[-] %d
 [cyan]Monitoring duration:[-] %s`,
+			notice,
 			currentProcess.PID,
-			currentProcess.Name,
+			currentProcess.PPID,
+			tview.Escape(currentProcess.Name),
+			orDash(currentProcess.Cmdline),
+			orDash(currentProcess.Username),
+			stateName(currentProcess.State),
+			currentProcess.Threads,
+			currentProcess.Priority,
+			currentProcess.Nice,
+			orDash(currentProcess.TTY),
+			orDash(currentProcess.Cwd),
+			orDash(currentProcess.Exe),
 			currentProcess.CPUPercent,
 			currentProcess.MemoryMB,
 			currentProcess.MemoryPerc,
@@ -499,7 +1095,12 @@ func (ui *UI) updateDetailView() {
 			currentProcess.DiskWriteRate,
 			currentProcess.NetSentRate,
 			currentProcess.NetRecvRate,
-			len(metrics.CPUPercent),
+			currentProcess.CtxSwitchRate,
+			currentProcess.MinorFaultPS,
+			currentProcess.MajorFaultPS,
+			ui.schedulingText(),
+			ui.tierLabel(),
+			len(metrics),
 			monitoringDuration,
 		)
 		ui.processInfo.SetText(info)
@@ -507,31 +1108,136 @@ func (ui *UI) updateDetailView() {
 
 	// Update graphs
 	// Re-fetch metrics to include any updates from GetCurrentProcessData
-	metrics = ui.monitor.GetProcessMetrics(ui.selectedPID)
-	if metrics == nil || len(metrics.CPUPercent) == 0 {
+	metrics = ui.withStoredSamples(ui.monitor.GetProcessHistory(ui.selectedKey, ui.historyTier))
+	ui.updateDetailGraphs(metrics)
+}
+
+// showProcessGone handles a selected process that is no longer running,
+// falling back to its recorded history when there is one
+func (ui *UI) showProcessGone() {
+	if len(ui.stored) == 0 {
+		ui.processInfo.SetText("Process not found or no data available")
+		return
+	}
+	if ui.archived == nil {
+		// Only reached once samples were loaded, so the summary is indexed
+		summary, _ := ui.history.Process(ui.selectedKey)
+		summary.Key = ui.selectedKey
+		ui.archived = &summary
+	}
+	ui.updateArchivedView()
+}
+
+// updateArchivedView shows a process that only exists in the recorded history
+func (ui *UI) updateArchivedView() {
+	metrics := ui.storedSamples()
+	if len(metrics) == 0 {
+		ui.processInfo.SetText("Loading recorded history...")
 		return
 	}
-	if len(metrics.CPUPercent) > 0 {
+	last := metrics[len(metrics)-1]
+
+	notice := ""
+	if ui.detailNotice != "" {
+		notice = ui.detailNotice + "\n\n"
+	}
+	name := ui.archived.Name
+	if name == "" {
+		name = "(unknown)"
+	}
+
+	info := fmt.Sprintf(`%s[yellow]Process Information[-]
+
+[red]Not running - showing recorded history[-]
+
+[white]PID:[-] %d
+[white]Name:[-] %s
+[white]Created:[-] %s
+[white]Last seen:[-] %s
+
+[green]Last CPU:[-] %.1f%%
+[green]Last Memory:[-] %.1fMB
+[green]Disk I/O:[-] %.1f%% (R: %.1f KB/s, W: %.1f KB/s)
+[green]Network:[-] S: %.1f KB/s, R: %.1f KB/s
+
+[cyan]Resolution:[-] %s (r to change)
+[cyan]Data points:[-] %d
+[cyan]Recorded duration:[-] %s`,
+		notice,
+		ui.archived.Key.PID,
+		name,
+		time.UnixMilli(ui.archived.Key.StartTime).Format("2006-01-02 15:04:05"),
+		last.Timestamp.Format("2006-01-02 15:04:05"),
+		last.CPUPercent,
+		last.MemoryMB,
+		last.DiskReadPerc+last.DiskWritePerc,
+		last.DiskReadRate,
+		last.DiskWriteRate,
+		last.NetSentRate,
+		last.NetRecvRate,
+		ui.tierLabel(),
+		len(metrics),
+		last.Timestamp.Sub(metrics[0].Timestamp).Round(time.Second),
+	)
+	ui.processInfo.SetText(info)
+	ui.updateDetailGraphs(metrics)
+}
+
+// updateDetailGraphs draws the detail graphs from a series of samples
+func (ui *UI) updateDetailGraphs(metrics []monitor.MetricSample) {
+	if len(metrics) > 0 {
 		// Get system metrics for memory max
 		systemMetrics := ui.monitor.GetSystemMetrics()
 
-		ui.cpuGraph.UpdateData(metrics.CPUPercent, 100.0)
-		ui.memoryGraph.UpdateData(metrics.MemoryMB, systemMetrics.TotalMemoryMB)
+		// Per-core usage can exceed 100%, so grow the scale with the data
+		cpuData := sampleValues(metrics, func(s monitor.MetricSample) float64 { return s.CPUPercent })
+		cpuMax := 100.0
+		if ui.monitor.GetCPUMode() == monitor.CPUModePerCore {
+			for _, val := range cpuData {
+				cpuMax = math.Max(cpuMax, val)
+			}
+		}
+		ui.cpuGraph.SetTitle(fmt.Sprintf("CPU Usage (%s)", ui.cpuModeLabel()))
+		ui.cpuGraph.UpdateData(cpuData, cpuMax)
+		ui.memoryGraph.UpdateData(sampleValues(metrics, func(s monitor.MetricSample) float64 { return s.MemoryMB }), systemMetrics.TotalMemoryMB)
 
 		// Combine disk read and write percentages for display
-		diskPercData := make([]float64, len(metrics.DiskReadPerc))
-		for i := range diskPercData {
-			diskPercData[i] = metrics.DiskReadPerc[i] + metrics.DiskWritePerc[i]
-		}
-		ui.diskGraph.UpdateData(diskPercData)
+		ui.diskGraph.UpdateData(sampleValues(metrics, func(s monitor.MetricSample) float64 { return s.DiskReadPerc + s.DiskWritePerc }))
 
 		// Combine network sent and received rates for display
-		netRateData := make([]float64, len(metrics.NetSentRate))
-		for i := range netRateData {
-			netRateData[i] = metrics.NetSentRate[i] + metrics.NetRecvRate[i]
-		}
-		ui.networkGraph.UpdateData(netRateData)
+		ui.networkGraph.UpdateData(sampleValues(metrics, func(s monitor.MetricSample) float64 { return s.NetSentRate + s.NetRecvRate }))
+	}
+}
+
+// sampleValues extracts one series from a list of samples for graphing
+func sampleValues(samples []monitor.MetricSample, value func(monitor.MetricSample) float64) []float64 {
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		values[i] = value(sample)
 	}
+	return values
+}
+
+// cycleHistoryTier switches the detail graphs to the next resolution tier
+func (ui *UI) cycleHistoryTier() {
+	ui.historyTier = (ui.historyTier + 1) % len(ui.monitor.MetricTiers())
+	ui.resetDetailGraphs()
+	ui.loadStoredSamples()
+	ui.triggerUpdate()
+}
+
+// tierLabel describes the resolution tier shown in the detail graphs
+func (ui *UI) tierLabel() string {
+	tier := ui.monitor.MetricTiers()[ui.historyTier]
+	return fmt.Sprintf("%s per point, up to %s", tier.Resolution, tier.Span())
+}
+
+// resetDetailGraphs clears the detail graphs, e.g. when a different process is shown
+func (ui *UI) resetDetailGraphs() {
+	ui.cpuGraph.UpdateData(nil, 0)
+	ui.memoryGraph.UpdateData(nil, 0)
+	ui.diskGraph.UpdateData(nil)
+	ui.networkGraph.UpdateData(nil)
 }
 
 func (ui *UI) updateStatusBar() {
@@ -542,7 +1248,9 @@ func (ui *UI) updateStatusBar() {
 		processes := ui.monitor.GetProcesses()
 
 		filteredCount := len(processes)
-		if ui.searchQuery != "" {
+		if ui.treeMode {
+			filteredCount = len(ui.rowKeys) // The tree lists every process, not only the top ones
+		} else if ui.searchQuery != "" {
 			query := strings.ToLower(ui.searchQuery)
 			filteredCount = 0
 			for _, proc := range processes {
@@ -554,13 +1262,32 @@ func (ui *UI) updateStatusBar() {
 		}
 
 		statusText := fmt.Sprintf(
-			"[green]Processes: %d[-] [blue]System CPU: %.1f%%[-] [blue]Memory: %.1f%%[-] [yellow]Last updated: %s[-]",
+			"[green]Processes: %d[-] [blue]System CPU: %.1f%%[-] [blue]CPU%%: %s[-] %s[blue]Memory: %.1f%%[-] [blue]Disk: R %.0f KB/s W %.0f KB/s[-] [yellow]Last updated: %s[-]",
 			filteredCount,
 			systemMetrics.CPUPercent,
+			ui.cpuModeLabel(),
+			ui.treeLabel(),
 			systemMetrics.MemoryPercent,
+			systemMetrics.DiskReadRate,
+			systemMetrics.DiskWriteRate,
 			systemMetrics.Timestamp.Format("15:04:05"),
 		)
 
+		// A fleet sums up its hosts instead of showing one host's metrics
+		if ui.hosts != nil {
+			statusText = fmt.Sprintf("%s[green]Processes: %d[-] [blue]CPU%%: %s[-] %s",
+				ui.fleetLabel(), len(ui.rowKeys), ui.cpuModeLabel(), ui.treeLabel())
+		} else if ui.remote != nil {
+			statusText = ui.remoteLabel() + statusText
+		}
+		if ui.playback != nil {
+			statusText = ui.playbackLabel() + statusText
+		}
+		if ui.alerts != nil {
+			statusText = ui.alertsLabel() + statusText
+		}
+		statusText = ui.errorLabel() + statusText
+
 		ui.statusBar.SetText(statusText)
 	}
 }
@@ -0,0 +1,244 @@
+// Pulse dashboard: a process table fed by /api/events and the detail graphs
+// of the terminal UI, drawn on canvases. No dependencies.
+"use strict";
+
+const state = {
+  processes: [],
+  system: null,
+  cpuMode: "per-core",
+  sortKey: "cpu_percent",
+  sortDesc: true,
+  filter: "",
+  selected: null, // {pid, start_time, name}
+  tier: 0,
+};
+
+const $ = (id) => document.getElementById(id);
+
+// Text columns sort ascending first, usage columns descending, like the UI
+const textColumns = new Set(["pid", "name", "user"]);
+
+function fmt(value, digits = 1) {
+  return Number(value || 0).toFixed(digits);
+}
+
+function escapeHTML(text) {
+  return String(text).replace(/[&<>"']/g, (c) => `&#${c.charCodeAt(0)};`);
+}
+
+// levelColor picks the graph color for a share of the maximum, matching the
+// low/medium/high thresholds of the terminal graphs
+function levelColor(ratio) {
+  const style = getComputedStyle(document.documentElement);
+  if (ratio >= 0.8) return style.getPropertyValue("--high");
+  if (ratio >= 0.5) return style.getPropertyValue("--medium");
+  return style.getPropertyValue("--low");
+}
+
+function renderSystem() {
+  const sys = state.system;
+  if (!sys) return;
+  $("sys-cpu").textContent = `${fmt(sys.cpu_percent)}%`;
+  $("sys-mem").textContent = `${fmt(sys.used_memory_mb / 1024, 2)} / ${fmt(sys.total_memory_mb / 1024, 2)} GB`;
+  $("sys-disk").textContent = `R ${fmt(sys.disk_read_kbps)} W ${fmt(sys.disk_write_kbps)} KB/s`;
+}
+
+function visibleProcesses() {
+  const query = state.filter.toLowerCase();
+  const rows = state.processes.filter((p) =>
+    !query || p.name.toLowerCase().includes(query) || String(p.pid).includes(query));
+
+  const key = state.sortKey;
+  rows.sort((a, b) => {
+    const x = a[key] ?? "";
+    const y = b[key] ?? "";
+    const result = typeof x === "string" ? x.localeCompare(y) : x - y;
+    return state.sortDesc ? -result : result;
+  });
+  return rows;
+}
+
+function renderTable() {
+  const selected = state.selected;
+  const html = visibleProcesses().map((p) => {
+    const isSelected = selected && selected.pid === p.pid && selected.start_time === p.start_time;
+    return `<tr data-pid="${p.pid}" data-start="${p.start_time}"${isSelected ? ' class="selected"' : ""}>` +
+      `<td class="num">${p.pid}</td><td>${escapeHTML(p.name)}</td><td>${escapeHTML(p.user || "")}</td>` +
+      `<td class="num">${fmt(p.cpu_percent)}</td><td class="num">${fmt(p.memory_mb)}</td>` +
+      `<td class="num">${fmt(p.memory_percent)}</td><td class="num">${fmt(p.disk_read_kbps)}</td>` +
+      `<td class="num">${fmt(p.disk_write_kbps)}</td><td class="num">${fmt(p.net_sent_kbps)}</td>` +
+      `<td class="num">${fmt(p.net_recv_kbps)}</td></tr>`;
+  }).join("");
+  $("processes").innerHTML = html;
+
+  for (const th of document.querySelectorAll("th[data-sort]")) {
+    th.classList.toggle("sorted", th.dataset.sort === state.sortKey);
+    th.classList.toggle("asc", th.dataset.sort === state.sortKey && !state.sortDesc);
+  }
+}
+
+// drawChart renders an area graph like Graph and SparklineGraph in the
+// terminal UI. max of 0 scales to the data.
+function drawChart(canvas, values, max, unit) {
+  const ratio = window.devicePixelRatio || 1;
+  const width = canvas.clientWidth;
+  const height = canvas.clientHeight;
+  canvas.width = width * ratio;
+  canvas.height = height * ratio;
+  const ctx = canvas.getContext("2d");
+  ctx.scale(ratio, ratio);
+  ctx.clearRect(0, 0, width, height);
+
+  const peak = Math.max(0, ...values);
+  const scale = max > 0 ? Math.max(max, peak) : (peak > 0 ? peak * 1.1 : 1);
+  const left = 56;
+  const plotWidth = width - left;
+
+  ctx.fillStyle = "#7d8a96";
+  ctx.font = "11px ui-monospace, monospace";
+  ctx.fillText(`${fmt(scale)} ${unit}`, 0, 10);
+  ctx.fillText(`0 ${unit}`, 0, height - 2);
+  ctx.strokeStyle = "#2a323b";
+  ctx.beginPath();
+  ctx.moveTo(left, 0.5);
+  ctx.lineTo(width, 0.5);
+  ctx.moveTo(left, height - 0.5);
+  ctx.lineTo(width, height - 0.5);
+  ctx.stroke();
+
+  if (values.length === 0) {
+    ctx.fillText("no data yet", left + 8, height / 2);
+    return;
+  }
+
+  const step = values.length > 1 ? plotWidth / (values.length - 1) : 0;
+  const y = (v) => height - (v / scale) * (height - 4) - 1;
+  const color = levelColor(values[values.length - 1] / scale);
+
+  ctx.beginPath();
+  ctx.moveTo(left, height);
+  values.forEach((v, i) => ctx.lineTo(left + i * step, y(v)));
+  ctx.lineTo(left + (values.length - 1) * step, height);
+  ctx.closePath();
+  ctx.globalAlpha = 0.25;
+  ctx.fillStyle = color;
+  ctx.fill();
+
+  ctx.globalAlpha = 1;
+  ctx.beginPath();
+  values.forEach((v, i) => (i === 0 ? ctx.moveTo(left, y(v)) : ctx.lineTo(left + i * step, y(v))));
+  ctx.strokeStyle = color;
+  ctx.lineWidth = 1.5;
+  ctx.stroke();
+
+  ctx.fillStyle = "#d8dee4";
+  ctx.fillText(`${fmt(values[values.length - 1])} ${unit}`, width - 90, 10);
+}
+
+function openDetail(pid, startTime) {
+  const proc = state.processes.find((p) => p.pid === pid && p.start_time === startTime);
+  state.selected = { pid, start_time: startTime, name: proc ? proc.name : String(pid) };
+  $("list").hidden = true;
+  $("detail").hidden = false;
+  $("detail-info").textContent = "";
+  $("detail-info").classList.remove("gone");
+  $("detail-title").textContent = `${state.selected.name} (PID ${pid})`;
+  updateDetail();
+}
+
+function closeDetail() {
+  state.selected = null;
+  $("detail").hidden = true;
+  $("list").hidden = false;
+  renderTable();
+}
+
+async function updateDetail() {
+  const sel = state.selected;
+  if (!sel) return;
+
+  const response = await fetch(`api/processes/${sel.pid}/metrics?tier=${state.tier}&start_time=${sel.start_time}`);
+  if (state.selected !== sel) return; // Another process was opened meanwhile
+  if (!response.ok) {
+    $("detail-info").textContent = "Process has exited; showing its last samples.";
+    $("detail-info").classList.add("gone");
+    return;
+  }
+  const metrics = await response.json();
+
+  const proc = state.processes.find((p) => p.pid === sel.pid && p.start_time === sel.start_time);
+  if (proc) {
+    $("detail-info").textContent =
+      `User ${proc.user || "?"} · started ${new Date(proc.create_time).toLocaleString()} · ` +
+      `CPU ${fmt(proc.cpu_percent)}% · ${fmt(proc.memory_mb)} MB · ` +
+      `ctx switches ${fmt(proc.ctx_switches_ps, 0)}/s · faults ${fmt(proc.minor_faults_ps, 0)}/${fmt(proc.major_faults_ps, 0)}/s`;
+  }
+
+  const samples = metrics.samples;
+  const series = (fn) => samples.map(fn);
+  const perCore = metrics.cpu_mode === "per-core";
+  $("cpu-title").textContent = `CPU Usage (${perCore ? "% of one core" : "% of all cores"})`;
+  drawChart($("chart-cpu"), series((s) => s.cpu_percent), 100, "%");
+  drawChart($("chart-memory"), series((s) => s.memory_mb), state.system ? state.system.total_memory_mb : 0, "MB");
+  drawChart($("chart-disk"), series((s) => s.disk_read_percent + s.disk_write_percent), 0, "%");
+  drawChart($("chart-network"), series((s) => s.net_sent_kbps + s.net_recv_kbps), 0, "KB/s");
+}
+
+function connect() {
+  const events = new EventSource("api/events");
+  events.addEventListener("open", () => {
+    $("status").textContent = "live";
+    $("status").className = "online";
+  });
+  events.addEventListener("error", () => {
+    $("status").textContent = "reconnecting";
+    $("status").className = "offline";
+  });
+  events.addEventListener("update", (event) => {
+    const update = JSON.parse(event.data);
+    state.system = update.system;
+    state.cpuMode = update.cpu_mode;
+    state.processes = update.processes;
+    renderSystem();
+    if (state.selected) {
+      updateDetail();
+    } else {
+      renderTable();
+    }
+  });
+}
+
+document.querySelector("thead").addEventListener("click", (event) => {
+  const key = event.target.dataset.sort;
+  if (!key) return;
+  if (key === state.sortKey) {
+    state.sortDesc = !state.sortDesc;
+  } else {
+    state.sortKey = key;
+    state.sortDesc = !textColumns.has(key);
+  }
+  renderTable();
+});
+
+$("processes").addEventListener("click", (event) => {
+  const row = event.target.closest("tr");
+  if (row) openDetail(Number(row.dataset.pid), Number(row.dataset.start));
+});
+
+$("search").addEventListener("input", (event) => {
+  state.filter = event.target.value;
+  renderTable();
+});
+
+$("tier").addEventListener("change", (event) => {
+  state.tier = Number(event.target.value);
+  updateDetail();
+});
+
+$("close").addEventListener("click", closeDetail);
+document.addEventListener("keydown", (event) => {
+  if (event.key === "Escape" && state.selected) closeDetail();
+});
+window.addEventListener("resize", () => state.selected && updateDetail());
+
+connect();
@@ -0,0 +1,25 @@
+// Package web serves a browser dashboard mirroring the terminal UI. All
+// assets are embedded in the binary; the page talks to the JSON API only.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Register adds the dashboard routes to a mux. The dashboard needs the JSON
+// API on the same mux.
+func Register(mux *http.ServeMux) {
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err) // The embedded directory is fixed at build time
+	}
+	files := http.FileServerFS(assets)
+
+	mux.Handle("GET /{$}", files)
+	mux.Handle("GET /static/", http.StripPrefix("/static/", files))
+}
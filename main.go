@@ -1,13 +1,124 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"hyperbyte-proc-monitor/internal/app"
+	"hyperbyte-proc-monitor/internal/monitor"
+	"hyperbyte-proc-monitor/internal/remote"
+	"hyperbyte-proc-monitor/internal/ui"
 )
 
 func main() {
-	application, err := app.NewApp()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "record":
+			runRecord(os.Args[2:])
+			return
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "connect":
+			runConnect(os.Args[2:])
+			return
+		}
+	}
+	runMonitor(os.Args[1:])
+}
+
+// runMonitor starts the interactive monitor for the local host
+func runMonitor(args []string) {
+	config := app.DefaultConfig()
+
+	flags := flag.NewFlagSet("pulse", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage:\n  pulse [flags]\n  pulse --batch [--format jsonl|csv] [flags]\n  pulse record -o FILE [flags]\n  pulse replay FILE\n  pulse agent --listen ADDR [flags]\n  pulse connect [flags] HOST:PORT...\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	var historyMaxMB int64
+	flags.BoolVar(&config.HistoryEnabled, "history", config.HistoryEnabled, "record metrics history on disk")
+	flags.StringVar(&config.HistoryDir, "history-dir", config.HistoryDir, "directory for the metrics history")
+	flags.DurationVar(&config.HistoryRetention, "history-retention", config.HistoryRetention, "how long metrics history is kept")
+	flags.Int64Var(&historyMaxMB, "history-max-mb", config.HistoryMaxBytes>>20, "maximum size of the metrics history in MB")
+
+	var metricsLabels, columns string
+	flags.BoolVar(&config.Headless, "no-ui", false, "run without the UI, e.g. only to serve --listen")
+	flags.StringVar(&columns, "columns", "", "optional process table columns, comma-separated ("+strings.Join(ui.ColumnNames(), ",")+")")
+	flags.StringVar(&config.Listen, "listen", "", "serve Prometheus metrics on this address, e.g. :9256")
+	flags.StringVar(&metricsLabels, "metrics-labels", strings.Join(config.MetricsLabels, ","), "process labels of the metrics: any of pid,name,user (processes sharing all values are summed)")
+	flags.IntVar(&config.MetricsMaxSeries, "metrics-max-series", config.MetricsMaxSeries, "maximum process series; the rest is summed into name=\"_other\" (0 for no limit)")
+
+	var remoteWriteMaxMB int64
+	flags.StringVar(&config.RemoteWriteURL, "remote-write", "", "push metrics to this Prometheus remote-write URL")
+	flags.StringVar(&config.RemoteWriteQueueDir, "remote-write-queue-dir", config.RemoteWriteQueueDir, "directory for batches not yet delivered (empty keeps them in memory)")
+	flags.StringVar(&config.RemoteWriteRelabel, "remote-write-relabel", "", "JSON file with relabeling rules for pushed series")
+	flags.DurationVar(&config.RemoteWriteInterval, "remote-write-interval", config.RemoteWriteInterval, "time between remote-write batches")
+	flags.Int64Var(&remoteWriteMaxMB, "remote-write-queue-max-mb", config.RemoteWriteMaxQueueBytes>>20, "maximum size of undelivered batches in MB")
+
+	var otlpHeaders string
+	flags.StringVar(&config.OTLPEndpoint, "otlp-endpoint", "", "export metrics to this OpenTelemetry collector via OTLP/HTTP, e.g. http://localhost:4318")
+	flags.StringVar(&config.OTLPProtocol, "otlp-protocol", config.OTLPProtocol, "OTLP encoding: http/protobuf or http/json")
+	flags.StringVar(&otlpHeaders, "otlp-headers", "", "extra OTLP request headers as comma-separated key=value pairs")
+	flags.DurationVar(&config.OTLPInterval, "otlp-interval", config.OTLPInterval, "time between OTLP exports")
+
+	flags.StringVar(&config.AlertRules, "alert-rules", "", "JSON file with alert rules evaluated on every update")
+	flags.StringVar(&config.Notify, "notify", "", "JSON file with channels notified of alerts and process events")
+	flags.StringVar(&config.AuditLog, "audit-log", config.AuditLog, "file recording signals and scheduling changes (empty to disable)")
+
+	flags.Func("sink", "write metrics to an Influx or Graphite sink, repeatable: influx+file:///path, influx+udp://host:port, influx+http://host:port/write?db=..., graphite://host:port (options: interval, fields, buffer)", func(spec string) error {
+		config.Sinks = append(config.Sinks, spec)
+		return nil
+	})
+
+	var batch app.BatchConfig
+	var batchMode, ascending bool
+	var fields, sortBy string
+	flags.BoolVar(&batchMode, "batch", false, "print snapshots to stdout instead of starting the UI")
+	flags.StringVar(&batch.Format, "format", "jsonl", "batch output format: jsonl or csv")
+	flags.DurationVar(&batch.Interval, "interval", 2*time.Second, "batch: time between snapshots")
+	flags.IntVar(&batch.Count, "count", 0, "batch: number of snapshots (0 runs until interrupted)")
+	flags.StringVar(&fields, "fields", "", "batch: comma-separated process fields ("+strings.Join(app.BatchFieldNames(), ",")+")")
+	flags.StringVar(&batch.Filter, "filter", "", "batch: only processes whose name or PID contains this")
+	flags.StringVar(&sortBy, "sort", "cpu", "batch: sort by pid, name, cpu or memory")
+	flags.BoolVar(&ascending, "asc", false, "batch: sort ascending (default: descending for cpu/memory)")
+	flags.IntVar(&batch.Top, "top", 0, "batch: maximum processes per snapshot (0 for all)")
+	flags.Parse(args)
+	config.HistoryMaxBytes = historyMaxMB << 20
+	config.MetricsLabels = splitList(metricsLabels)
+	config.Columns = splitList(columns)
+	config.RemoteWriteMaxQueueBytes = remoteWriteMaxMB << 20
+	headers, err := parseHeaders(otlpHeaders)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.OTLPHeaders = headers
+
+	if batchMode {
+		order, err := monitor.ParseSortBy(sortBy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		batch.SortBy = order
+		batch.SortDesc = order.DefaultDescending() && !ascending
+		batch.Fields = splitList(fields)
+		if batch.Interval <= 0 {
+			log.Fatal("interval must be positive")
+		}
+		if err := app.RunBatch(batch, os.Stdout); err != nil {
+			log.Fatalf("Batch mode failed: %v", err)
+		}
+		return
+	}
+
+	application, err := app.NewApp(config)
 	if err != nil {
 		log.Fatalf("Failed to create application: %v", err)
 	}
@@ -16,3 +127,168 @@ func main() {
 		log.Fatalf("Application error: %v", err)
 	}
 }
+
+// runRecord captures snapshots to a session file without starting the UI
+func runRecord(args []string) {
+	var config app.RecordConfig
+
+	flags := flag.NewFlagSet("pulse record", flag.ExitOnError)
+	flags.StringVar(&config.Output, "o", "", "session file to write (required)")
+	flags.DurationVar(&config.Interval, "interval", 2*time.Second, "time between snapshots")
+	flags.DurationVar(&config.Duration, "duration", 0, "stop after this long (0 records until interrupted)")
+	flags.Parse(args)
+
+	if config.Output == "" || config.Interval <= 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := app.Record(config); err != nil {
+		log.Fatalf("Recording failed: %v", err)
+	}
+}
+
+// runReplay plays back a session file in the UI
+func runReplay(args []string) {
+	flags := flag.NewFlagSet("pulse replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage:\n  pulse replay FILE\n")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	application, err := app.NewReplayApp(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load session: %v", err)
+	}
+
+	if err := application.Run(); err != nil {
+		log.Fatalf("Application error: %v", err)
+	}
+}
+
+// runAgent monitors the local host without a UI and streams snapshots to viewers
+func runAgent(args []string) {
+	config := app.DefaultConfig()
+	config.Headless = true
+
+	var tokenFile string
+	flags := flag.NewFlagSet("pulse agent", flag.ExitOnError)
+	flags.StringVar(&config.AgentListen, "listen", "", "address to accept viewers on, e.g. :9257 (required)")
+	flags.StringVar(&config.AgentTLS.Cert, "cert", "", "agent certificate (PEM)")
+	flags.StringVar(&config.AgentTLS.Key, "key", "", "private key of the agent certificate (PEM)")
+	flags.StringVar(&config.AgentTLS.CA, "client-ca", "", "CA certificate that signs viewer certificates (PEM)")
+	flags.StringVar(&tokenFile, "token-file", "", "file with the token viewers must present (default: $PULSE_TOKEN)")
+	flags.BoolVar(&config.AgentAllowSignals, "allow-signals", false, "let viewers send signals to processes and change their scheduling")
+	flags.StringVar(&config.AuditLog, "audit-log", config.AuditLog, "file recording the signals viewers send (empty to disable)")
+	flags.StringVar(&config.AlertRules, "alert-rules", "", "JSON file with alert rules evaluated on every update")
+	flags.StringVar(&config.Notify, "notify", "", "JSON file with channels notified of alerts and process events")
+	flags.BoolVar(&config.HistoryEnabled, "history", config.HistoryEnabled, "record metrics history on disk")
+	flags.StringVar(&config.HistoryDir, "history-dir", config.HistoryDir, "directory for the metrics history")
+	flags.Parse(args)
+
+	if config.AgentListen == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	token, err := readToken(tokenFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.AgentToken = token
+
+	application, err := app.NewApp(config)
+	if err != nil {
+		log.Fatalf("Failed to create application: %v", err)
+	}
+
+	if err := application.Run(); err != nil {
+		log.Fatalf("Application error: %v", err)
+	}
+}
+
+// runConnect shows the processes of one or more remote agents in the UI
+func runConnect(args []string) {
+	var opts remote.ClientOptions
+	var tokenFile, alertRules, auditLog string
+
+	flags := flag.NewFlagSet("pulse connect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage:\n  pulse connect [flags] HOST:PORT...\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.TLS.Cert, "cert", "", "viewer certificate (PEM)")
+	flags.StringVar(&opts.TLS.Key, "key", "", "private key of the viewer certificate (PEM)")
+	flags.StringVar(&opts.TLS.CA, "ca", "", "CA certificate that signs the agent certificate (PEM)")
+	flags.StringVar(&opts.ServerName, "server-name", "", "name in the agent certificate (default: the host of each HOST:PORT)")
+	flags.StringVar(&tokenFile, "token-file", "", "file with the agent token (default: $PULSE_TOKEN)")
+	flags.StringVar(&alertRules, "alert-rules", "", "JSON file with alert rules evaluated on the received snapshots")
+	flags.StringVar(&auditLog, "audit-log", app.DefaultAuditLog(), "file recording the signals sent to the agents (empty to disable)")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	token, err := readToken(tokenFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts.Token = token
+
+	// All agents share the credentials; several addresses open the fleet view
+	agents := make([]remote.ClientOptions, flags.NArg())
+	for i, address := range flags.Args() {
+		agents[i] = opts
+		agents[i].Address = address
+	}
+
+	application, err := app.NewConnectApp(agents, alertRules, auditLog)
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+
+	if err := application.Run(); err != nil {
+		log.Fatalf("Application error: %v", err)
+	}
+}
+
+// readToken loads the agent token from a file, or from $PULSE_TOKEN
+func readToken(path string) (string, error) {
+	if path != "" {
+		return remote.LoadToken(path)
+	}
+	if token := strings.TrimSpace(os.Getenv("PULSE_TOKEN")); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("a token is required: use --token-file or set PULSE_TOKEN")
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseHeaders parses comma-separated key=value pairs, the format of
+// OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", item)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}